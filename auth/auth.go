@@ -23,9 +23,9 @@ var jwtSecrets = struct {
 
 func init() {
 	// Load environment variables
-	err := godotenv.Load() // Load .env file
+	err := godotenv.Load() // Load .env file, the variables may also be set directly
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Printf("Error loading .env file")
 	}
 
 	// Load active JWT secret from environment variables
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// CheckInTokenTTL is how long a booking check-in code stays valid
+const CheckInTokenTTL = 5 * time.Minute
+
+// checkInPurpose marks check-in tokens so they can't be used as access tokens and vice versa
+const checkInPurpose = "check-in"
+
+// GenerateCheckInToken generates a short-lived signed check-in code for a booking.
+// Each code carries a random nonce so a scanned code can be rejected if presented again.
+func GenerateCheckInToken(bookingID uint) (string, time.Time, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(CheckInTokenTTL)
+	claims := jwt.MapClaims{
+		"booking_id": bookingID,
+		"purpose":    checkInPurpose,
+		"jti":        hex.EncodeToString(nonce),
+		"exp":        expiresAt.Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(GetActiveSecret())) // Sign using the active secret
+	return signed, expiresAt, err
+}
+
+// ValidateCheckInToken validates a check-in code and returns the booking ID and the code nonce.
+func ValidateCheckInToken(tokenString string) (uint, string, error) {
+	// Try the active secret first, then the expired ones so codes survive a rotation
+	secrets := append([]string{jwtSecrets.ActiveSecret}, jwtSecrets.ExpiredSecrets...)
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			// Ensure the signing method is HMAC
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("invalid signing method")
+			}
+			return []byte(secret), nil
+		})
+		if err != nil || !token.Valid {
+			continue
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok || claims["purpose"] != checkInPurpose {
+			return 0, "", errors.New("not a check-in code")
+		}
+		bookingID, ok := claims["booking_id"].(float64)
+		if !ok {
+			return 0, "", errors.New("booking_id not found in check-in code")
+		}
+		nonce, ok := claims["jti"].(string)
+		if !ok || nonce == "" {
+			return 0, "", errors.New("nonce not found in check-in code")
+		}
+		return uint(bookingID), nonce, nil
+	}
+
+	return 0, "", errors.New("invalid or expired check-in code")
+}
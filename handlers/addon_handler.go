@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/pricing"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errUnknownAddOn       = errors.New("add-on not found or not available in this arena")
+	errAddOnOutOfStock    = errors.New("not enough units of the add-on are left for this slot")
+	errAddOnsLocked       = errors.New("add-ons can only be attached to pending bookings that are not split or partly paid")
+	errInvalidAddOnList   = errors.New("add_ons must be a comma separated list of add_on_id:quantity")
+	errNotBookingCustomer = errors.New("only the customer who made the booking can change it")
+)
+
+// addOnRequest asks for a quantity of an add-on with a booking
+type addOnRequest struct {
+	AddOnID  uint `json:"add_on_id" binding:"required"`
+	Quantity int  `json:"quantity" binding:"required,min=1"`
+}
+
+// addOnAvailability is an add-on with the units still free for a slot
+type addOnAvailability struct {
+	models.AddOn
+	Available int `json:"available"`
+}
+
+// CreateAddOn handles adding an add-on to the catalogue of an arena, by its owner or staff
+// allowed to manage add-ons
+func CreateAddOn(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadStaffArena(c, arenaID, user, models.StaffManageAddOns)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name        string      `json:"name" binding:"required"`
+		Description string      `json:"description"`
+		Price       money.Money `json:"price"`
+		PerHour     bool        `json:"per_hour"`
+		Stock       int         `json:"stock"`
+		Active      *bool       `json:"active"` // Defaults to true
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addOn := models.AddOn{
+		ArenaID:     arena.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		PerHour:     req.PerHour,
+		Stock:       req.Stock,
+		Active:      req.Active == nil || *req.Active,
+	}
+	if err := pricing.ValidateAddOn(&addOn, arena.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Create(&addOn).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create add-on"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Add-on created successfully", "add_on": addOn})
+}
+
+// ListAddOns handles listing the add-on catalogue of an arena. With start (and duration,
+// 1 hour by default) each add-on comes with the units still available for that slot.
+func ListAddOns(c *gin.Context) {
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var addOns []models.AddOn
+	if err := database.DB.Where("arena_id = ?", arenaID).Order("name, id").Find(&addOns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load add-ons"})
+		return
+	}
+
+	value := c.Query("start")
+	if value == "" {
+		c.JSON(http.StatusOK, gin.H{"add_ons": addOns})
+		return
+	}
+	start, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must be an RFC 3339 timestamp"})
+		return
+	}
+	duration, err := strconv.Atoi(c.DefaultQuery("duration", "1"))
+	if err != nil || duration < 1 || duration > pricing.MaxDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": pricing.ErrInvalidDuration.Error()})
+		return
+	}
+
+	ids := make([]uint, 0, len(addOns))
+	for _, addOn := range addOns {
+		ids = append(ids, addOn.ID)
+	}
+	reserved, err := reservedAddOns(database.DB, ids, start, duration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check add-on stock"})
+		return
+	}
+
+	result := make([]addOnAvailability, 0, len(addOns))
+	for _, addOn := range addOns {
+		result = append(result, addOnAvailability{AddOn: addOn, Available: max(addOn.Stock-reserved[addOn.ID], 0)})
+	}
+	c.JSON(http.StatusOK, gin.H{"add_ons": result})
+}
+
+// UpdateAddOn handles the arena owner or staff changing an add-on. Price
+// changes only apply to new bookings, and lowering the stock doesn't cancel reservations.
+func UpdateAddOn(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	addOnID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var addOn models.AddOn
+	if err := database.DB.First(&addOn, addOnID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Add-on not found"})
+		return
+	}
+	arena, ok := loadStaffArena(c, addOn.ArenaID, user, models.StaffManageAddOns)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name        *string      `json:"name"`
+		Description *string      `json:"description"`
+		Price       *money.Money `json:"price"`
+		PerHour     *bool        `json:"per_hour"`
+		Stock       *int         `json:"stock"`
+		Active      *bool        `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != nil {
+		addOn.Name = *req.Name
+	}
+	if req.Description != nil {
+		addOn.Description = *req.Description
+	}
+	if req.Price != nil {
+		addOn.Price = *req.Price
+	}
+	if req.PerHour != nil {
+		addOn.PerHour = *req.PerHour
+	}
+	if req.Stock != nil {
+		addOn.Stock = *req.Stock
+	}
+	if req.Active != nil {
+		addOn.Active = *req.Active
+	}
+	if err := pricing.ValidateAddOn(&addOn, arena.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Save(&addOn).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update add-on"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Add-on updated successfully", "add_on": addOn})
+}
+
+// DeleteAddOn handles the arena owner or staff removing an add-on from the catalogue.
+// Bookings that already include it keep their reservation.
+func DeleteAddOn(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	addOnID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var addOn models.AddOn
+	if err := database.DB.First(&addOn, addOnID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Add-on not found"})
+		return
+	}
+	if _, ok := loadStaffArena(c, addOn.ArenaID, user, models.StaffManageAddOns); !ok {
+		return
+	}
+
+	if err := database.DB.Delete(&addOn).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete add-on"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Add-on deleted successfully"})
+}
+
+// AttachBookingAddOns handles the customer adding add-ons to a booking still waiting for
+// payment. The units are reserved for the booking slot and the total is updated.
+func AttachBookingAddOns(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	bookingID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		AddOns []addOnRequest `json:"add_ons" binding:"required,min=1,max=20,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var booking models.Booking
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		if booking.UserID != user.ID {
+			return errNotBookingCustomer
+		}
+
+		// The amount due must not change under a payment or a split that is already under way
+		var shares, payments int64
+		if err := tx.Model(&models.PaymentShare{}).Where("booking_id = ?", booking.ID).Count(&shares).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Payment{}).Where("booking_id = ?", booking.ID).Count(&payments).Error; err != nil {
+			return err
+		}
+		if booking.Status != models.BookingStatusPending || shares > 0 || payments > 0 {
+			return errAddOnsLocked
+		}
+
+		if err := reserveAddOns(tx, &booking, req.AddOns); err != nil {
+			return err
+		}
+		for i := range booking.AddOns {
+			booking.AddOns[i].BookingID = booking.ID
+		}
+		for i := range booking.Lines {
+			booking.Lines[i].BookingID = booking.ID
+		}
+		if err := tx.Create(&booking.AddOns).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&booking.Lines).Error; err != nil {
+			return err
+		}
+		return tx.Model(&booking).Update("total_amount_minor", booking.TotalAmount.Minor).Error
+	})
+	if respondAddOnError(c, err, "Failed to attach add-ons") {
+		return
+	}
+
+	database.DB.Preload("Lines").Preload("AddOns.AddOn").First(&booking, booking.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Add-ons attached successfully", "booking": booking})
+}
+
+// reserveAddOns reserves the requested add-ons for the booking slot inside a transaction,
+// adding them and their lines to the booking and its total. The add-on rows are locked so
+// concurrent bookings can't both take the last units.
+func reserveAddOns(tx *gorm.DB, booking *models.Booking, requests []addOnRequest) error {
+	quantities := map[uint]int{}
+	var ids []uint
+	for _, req := range requests {
+		if req.Quantity <= 0 {
+			return pricing.ErrInvalidQuantity
+		}
+		if _, seen := quantities[req.AddOnID]; !seen {
+			ids = append(ids, req.AddOnID)
+		}
+		quantities[req.AddOnID] += req.Quantity
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var addOns []models.AddOn
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("arena_id = ? AND active = ?", booking.ArenaID, true).
+		Order("id").Find(&addOns, ids).Error; err != nil {
+		return err
+	}
+	if len(addOns) != len(ids) {
+		return errUnknownAddOn
+	}
+
+	reserved, err := reservedAddOns(tx, ids, booking.BookingTime, booking.Duration)
+	if err != nil {
+		return err
+	}
+
+	for _, addOn := range addOns {
+		quantity := quantities[addOn.ID]
+		if reserved[addOn.ID]+quantity > addOn.Stock {
+			return errAddOnOutOfStock
+		}
+
+		quote, err := pricing.QuoteAddOn(addOn, quantity, booking.Duration)
+		if err != nil {
+			return err
+		}
+		if booking.TotalAmount, err = booking.TotalAmount.Add(quote.Amount); err != nil {
+			return err
+		}
+		booking.AddOns = append(booking.AddOns, models.BookingAddOn{
+			AddOnID:   addOn.ID,
+			Quantity:  quantity,
+			UnitPrice: quote.UnitPrice,
+			Amount:    quote.Amount,
+		})
+		booking.Lines = append(booking.Lines, quote.BookingLine())
+	}
+	return nil
+}
+
+// reservedAddOns returns how many units of each add-on active bookings overlapping the slot hold.
+func reservedAddOns(tx *gorm.DB, addOnIDs []uint, start time.Time, duration int) (map[uint]int, error) {
+	reserved := map[uint]int{}
+	if len(addOnIDs) == 0 {
+		return reserved, nil
+	}
+	end := start.Add(time.Duration(duration) * time.Hour)
+
+	var rows []struct {
+		AddOnID  uint
+		Quantity int
+	}
+	err := tx.Model(&models.BookingAddOn{}).
+		Select("booking_add_ons.add_on_id, SUM(booking_add_ons.quantity) AS quantity").
+		Joins("JOIN bookings ON bookings.id = booking_add_ons.booking_id AND bookings.deleted_at IS NULL").
+		Where("booking_add_ons.add_on_id IN ?", addOnIDs).
+		Scopes(models.ActiveBookingsBetween(start, end)).
+		Group("booking_add_ons.add_on_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		reserved[row.AddOnID] = row.Quantity
+	}
+	return reserved, err
+}
+
+// quoteAddOns adds the add-ons requested with the add_ons query parameter ("id:quantity,...")
+// to a quote of a field of the arena.
+func quoteAddOns(quote *pricing.Quote, arenaID uint, value string) error {
+	var requests []addOnRequest
+	for _, item := range strings.Split(value, ",") {
+		id, quantity, found := strings.Cut(strings.TrimSpace(item), ":")
+		addOnID, errID := strconv.ParseUint(id, 10, 64)
+		count, errQuantity := strconv.Atoi(quantity)
+		if !found || errID != nil || errQuantity != nil || count <= 0 {
+			return errInvalidAddOnList
+		}
+		requests = append(requests, addOnRequest{AddOnID: uint(addOnID), Quantity: count})
+	}
+
+	for _, req := range requests {
+		var addOn models.AddOn
+		if err := database.DB.Where("arena_id = ? AND active = ?", arenaID, true).First(&addOn, req.AddOnID).Error; err != nil {
+			return errUnknownAddOn
+		}
+		addOnQuote, err := pricing.QuoteAddOn(addOn, req.Quantity, quote.Duration)
+		if err != nil {
+			return err
+		}
+		if err := quote.AddAddOn(addOnQuote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// respondAddOnError writes the response for a failed add-on reservation and reports
+// whether there was an error.
+func respondAddOnError(c *gin.Context, err error, fallback string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+	case errors.Is(err, errNotBookingCustomer):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, errAddOnOutOfStock), errors.Is(err, errAddOnsLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errUnknownAddOn), errors.Is(err, pricing.ErrInvalidQuantity):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+	return true
+}
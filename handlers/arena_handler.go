@@ -1,26 +1,252 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
 	"sparring-backend/internal/database"
+	"sparring-backend/internal/geo"
 	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/search"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// CreateArena handles creating a new arena
+// CreateArena handles creating a new arena owned by the current user
 func CreateArena(c *gin.Context) {
-	var arena models.Arena
-	if err := c.ShouldBindJSON(&arena); err != nil {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name        string   `json:"name" binding:"required"`
+		Location    string   `json:"location" binding:"required"`
+		Description string   `json:"description"`
+		Currency    string   `json:"currency"`
+		Timezone    string   `json:"timezone"`
+		TaxName     string   `json:"tax_name"`
+		TaxRateBps  int64    `json:"tax_rate_bps"`
+		Latitude    *float64 `json:"latitude"`
+		Longitude   *float64 `json:"longitude"`
+		Fields      []struct {
+			FieldName  string      `json:"field_name" binding:"required"`
+			SportType  string      `json:"sport_type" binding:"required"`
+			PricePerHr money.Money `json:"price_per_hr"`
+		} `json:"fields" binding:"dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Ratings only come from reviews and commissions are set by admins,
+	// so only the details the owner controls are copied over
+	arena := models.Arena{
+		Name:        req.Name,
+		Location:    req.Location,
+		Description: req.Description,
+		Currency:    req.Currency,
+		Timezone:    req.Timezone,
+		TaxName:     req.TaxName,
+		TaxRateBps:  req.TaxRateBps,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		OwnerID:     user.ID,
+	}
+	for _, field := range req.Fields {
+		arena.Fields = append(arena.Fields, models.Field{
+			FieldName:  field.FieldName,
+			SportType:  field.SportType,
+			PricePerHr: field.PricePerHr,
+		})
+	}
+
+	// Validate the arena currency, falling back to the default one
+	arena.Currency = money.NormalizeCurrency(arena.Currency)
+	if arena.Currency == "" {
+		arena.Currency = money.DefaultCurrency
+	}
+	if !money.ValidCurrency(arena.Currency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+
+	if err := validateArenaSettings(&arena); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Field prices are always expressed in the arena currency
+	for i := range arena.Fields {
+		price, err := arena.Fields[i].PricePerHr.WithCurrency(arena.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field price must be in the arena currency"})
+			return
+		}
+		arena.Fields[i].PricePerHr = price
+	}
+
 	// Save arena to database
 	if err := database.DB.Create(&arena).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create arena"})
 		return
 	}
+	indexArena(arena.ID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Arena created successfully"})
 }
+
+// UpdateArena handles updating the details, coordinates and tax settings of an arena owned by the current user.
+// The currency cannot be changed since every price of the arena is expressed in it.
+func UpdateArena(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadOwnedArena(c, arenaID, user)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name        *string  `json:"name"`
+		Location    *string  `json:"location"`
+		Description *string  `json:"description"`
+		Timezone    *string  `json:"timezone"`
+		TaxName     *string  `json:"tax_name"`
+		TaxRateBps  *int64   `json:"tax_rate_bps"`
+		Latitude    *float64 `json:"latitude"`
+		Longitude   *float64 `json:"longitude"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != nil {
+		arena.Name = *req.Name
+	}
+	if req.Location != nil {
+		arena.Location = *req.Location
+	}
+	if req.Description != nil {
+		arena.Description = *req.Description
+	}
+	if req.Timezone != nil {
+		arena.Timezone = *req.Timezone
+	}
+	if req.TaxName != nil {
+		arena.TaxName = *req.TaxName
+	}
+	if req.TaxRateBps != nil {
+		arena.TaxRateBps = *req.TaxRateBps
+	}
+	if req.Latitude != nil && req.Longitude != nil {
+		arena.Latitude, arena.Longitude = req.Latitude, req.Longitude
+	}
+
+	if err := validateArenaSettings(&arena); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Save(&arena).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update arena"})
+		return
+	}
+	indexArena(arena.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Arena updated successfully", "arena": arena})
+}
+
+// DeleteArena handles removing an arena owned by the current user. Arenas with upcoming
+// active bookings can't be removed.
+func DeleteArena(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadOwnedArena(c, arenaID, user)
+	if !ok {
+		return
+	}
+
+	var upcoming int64
+	if err := database.DB.Model(&models.Booking{}).
+		Where("arena_id = ? AND status IN ? AND booking_time > ?", arena.ID, models.ActiveBookingStatuses, time.Now()).
+		Count(&upcoming).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check upcoming bookings"})
+		return
+	}
+	if upcoming > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The arena has upcoming bookings"})
+		return
+	}
+
+	if err := database.DB.Delete(&arena).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete arena"})
+		return
+	}
+	if err := search.Default.Delete(context.Background(), arena.ID); err != nil {
+		log.Printf("Failed to remove arena %d from the search index: %v", arena.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Arena deleted successfully"})
+}
+
+// indexArena refreshes the arena in the search index. Failures are logged, since the
+// index can always be rebuilt from the database.
+func indexArena(arenaID uint) {
+	var arena models.Arena
+	if err := database.DB.Preload("Fields").First(&arena, arenaID).Error; err != nil {
+		log.Printf("Failed to load arena %d for indexing: %v", arenaID, err)
+		return
+	}
+	if err := search.Default.Upsert(context.Background(), search.ArenaDocument(arena)); err != nil {
+		log.Printf("Failed to index arena %d: %v", arenaID, err)
+	}
+}
+
+// validateArenaSettings fills in defaults and checks the time zone, coordinates and tax settings of an arena.
+func validateArenaSettings(arena *models.Arena) error {
+	// Pricing rules match hours in the arena time zone
+	if arena.Timezone == "" {
+		arena.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(arena.Timezone); err != nil {
+		return errors.New("Unknown time zone")
+	}
+
+	// Coordinates go together and must be on the map
+	if (arena.Latitude == nil) != (arena.Longitude == nil) {
+		return errors.New("Latitude and longitude must be set together")
+	}
+	arena.Geohash = ""
+	if arena.Latitude != nil {
+		point := geo.Point{Lat: *arena.Latitude, Lng: *arena.Longitude}
+		if !point.Valid() {
+			return errors.New("Coordinates are out of range")
+		}
+		arena.Geohash = geo.Geohash(point, geo.GeohashPrecision)
+	}
+
+	if arena.TaxName == "" {
+		arena.TaxName = "VAT"
+	}
+	if arena.TaxRateBps < 0 || arena.TaxRateBps > 10000 {
+		return errors.New("Tax rate must be between 0 and 10000 basis points")
+	}
+	return nil
+}
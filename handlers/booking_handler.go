@@ -1,26 +1,344 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"sparring-backend/internal/database"
+	"sparring-backend/internal/loyalty"
 	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/pricing"
+	"sparring-backend/internal/promo"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// CreateBooking handles booking a sports arena
+// CreateBooking handles booking a sports arena for the current user
 func CreateBooking(c *gin.Context) {
-	var booking models.Booking
-	if err := c.ShouldBindJSON(&booking); err != nil {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ArenaID      uint           `json:"arena_id" binding:"required"`
+		FieldID      uint           `json:"field_id" binding:"required"`
+		BookingTime  time.Time      `json:"booking_time" binding:"required"`
+		Duration     int            `json:"duration" binding:"required,min=1,max=24"`
+		PromoCode    string         `json:"promo_code"`                    // Optional promo code to apply
+		TeamID       *uint          `json:"team_id"`                       // Book on behalf of a team the user captains
+		AddOns       []addOnRequest `json:"add_ons" binding:"max=20,dive"` // Equipment and amenities to rent with the field
+		PassID       *uint          `json:"pass_id"`                       // Cover the field with this pass or membership
+		UsePass      bool           `json:"use_pass"`                      // Cover the field with the first pass to expire that applies
+		RedeemPoints int            `json:"redeem_points" binding:"min=0"` // Loyalty points to exchange for free hours
+		UserID       *uint          `json:"user_id"`                       // Sent by clients from before bookings required a login
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Save booking to database
-	if err := database.DB.Create(&booking).Error; err != nil {
+	// The customer is the logged in user: promo limits, passes and points are per user
+	if req.UserID != nil && *req.UserID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bookings can only be made for the logged in user"})
+		return
+	}
+
+	if !req.BookingTime.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "booking_time must be in the future"})
+		return
+	}
+
+	usePass := req.PassID != nil || req.UsePass
+	if usePass && req.PromoCode != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errPassWithPromo.Error()})
+		return
+	}
+	if usePass && req.RedeemPoints > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errPointsWithPass.Error()})
+		return
+	}
+	if req.TeamID != nil && !requireCaptain(c, *req.TeamID, user) {
+		return
+	}
+
+	// Price the booking from the field and its pricing rules, never from the client
+	var field models.Field
+	if err := database.DB.Where("id = ? AND arena_id = ?", req.FieldID, req.ArenaID).First(&field).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Field not found in this arena"})
+		return
+	}
+
+	booking, err := newBooking(user, field, req.BookingTime, req.Duration)
+	if errors.Is(err, pricing.ErrInvalidDuration) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to price booking"})
+		return
+	}
+	booking.TeamID = req.TeamID
+
+	// Save booking to database, reserving the add-ons, redeeming the promo code, pass or points
+	// and charging outstanding penalties in the same transaction
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if len(req.AddOns) > 0 {
+			if err := reserveAddOns(tx, &booking, req.AddOns); err != nil {
+				return err
+			}
+		}
+
+		var passRedemption *models.PassRedemption
+		if usePass {
+			var err error
+			if passRedemption, err = applyPass(tx, req.PassID, user, field, &booking); err != nil {
+				return err
+			}
+		}
+		if req.RedeemPoints > 0 {
+			if err := applyPoints(req.RedeemPoints, &booking); err != nil {
+				return err
+			}
+		}
+
+		var redemption *models.PromoRedemption
+		if req.PromoCode != "" {
+			var err error
+			if redemption, err = applyPromoCode(tx, req.PromoCode, user, field, &booking); err != nil {
+				return err
+			}
+		}
+
+		penalties, err := chargePenalties(tx, &booking)
+		if err != nil {
+			return err
+		}
+		if booking.TotalAmount.IsZero() {
+			booking.Status = models.BookingStatusConfirmed // Nothing left to pay after discounts and penalties
+		}
+
+		if err := insertBooking(tx, &booking); err != nil {
+			return err
+		}
+		if err := markPenaltiesCharged(tx, penalties, booking.ID); err != nil {
+			return err
+		}
+
+		if passRedemption != nil {
+			passRedemption.BookingID = booking.ID
+			if err := tx.Create(passRedemption).Error; err != nil {
+				return err
+			}
+		}
+		if req.RedeemPoints > 0 {
+			if err := redeemPoints(tx, req.RedeemPoints, booking); err != nil {
+				return err
+			}
+		}
+		if redemption != nil {
+			redemption.BookingID = booking.ID
+			if err := tx.Create(redemption).Error; err != nil {
+				return err
+			}
+		}
+		if booking.Status == models.BookingStatusConfirmed {
+			return issueInvoice(tx, &booking) // Nothing left to pay
+		}
+		return nil
+	})
+	if errors.Is(err, errSlotTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errUnknownAddOn) || errors.Is(err, errAddOnOutOfStock) {
+		respondAddOnError(c, err, "Failed to create booking")
+		return
+	}
+	if errors.Is(err, loyalty.ErrInsufficientPoints) || errors.Is(err, loyalty.ErrInvalidRedemption) || errors.Is(err, errTooManyFreeHours) {
+		respondPointsError(c, err, "Failed to create booking")
+		return
+	}
+	if errors.Is(err, errPromoNotFound) || errors.Is(err, promo.ErrNotActive) || errors.Is(err, promo.ErrExhausted) ||
+		errors.Is(err, promo.ErrUserLimit) || errors.Is(err, promo.ErrNotApplicable) {
+		respondPromoError(c, err, "Failed to create booking")
+		return
+	}
+	if usePass && respondPassError(c, err, "Failed to create booking") {
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Booking created successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Booking created successfully", "booking": booking})
+}
+
+var (
+	errSlotTaken      = errors.New("the field is already booked at that time")
+	errNotCancellable = errors.New("only pending or confirmed bookings that haven't started can be cancelled")
+	errBookingPaid    = errors.New("paid bookings are cancelled by refunding their payment")
+	errFixtureBooking = errors.New("tournament fixture bookings can't be cancelled")
+)
+
+// newBooking prices a pending booking of the field for the user with the arena's pricing rules.
+func newBooking(user models.User, field models.Field, start time.Time, duration int) (models.Booking, error) {
+	quote, err := quoteBooking(field, start, duration, user)
+	if err != nil {
+		return models.Booking{}, err
+	}
+
+	return models.Booking{
+		UserID:      user.ID,
+		ArenaID:     field.ArenaID,
+		FieldID:     field.ID,
+		BookingTime: start,
+		Duration:    duration,
+		TotalAmount: quote.Total,
+		Discount:    money.Zero(quote.Total.Currency),
+		Lines:       quote.BookingLines(field.FieldName),
+		Status:      models.BookingStatusPending,
+	}, nil
+}
+
+// insertBooking saves a booking inside a transaction, failing with errSlotTaken when
+// its field is not free. The field row is locked so concurrent bookings can't both pass the check.
+func insertBooking(tx *gorm.DB, booking *models.Booking) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Field{}, booking.FieldID).Error; err != nil {
+		return err
+	}
+
+	available, err := fieldAvailable(tx, booking.FieldID, booking.BookingTime, booking.Duration)
+	if err != nil {
+		return err
+	}
+	if !available {
+		return errSlotTaken
+	}
+
+	return tx.Create(booking).Error
+}
+
+// fieldAvailable reports whether no active booking of the field overlaps the given time.
+func fieldAvailable(tx *gorm.DB, fieldID uint, start time.Time, duration int) (bool, error) {
+	end := start.Add(time.Duration(duration) * time.Hour)
+
+	var count int64
+	err := tx.Model(&models.Booking{}).
+		Where("field_id = ?", fieldID).
+		Scopes(models.ActiveBookingsBetween(start, end)).
+		Count(&count).Error
+	return count == 0, err
+}
+
+// ListMyBookings handles listing the bookings of the current user, including those made for their teams
+func ListMyBookings(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	teamIDs := teamIDsOf(user.ID)
+	var bookings []models.Booking
+	if err := database.DB.Preload("Arena").Preload("Field").Preload("Lines").Preload("AddOns.AddOn").
+		Where("user_id = ? OR team_id IN ? OR opponent_team_id IN ?", user.ID, teamIDs, teamIDs).
+		Order("booking_time DESC").
+		Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bookings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bookings": bookings})
+}
+
+// CancelBooking handles a customer cancelling a booking before it starts. Paid bookings
+// are cancelled by refunding their payment instead. The credit of a pass comes back when
+// the booking is cancelled early enough for the pass plan, and redeemed points always do.
+func CancelBooking(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	bookingID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	restored, points := false, 0
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		if booking.UserID != user.ID {
+			return errNotBookingCustomer
+		}
+		if booking.TournamentID != nil {
+			return errFixtureBooking
+		}
+		now := time.Now()
+		if (booking.Status != models.BookingStatusPending && booking.Status != models.BookingStatusConfirmed) || !booking.BookingTime.After(now) {
+			return errNotCancellable
+		}
+
+		var paid int64
+		if err := tx.Model(&models.Payment{}).Where("booking_id = ?", booking.ID).Count(&paid).Error; err != nil {
+			return err
+		}
+		if paid > 0 {
+			return errBookingPaid
+		}
+
+		if err := tx.Model(&booking).Update("status", models.BookingStatusCancelled).Error; err != nil {
+			return err
+		}
+		if err := releasePenalties(tx, booking); err != nil {
+			return err
+		}
+		if err := releasePromoCode(tx, booking); err != nil {
+			return err
+		}
+		var err error
+		if restored, err = restorePassCredit(tx, booking, now, false); err != nil {
+			return err
+		}
+		points, err = restorePoints(tx, booking, now)
+		return err
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	case errors.Is(err, errNotBookingCustomer):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errNotCancellable), errors.Is(err, errBookingPaid), errors.Is(err, errFixtureBooking):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled", "pass_credit_restored": restored, "points_restored": points})
+}
+
+// canViewBooking reports whether the user may see the booking: its customer, members of
+// the teams playing, the arena owner, arena staff allowed to view bookings and admins.
+// The arena must be loaded.
+func canViewBooking(user models.User, booking models.Booking) bool {
+	if booking.UserID == user.ID {
+		return true
+	}
+	if booking.TeamID != nil && teamRole(*booking.TeamID, user.ID) != "" {
+		return true
+	}
+	if booking.OpponentTeamID != nil && teamRole(*booking.OpponentTeamID, user.ID) != "" {
+		return true
+	}
+	return hasArenaPermission(user, booking.Arena, models.StaffViewBookings)
 }
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/ical"
+	"sparring-backend/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// feedHistory is how far back subscription feeds include past bookings
+const feedHistory = 90 * 24 * time.Hour
+
+// GetBooking handles fetching a booking of the current user. When the ID is
+// suffixed with ".ics" the booking is returned as an iCalendar file.
+func GetBooking(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	rawID, asCalendar := strings.CutSuffix(c.Param("id"), ".ics")
+	bookingID, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var booking models.Booking
+	if err := database.DB.Preload("Arena").Preload("Field").Preload("Lines").Preload("AddOns.AddOn").First(&booking, bookingID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if !canViewBooking(user, booking) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot access this booking"})
+		return
+	}
+
+	if asCalendar {
+		cal := ical.Calendar{Events: []ical.Event{bookingEvent(booking)}}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="booking-%d.ics"`, booking.ID))
+		c.Data(http.StatusOK, "text/calendar; charset=utf-8", cal.Encode())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"booking": booking})
+}
+
+// GetCalendarFeeds handles returning the subscription feed paths of the current user,
+// creating the secret feed token on first use
+func GetCalendarFeeds(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.CalendarToken == nil {
+		if !rotateCalendarToken(c, &user) {
+			return
+		}
+	}
+
+	respondCalendarFeeds(c, user)
+}
+
+// ResetCalendarToken handles replacing the calendar feed token, revoking previously shared feed URLs
+func ResetCalendarToken(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !rotateCalendarToken(c, &user) {
+		return
+	}
+
+	respondCalendarFeeds(c, user)
+}
+
+// UserBookingsFeed handles the iCalendar subscription feed of a user's bookings, team bookings included
+func UserBookingsFeed(c *gin.Context) {
+	user, ok := calendarTokenUser(c)
+	if !ok {
+		return
+	}
+
+	teamIDs := teamIDsOf(user.ID)
+	var bookings []models.Booking
+	if err := database.DB.Preload("Arena").Preload("Field").
+		Where("(user_id = ? OR team_id IN ? OR opponent_team_id IN ?) AND booking_time >= ?", user.ID, teamIDs, teamIDs, time.Now().Add(-feedHistory)).
+		Order("booking_time").
+		Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bookings"})
+		return
+	}
+
+	respondCalendar(c, "My bookings", bookings)
+}
+
+// ArenaBookingsFeed handles the iCalendar subscription feed of every booking on an arena's fields,
+// for the arena owner
+func ArenaBookingsFeed(c *gin.Context) {
+	user, ok := calendarTokenUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadOwnedArena(c, arenaID, user)
+	if !ok {
+		return
+	}
+
+	var bookings []models.Booking
+	if err := database.DB.Preload("Arena").Preload("Field").Preload("User").
+		Where("arena_id = ? AND booking_time >= ?", arena.ID, time.Now().Add(-feedHistory)).
+		Order("booking_time").
+		Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bookings"})
+		return
+	}
+
+	respondCalendar(c, arena.Name+" bookings", bookings)
+}
+
+// calendarTokenUser resolves the user owning the feed token in the URL.
+func calendarTokenUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	token := c.Param("token")
+	if token == "" || database.DB.Where("calendar_token = ?", token).First(&user).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// rotateCalendarToken assigns a new random feed token to the user.
+func rotateCalendarToken(c *gin.Context, user *models.User) bool {
+	token, err := randomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate calendar token"})
+		return false
+	}
+	if err := database.DB.Model(user).Update("calendar_token", token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save calendar token"})
+		return false
+	}
+	user.CalendarToken = &token
+	return true
+}
+
+// respondCalendarFeeds writes the feed paths of the user, including one per owned arena.
+func respondCalendarFeeds(c *gin.Context, user models.User) {
+	base := "/api/calendar/" + *user.CalendarToken
+
+	var arenas []models.Arena
+	database.DB.Where("owner_id = ?", user.ID).Find(&arenas)
+	arenaFeeds := []gin.H{}
+	for _, arena := range arenas {
+		arenaFeeds = append(arenaFeeds, gin.H{
+			"arena_id": arena.ID,
+			"name":     arena.Name,
+			"url":      fmt.Sprintf("%s/arenas/%d/bookings.ics", base, arena.ID),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bookings_url": base + "/bookings.ics",
+		"arenas":       arenaFeeds,
+	})
+}
+
+// respondCalendar writes the bookings as an iCalendar feed.
+func respondCalendar(c *gin.Context, name string, bookings []models.Booking) {
+	cal := ical.Calendar{Name: name}
+	for _, booking := range bookings {
+		cal.Events = append(cal.Events, bookingEvent(booking))
+	}
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", cal.Encode())
+}
+
+// bookingEvent converts a booking, with its arena and field loaded, to a calendar event.
+func bookingEvent(booking models.Booking) ical.Event {
+	summary := fmt.Sprintf("%s - %s at %s", booking.Field.SportType, booking.Field.FieldName, booking.Arena.Name)
+	description := fmt.Sprintf("Booking #%d (%s)\nTotal: %s", booking.ID, booking.Status, booking.TotalAmount)
+	if booking.User.Name != "" {
+		description += "\nBooked by: " + booking.User.Name
+	}
+
+	return ical.Event{
+		UID:         ical.UID("booking", booking.ID),
+		Stamp:       booking.UpdatedAt,
+		Start:       booking.BookingTime,
+		End:         booking.BookingTime.Add(time.Duration(booking.Duration) * time.Hour),
+		Summary:     summary,
+		Location:    booking.Arena.Location,
+		Description: description,
+		Status:      bookingEventStatus(booking.Status),
+	}
+}
+
+// bookingEventStatus maps a booking status to an iCalendar event status.
+func bookingEventStatus(status string) string {
+	switch status {
+	case models.BookingStatusConfirmed, models.BookingStatusCheckedIn, models.BookingStatusCompleted:
+		return ical.StatusConfirmed
+	case models.BookingStatusCancelled, models.BookingStatusRefunded:
+		return ical.StatusCancelled
+	}
+	return ical.StatusTentative
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sparring-backend/auth"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// checkInOpensBefore is how long before the booking starts the customer can check in
+const checkInOpensBefore = time.Hour
+
+var (
+	errCodeReused       = errors.New("check-in code already used")
+	errAlreadyCheckedIn = errors.New("booking already checked in")
+	errNotConfirmed     = errors.New("only confirmed bookings can be checked in")
+	errOutsideWindow    = errors.New("check-in is only possible from one hour before the booking until it ends")
+)
+
+// GetCheckInCode handles issuing a short-lived signed check-in code for a confirmed booking
+// of the current user, as JSON or as a QR code PNG with ?format=png
+func GetCheckInCode(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	bookingID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	if err := database.DB.First(&booking, bookingID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if booking.UserID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot access this booking"})
+		return
+	}
+	if booking.Status != models.BookingStatusConfirmed {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": errNotConfirmed.Error()})
+		return
+	}
+
+	code, expiresAt, err := auth.GenerateCheckInToken(booking.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate check-in code"})
+		return
+	}
+
+	// Codes are short-lived, never let anything cache them
+	c.Header("Cache-Control", "no-store")
+
+	if c.Query("format") == "png" {
+		png, err := qrcode.Encode(code, qrcode.Medium, 320)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render QR code"})
+			return
+		}
+		c.Header("Expires", expiresAt.UTC().Format(http.TimeFormat))
+		c.Data(http.StatusOK, "image/png", png)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": code, "expires_at": expiresAt})
+}
+
+// CheckInBooking handles staff scanning a customer's check-in code at the desk
+func CheckInBooking(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bookingID, nonce, err := auth.ValidateCheckInToken(req.Code)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired check-in code"})
+		return
+	}
+
+	var booking models.Booking
+	if err := database.DB.Preload("Arena").First(&booking, bookingID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if !canCheckIn(user, booking.Arena) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only arena staff can check in bookings"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Burn the code first; the unique nonce rejects replays, even concurrent ones
+		used := models.CheckInNonce{Nonce: nonce, BookingID: booking.ID, UsedAt: time.Now()}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&used)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errCodeReused
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, booking.ID).Error; err != nil {
+			return err
+		}
+		switch booking.Status {
+		case models.BookingStatusConfirmed:
+		case models.BookingStatusCheckedIn:
+			return errAlreadyCheckedIn
+		default:
+			return errNotConfirmed
+		}
+
+		now := time.Now()
+		end := booking.BookingTime.Add(time.Duration(booking.Duration) * time.Hour)
+		if now.Before(booking.BookingTime.Add(-checkInOpensBefore)) || now.After(end) {
+			return errOutsideWindow
+		}
+
+		return tx.Model(&booking).Updates(map[string]interface{}{
+			"status":        models.BookingStatusCheckedIn,
+			"checked_in_at": now,
+			"checked_in_by": user.ID,
+		}).Error
+	})
+
+	switch {
+	case errors.Is(err, errCodeReused), errors.Is(err, errAlreadyCheckedIn):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errNotConfirmed), errors.Is(err, errOutsideWindow):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in booking"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Booking checked in successfully", "booking_id": booking.ID})
+	}
+}
+
+// canCheckIn reports whether the user may check in bookings at the arena: admins, its owner
+// and its staff with the check-in permission.
+func canCheckIn(user models.User, arena models.Arena) bool {
+	return hasArenaPermission(user, arena, models.StaffCheckIn)
+}
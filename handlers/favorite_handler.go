@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/geo"
+	"sparring-backend/internal/jobs"
+	"sparring-backend/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Limits keeping the watch job cheap
+const (
+	maxFieldWatches     = 20
+	maxWatchWindowHours = 14 * 24
+)
+
+// AddFavorite handles the current user favoriting an arena, or a single field with field_id
+func AddFavorite(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ArenaID uint `json:"arena_id"`
+		FieldID uint `json:"field_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	favorite := models.Favorite{UserID: user.ID, ArenaID: req.ArenaID}
+	switch {
+	case req.FieldID != 0:
+		var field models.Field
+		if err := database.DB.First(&field, req.FieldID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Field not found"})
+			return
+		}
+		favorite.ArenaID, favorite.FieldID = field.ArenaID, &field.ID
+	case req.ArenaID != 0:
+		if err := database.DB.First(&models.Arena{}, req.ArenaID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Arena not found"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "arena_id or field_id is required"})
+		return
+	}
+
+	// Favoriting twice returns the existing favorite
+	err := favoriteQuery(user.ID, favorite.ArenaID, favorite.FieldID).First(&favorite).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = database.DB.Create(&favorite).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save favorite"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Added to favorites", "favorite": favorite})
+}
+
+// ListFavorites handles listing the current user's favorite arenas and fields
+func ListFavorites(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var favorites []models.Favorite
+	if err := database.DB.Preload("Arena").Preload("Field").Where("user_id = ?", user.ID).
+		Order("created_at DESC").Find(&favorites).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load favorites"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"favorites": favorites})
+}
+
+// RemoveFavorite handles removing one of the current user's favorites
+func RemoveFavorite(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	favoriteID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("id = ? AND user_id = ?", favoriteID, user.ID).Delete(&models.Favorite{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove favorite"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Favorite not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Removed from favorites"})
+}
+
+// CreateSavedSearch handles saving search filters for the current user
+func CreateSavedSearch(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var saved models.SavedSearch
+	if err := c.ShouldBindJSON(&saved); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	saved.ID = 0
+	saved.UserID = user.ID
+
+	switch {
+	case saved.Name == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	case saved.Query == "" && saved.SportType == "" && saved.Latitude == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Save at least a query, a sport or a location"})
+		return
+	case (saved.Latitude == nil) != (saved.Longitude == nil):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Latitude and longitude must be set together"})
+		return
+	case saved.Latitude != nil && !(geo.Point{Lat: *saved.Latitude, Lng: *saved.Longitude}).Valid():
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Coordinates are out of range"})
+		return
+	case saved.RadiusKm != nil && (*saved.RadiusKm <= 0 || *saved.RadiusKm > maxSearchRadiusKm):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "radius_km must be between 0 and 500"})
+		return
+	}
+
+	if err := database.DB.Create(&saved).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Search saved successfully", "saved_search": saved})
+}
+
+// ListSavedSearches handles listing the current user's saved searches
+func ListSavedSearches(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var searches []models.SavedSearch
+	if err := database.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&searches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load saved searches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"saved_searches": searches})
+}
+
+// DeleteSavedSearch handles deleting one of the current user's saved searches
+func DeleteSavedSearch(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	searchID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("id = ? AND user_id = ?", searchID, user.ID).Delete(&models.SavedSearch{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved search"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted"})
+}
+
+// CreateFieldWatch handles the current user asking to be notified when a favorited field
+// frees up for duration hours between starts_at and ends_at
+func CreateFieldWatch(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		FieldID  uint      `json:"field_id" binding:"required"`
+		StartsAt time.Time `json:"starts_at" binding:"required"`
+		EndsAt   time.Time `json:"ends_at" binding:"required"`
+		Duration int       `json:"duration" binding:"required,min=1,max=24"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	window := req.EndsAt.Sub(req.StartsAt)
+	if !req.EndsAt.After(time.Now()) || window < time.Duration(req.Duration)*time.Hour || window > maxWatchWindowHours*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The window must end in the future, fit the duration and last at most 14 days"})
+		return
+	}
+
+	var field models.Field
+	if err := database.DB.First(&field, req.FieldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Field not found"})
+		return
+	}
+	if err := favoriteQuery(user.ID, field.ArenaID, &field.ID).First(&models.Favorite{}).Error; err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Add the field to your favorites first"})
+		return
+	}
+
+	var active int64
+	database.DB.Model(&models.FieldWatch{}).Where("user_id = ? AND ends_at > ?", user.ID, time.Now()).Count(&active)
+	if active >= maxFieldWatches {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "You are watching too many fields already"})
+		return
+	}
+
+	watch := models.FieldWatch{
+		UserID:   user.ID,
+		FieldID:  field.ID,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Duration: req.Duration,
+	}
+	// Only changes from here on are notified
+	slot, available, err := jobs.FreeSlot(database.DB, watch, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability"})
+		return
+	}
+	watch.Available = available
+	if err := database.DB.Create(&watch).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save watch"})
+		return
+	}
+
+	response := gin.H{"message": "You will be notified when the field frees up", "watch": watch}
+	if available {
+		response["free_from"] = slot
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ListFieldWatches handles listing the current user's watches that haven't ended
+func ListFieldWatches(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var watches []models.FieldWatch
+	if err := database.DB.Where("user_id = ? AND ends_at > ?", user.ID, time.Now()).Order("starts_at").Find(&watches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load watches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"watches": watches})
+}
+
+// DeleteFieldWatch handles cancelling one of the current user's watches
+func DeleteFieldWatch(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	watchID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("id = ? AND user_id = ?", watchID, user.ID).Delete(&models.FieldWatch{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete watch"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watch not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Watch deleted"})
+}
+
+// favoriteQuery selects the user's favorite of the arena itself, or of one of its fields.
+func favoriteQuery(userID, arenaID uint, fieldID *uint) *gorm.DB {
+	query := database.DB.Where("user_id = ? AND arena_id = ?", userID, arenaID)
+	if fieldID != nil {
+		return query.Where("field_id = ?", *fieldID)
+	}
+	return query.Where("field_id IS NULL")
+}
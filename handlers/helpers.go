@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUser returns the user stored in the context by the authentication middleware.
+// When it is missing, an error response is written and false is returned.
+func currentUser(c *gin.Context) (models.User, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User context not found"})
+		return models.User{}, false
+	}
+
+	currentUser, ok := user.(models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return models.User{}, false
+	}
+	return currentUser, true
+}
+
+// paramID parses a numeric path parameter, writing a 400 response when it is invalid.
+func paramID(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// loadOwnedArena loads the arena with the given ID and checks the user owns it.
+// When it doesn't, an error response is written and false is returned.
+func loadOwnedArena(c *gin.Context, arenaID uint, user models.User) (models.Arena, bool) {
+	var arena models.Arena
+	if err := database.DB.First(&arena, arenaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Arena not found"})
+		return models.Arena{}, false
+	}
+	if arena.OwnerID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not own this arena"})
+		return models.Arena{}, false
+	}
+	return arena, true
+}
+
+// loadStaffArena loads the arena with the given ID and checks the user owns it or is on its
+// staff with the permission. When they aren't, an error response is written and false is returned.
+func loadStaffArena(c *gin.Context, arenaID uint, user models.User, permission string) (models.Arena, bool) {
+	var arena models.Arena
+	if err := database.DB.First(&arena, arenaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Arena not found"})
+		return models.Arena{}, false
+	}
+	if !hasArenaPermission(user, arena, permission) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this arena"})
+		return models.Arena{}, false
+	}
+	return arena, true
+}
+
+// hasArenaPermission reports whether the user may act on the arena with the staff permission:
+// admins and the owner always can, staff members only when they were granted it.
+func hasArenaPermission(user models.User, arena models.Arena, permission string) bool {
+	if user.Role == "admin" || arena.OwnerID == user.ID {
+		return true
+	}
+	var staff models.ArenaStaff
+	if err := database.DB.Where("arena_id = ? AND user_id = ?", arena.ID, user.ID).First(&staff).Error; err != nil {
+		return false
+	}
+	return staff.Has(permission)
+}
+
+// requireAdmin checks the user is a platform administrator, writing a 403 response otherwise.
+func requireAdmin(c *gin.Context, user models.User) bool {
+	if user.Role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return false
+	}
+	return true
+}
+
+// randomToken returns a URL safe random token of n bytes of entropy, hex encoded.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/invoice"
+	"sparring-backend/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetBookingInvoice handles fetching the invoice of a paid booking, as JSON or as a PDF
+// when requested with ?format=pdf or an "Accept: application/pdf" header
+func GetBookingInvoice(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	bookingID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	if err := database.DB.Preload("Arena").First(&booking, bookingID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	// Only the people who can see the booking can see its invoice
+	if !canViewBooking(user, booking) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot access this invoice"})
+		return
+	}
+
+	var inv models.Invoice
+	err := database.DB.Preload("Lines").Where("booking_id = ?", booking.ID).First(&inv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This booking has not been paid yet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load invoice"})
+		return
+	}
+
+	if c.Query("format") == "pdf" || c.NegotiateFormat(gin.MIMEJSON, "application/pdf") == "application/pdf" {
+		var customer models.User
+		if err := database.DB.First(&customer, inv.UserID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load customer"})
+			return
+		}
+
+		c.Header("Content-Disposition", `inline; filename="`+inv.Number+`.pdf"`)
+		c.Data(http.StatusOK, "application/pdf", invoice.RenderPDF(inv, booking.Arena, customer))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invoice": inv})
+}
+
+// issueInvoice creates the invoice of a booking that has just been paid. It must run
+// inside the payment transaction and does nothing if the booking already has one.
+func issueInvoice(tx *gorm.DB, booking *models.Booking) error {
+	var count int64
+	if err := tx.Model(&models.Invoice{}).Where("booking_id = ?", booking.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	// Lock the arena so concurrent payments get distinct sequence numbers
+	var arena models.Arena
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&arena, booking.ArenaID).Error; err != nil {
+		return err
+	}
+
+	var lastSequence int
+	if err := tx.Model(&models.Invoice{}).
+		Where("arena_id = ?", arena.ID).
+		Select("COALESCE(MAX(sequence), 0)").
+		Scan(&lastSequence).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(booking).Association("Lines").Find(&booking.Lines); err != nil {
+		return err
+	}
+
+	inv := invoice.Build(*booking, arena, lastSequence+1, time.Now())
+	return tx.Create(&inv).Error
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/ledger"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/notify"
+	"sparring-backend/internal/reporting"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errPayoutNotPending = errors.New("only pending payouts can be marked as sent or failed")
+
+// statementEntry is a movement of an owner's balance
+type statementEntry struct {
+	TransactionID uint
+	Kind          string
+	Description   string
+	BookingID     *uint
+	PayoutID      *uint
+	OccurredAt    time.Time
+	Currency      string
+	Amount        int64 // Credited to the owner, negative when taken from their balance
+}
+
+// GetMyStatement handles an arena owner's statement for a period (from and to, inclusive UTC
+// dates, the last 30 days by default): opening balance, every revenue, commission, refund and
+// payout movement, and closing balance per currency, as JSON or CSV with ?format=csv
+func GetMyStatement(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	rng, err := reporting.ParseRange(c.Query("from"), c.Query("to"), time.UTC, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var opening []struct {
+		Currency string
+		Balance  int64
+	}
+	if err := ownerPayable(user.ID).
+		Where("ledger_transactions.occurred_at < ?", rng.Start).
+		Select("ledger_postings.amount_currency AS currency, -SUM(ledger_postings.amount_minor) AS balance").
+		Group("ledger_postings.amount_currency").
+		Scan(&opening).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build statement"})
+		return
+	}
+
+	var entries []statementEntry
+	if err := ownerPayable(user.ID).
+		Where("ledger_transactions.occurred_at >= ? AND ledger_transactions.occurred_at < ?", rng.Start, rng.End).
+		Select("ledger_transactions.id AS transaction_id, ledger_transactions.kind, ledger_transactions.description, " +
+			"ledger_transactions.booking_id, ledger_transactions.payout_id, ledger_transactions.occurred_at, " +
+			"ledger_postings.amount_currency AS currency, -ledger_postings.amount_minor AS amount").
+		Order("ledger_transactions.occurred_at, ledger_transactions.id").
+		Scan(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build statement"})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		rows := make([][]string, 0, len(entries))
+		for _, entry := range entries {
+			rows = append(rows, []string{
+				entry.OccurredAt.UTC().Format(time.RFC3339),
+				entry.Kind,
+				entry.Description,
+				money.New(entry.Amount, entry.Currency).Decimal(),
+				entry.Currency,
+			})
+		}
+		writeCSV(c, "statement-"+rng.Start.Format("2006-01-02")+".csv", []string{"date", "kind", "description", "amount", "currency"}, rows)
+		return
+	}
+
+	// Group the movements per currency, starting from the opening balances
+	type currencyStatement struct {
+		Currency string      `json:"currency"`
+		Opening  money.Money `json:"opening_balance"`
+		Earned   money.Money `json:"earned"`   // Booking revenue after commission
+		Refunded money.Money `json:"refunded"` // Taken back for refunds
+		PaidOut  money.Money `json:"paid_out"` // Moved to payouts, net of failed ones
+		Closing  money.Money `json:"closing_balance"`
+		Entries  []gin.H     `json:"entries"`
+		order    int
+	}
+	statements := map[string]*currencyStatement{}
+	get := func(currency string) *currencyStatement {
+		if s, ok := statements[currency]; ok {
+			return s
+		}
+		zero := money.Zero(currency)
+		s := &currencyStatement{Currency: currency, Opening: zero, Earned: zero, Refunded: zero, PaidOut: zero, Closing: zero, Entries: []gin.H{}, order: len(statements)}
+		statements[currency] = s
+		return s
+	}
+	for _, row := range opening {
+		s := get(row.Currency)
+		s.Opening, s.Closing = money.New(row.Balance, row.Currency), money.New(row.Balance, row.Currency)
+	}
+	for _, entry := range entries {
+		s := get(entry.Currency)
+		amount := money.New(entry.Amount, entry.Currency)
+		switch entry.Kind {
+		case models.LedgerBookingPayment:
+			s.Earned, _ = s.Earned.Add(amount)
+		case models.LedgerRefund:
+			s.Refunded, _ = s.Refunded.Sub(amount)
+		default:
+			s.PaidOut, _ = s.PaidOut.Sub(amount)
+		}
+		s.Closing, _ = s.Closing.Add(amount)
+		s.Entries = append(s.Entries, gin.H{
+			"transaction_id": entry.TransactionID,
+			"kind":           entry.Kind,
+			"description":    entry.Description,
+			"booking_id":     entry.BookingID,
+			"payout_id":      entry.PayoutID,
+			"occurred_at":    entry.OccurredAt,
+			"amount":         amount,
+		})
+	}
+
+	result := make([]*currencyStatement, len(statements))
+	for _, s := range statements {
+		result[s.order] = s
+	}
+	c.JSON(http.StatusOK, gin.H{"from": rng.Start, "to": rng.End, "statements": result})
+}
+
+// ListMyPayouts handles listing the payouts of the current user, most recent first
+func ListMyPayouts(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var payouts []models.Payout
+	if err := database.DB.Where("owner_id = ?", user.ID).Order("id DESC").Limit(100).Find(&payouts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load payouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payouts": payouts})
+}
+
+// ListPayouts handles admins listing payouts, optionally with a status
+func ListPayouts(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok || !requireAdmin(c, user) {
+		return
+	}
+
+	query := database.DB.Order("id")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var payouts []models.Payout
+	if err := query.Limit(500).Find(&payouts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load payouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payouts": payouts})
+}
+
+// MarkPayoutSent handles an admin confirming the transfer of a payout, with its reference
+func MarkPayoutSent(c *gin.Context) {
+	var req struct {
+		Reference string `json:"reference" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	settlePayout(c, models.PayoutSent, req.Reference)
+}
+
+// MarkPayoutFailed handles an admin recording a failed transfer. The amount goes back to
+// the owner's balance and is paid out again by the next batch.
+func MarkPayoutFailed(c *gin.Context) {
+	settlePayout(c, models.PayoutFailed, "")
+}
+
+// settlePayout moves a pending payout to its final status and records it in the ledger.
+func settlePayout(c *gin.Context, status, reference string) {
+	user, ok := currentUser(c)
+	if !ok || !requireAdmin(c, user) {
+		return
+	}
+	payoutID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var payout models.Payout
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Owner").First(&payout, payoutID).Error; err != nil {
+			return err
+		}
+		if payout.Status != models.PayoutPending {
+			return errPayoutNotPending
+		}
+
+		now := time.Now()
+		kind := models.LedgerPayoutFailed
+		updates := map[string]interface{}{"status": status}
+		if status == models.PayoutSent {
+			kind = models.LedgerPayoutSent
+			updates["reference"], updates["sent_at"] = reference, now
+		}
+		if err := tx.Model(&payout).Updates(updates).Error; err != nil {
+			return err
+		}
+		txn := ledger.PayoutTransaction(payout, kind, now)
+		return ledger.Post(tx, &txn)
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payout not found"})
+		return
+	case errors.Is(err, errPayoutNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payout"})
+		return
+	}
+
+	subject, body := "Your payout was sent", "We sent payout #"+strconv.FormatUint(uint64(payout.ID), 10)+" of "+payout.Amount.String()+" (reference "+reference+")."
+	if status == models.PayoutFailed {
+		subject, body = "Your payout failed", "Payout #"+strconv.FormatUint(uint64(payout.ID), 10)+" of "+payout.Amount.String()+" could not be sent. The amount is back in your balance and will be paid out again."
+	}
+	notify.Send(c.Request.Context(), notify.Message{UserID: payout.OwnerID, Email: payout.Owner.Email, Subject: subject, Body: body})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payout updated", "payout": payout})
+}
+
+// SetArenaCommission handles an admin setting the commission rate of an arena in basis points,
+// or resetting it to the platform default with null. Only later payments use the new rate.
+func SetArenaCommission(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok || !requireAdmin(c, user) {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CommissionBps *int64 `json:"commission_bps" binding:"omitempty,min=0,max=10000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var arena models.Arena
+	if err := database.DB.First(&arena, arenaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Arena not found"})
+		return
+	}
+	if err := database.DB.Model(&arena).Update("commission_bps", req.CommissionBps).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update commission"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Commission updated", "arena_id": arena.ID, "commission_bps": ledger.CommissionBps(models.Arena{CommissionBps: req.CommissionBps})})
+}
+
+// GetLedgerReconciliation handles admins checking the ledger against itself and the payment
+// records: unbalanced transactions, payments whose net amount differs from the cash posted
+// for them, paid bookings without a payment and flagged payments waiting for a refund,
+// plus the balance of every account
+func GetLedgerReconciliation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok || !requireAdmin(c, user) {
+		return
+	}
+
+	var unbalanced []struct {
+		TransactionID uint   `json:"transaction_id"`
+		Currency      string `json:"currency"`
+		Difference    int64  `json:"difference_minor"`
+	}
+	if err := database.DB.Model(&models.LedgerPosting{}).
+		Select("transaction_id, amount_currency AS currency, SUM(amount_minor) AS difference").
+		Group("transaction_id, amount_currency").
+		Having("SUM(amount_minor) <> 0").
+		Limit(100).Scan(&unbalanced).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile ledger"})
+		return
+	}
+
+	var payments []struct {
+		PaymentID uint   `json:"payment_id"`
+		BookingID uint   `json:"booking_id"`
+		Currency  string `json:"currency"`
+		Expected  int64  `json:"expected_minor"` // Collected minus refunded
+		Posted    int64  `json:"posted_minor"`   // Net cash in the ledger
+	}
+	if err := database.DB.Table("payments").
+		Select("payments.id AS payment_id, payments.booking_id, payments.amount_currency AS currency, "+
+			"payments.amount_minor - payments.refunded_minor AS expected, COALESCE(SUM(ledger_postings.amount_minor), 0) AS posted").
+		Joins("LEFT JOIN ledger_transactions ON ledger_transactions.payment_id = payments.id").
+		Joins("LEFT JOIN ledger_postings ON ledger_postings.transaction_id = ledger_transactions.id AND ledger_postings.account = ?", ledger.AccountCash).
+		Where("payments.deleted_at IS NULL").
+		Group("payments.id, payments.booking_id, payments.amount_currency, payments.amount_minor, payments.refunded_minor").
+		Having("expected <> posted").
+		Limit(100).Scan(&payments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile ledger"})
+		return
+	}
+
+	var unpaid []uint
+	if err := database.DB.Model(&models.Booking{}).
+		Where("status IN ? AND total_amount_minor > 0", paidBookingStatuses).
+		Where("NOT EXISTS (SELECT 1 FROM payments WHERE payments.booking_id = bookings.id AND payments.deleted_at IS NULL)").
+		Limit(100).Pluck("id", &unpaid).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile ledger"})
+		return
+	}
+
+	var flagged []models.Payment
+	if err := database.DB.Where("status = ?", models.PaymentStatusFlagged).Order("id").Limit(100).Find(&flagged).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile ledger"})
+		return
+	}
+
+	var accounts []struct {
+		Account  string `json:"account"`
+		Currency string `json:"currency"`
+		Balance  int64  `json:"balance_minor"` // Debits minus credits
+	}
+	if err := database.DB.Model(&models.LedgerPosting{}).
+		Select("account, amount_currency AS currency, SUM(amount_minor) AS balance").
+		Group("account, amount_currency").Order("account, amount_currency").
+		Scan(&accounts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile ledger"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reconciled":                    len(unbalanced) == 0 && len(payments) == 0 && len(unpaid) == 0,
+		"unbalanced_transactions":       unbalanced,
+		"mismatched_payments":           payments,
+		"paid_bookings_without_payment": unpaid,
+		"flagged_payments":              flagged,
+		"accounts":                      accounts,
+	})
+}
+
+// ownerPayable returns a query on the owner's payable postings joined with their transactions.
+func ownerPayable(ownerID uint) *gorm.DB {
+	return database.DB.Model(&models.LedgerPosting{}).
+		Joins("JOIN ledger_transactions ON ledger_transactions.id = ledger_postings.transaction_id").
+		Where("ledger_postings.account = ? AND ledger_postings.owner_id = ?", ledger.AccountOwnerPayable, ownerID)
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/loyalty"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errPointsWithPass   = errors.New("loyalty points can't be combined with a pass")
+	errTooManyFreeHours = errors.New("you can't redeem more free hours than the booking lasts")
+)
+
+// GetMyPoints handles the current user's loyalty balance, the points expiring in the next
+// 30 days, their referral code and the latest movements of their points
+func GetMyPoints(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	pointsOverview(c, user)
+}
+
+// ListMyReferrals handles listing the users who registered with the current user's referral code
+func ListMyReferrals(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var referrals []models.Referral
+	if err := database.DB.Preload("Referred").Where("referrer_id = ?", user.ID).Order("id DESC").Find(&referrals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load referrals"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(referrals))
+	for _, referral := range referrals {
+		result = append(result, gin.H{
+			"name":        referral.Referred.Name,
+			"status":      referral.Status,
+			"joined_at":   referral.CreatedAt,
+			"rewarded_at": referral.RewardedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"referral_code": user.ReferralCode, "referrals": result})
+}
+
+// GetUserPoints handles admins auditing the loyalty points of a user
+func GetUserPoints(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok || !requireAdmin(c, user) {
+		return
+	}
+	userID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var target models.User
+	if err := database.DB.First(&target, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	pointsOverview(c, target)
+}
+
+// AdjustUserPoints handles admins adding points to a user's balance, or taking them away
+// with a negative amount, with the reason recorded in the audit
+func AdjustUserPoints(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok || !requireAdmin(c, user) {
+		return
+	}
+	userID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Points int    `json:"points" binding:"required"`
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var target models.User
+	if err := database.DB.First(&target, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	token, err := randomToken(8)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust points"})
+		return
+	}
+
+	now := time.Now()
+	entry := models.PointsEntry{
+		UserID:      target.ID,
+		Kind:        models.PointsAdjustment,
+		Points:      req.Points,
+		Reference:   "adjustment:" + token,
+		CreatedByID: &user.ID,
+		Description: req.Reason,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if entry.Points < 0 {
+			return loyalty.Debit(tx, &entry, now)
+		}
+		expiresAt := now.Add(loyalty.Default.Expiry)
+		entry.ExpiresAt = &expiresAt
+		_, err := loyalty.Credit(tx, &entry)
+		return err
+	})
+	if errors.Is(err, loyalty.ErrInsufficientPoints) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust points"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Points adjusted", "entry": entry})
+}
+
+// pointsOverview writes the loyalty balance and the latest point movements of the user.
+func pointsOverview(c *gin.Context, user models.User) {
+	now := time.Now()
+	balance, err := loyalty.Balance(database.DB, user.ID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load points"})
+		return
+	}
+	var expiring int
+	if err := database.DB.Model(&models.PointsEntry{}).
+		Where("user_id = ? AND remaining > 0 AND expires_at > ? AND expires_at <= ?", user.ID, now, now.AddDate(0, 0, 30)).
+		Select("COALESCE(SUM(remaining), 0)").Scan(&expiring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load points"})
+		return
+	}
+	var entries []models.PointsEntry
+	if err := database.DB.Where("user_id = ?", user.ID).Order("id DESC").Limit(100).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load points"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance":              balance,
+		"expiring_in_30_days":  expiring,
+		"points_per_free_hour": loyalty.Default.PointsPerFreeHour,
+		"referral_code":        user.ReferralCode,
+		"entries":              entries,
+	})
+}
+
+// applyPoints takes the price of free hours of field time off a booking for the given
+// points, starting with the first hours. The points themselves are debited by
+// redeemPoints once the booking is saved.
+func applyPoints(points int, booking *models.Booking) error {
+	hours, err := loyalty.Default.FreeHours(points)
+	if err != nil {
+		return err
+	}
+	if hours > booking.Duration {
+		return errTooManyFreeHours
+	}
+
+	discount := money.Zero(booking.TotalAmount.Currency)
+	free := 0
+	for _, line := range booking.Lines {
+		if line.Kind != models.BookingLineHour || free == hours {
+			continue
+		}
+		if discount, err = discount.Add(line.Amount); err != nil {
+			return err
+		}
+		free++
+	}
+	total, err := booking.TotalAmount.Sub(discount)
+	if err != nil {
+		return err
+	}
+	booking.TotalAmount = total
+	booking.FreeHours = hours
+	booking.Lines = append(booking.Lines, models.BookingLine{
+		Kind:        models.BookingLinePoints,
+		Description: fmt.Sprintf("%d loyalty points, %d free hours", points, hours),
+		Quantity:    1,
+		UnitPrice:   discount.Neg(),
+		Amount:      discount.Neg(),
+	})
+	return nil
+}
+
+// redeemPoints debits the points used for a saved booking inside its transaction.
+func redeemPoints(tx *gorm.DB, points int, booking models.Booking) error {
+	bookingID := booking.ID
+	return loyalty.Debit(tx, &models.PointsEntry{
+		UserID:      booking.UserID,
+		Kind:        models.PointsRedeemed,
+		Points:      -points,
+		Reference:   "redeem:" + strconv.FormatUint(uint64(booking.ID), 10),
+		BookingID:   &bookingID,
+		Description: fmt.Sprintf("Booking #%d", booking.ID),
+	}, time.Now())
+}
+
+// restorePoints gives the points redeemed for a cancelled or refunded booking back to the
+// lots they came from, with their expiry dates. It reports how many points came back.
+func restorePoints(tx *gorm.DB, booking models.Booking, now time.Time) (int, error) {
+	var redeemed models.PointsEntry
+	err := tx.Where("reference = ?", "redeem:"+strconv.FormatUint(uint64(booking.ID), 10)).First(&redeemed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	bookingID := booking.ID
+	return loyalty.Restore(tx, redeemed, &models.PointsEntry{
+		UserID:      booking.UserID,
+		Kind:        models.PointsRestored,
+		Reference:   "restore:" + strconv.FormatUint(uint64(booking.ID), 10),
+		BookingID:   &bookingID,
+		Description: fmt.Sprintf("Booking #%d cancelled", booking.ID),
+	}, now)
+}
+
+// respondPointsError writes the response for points that can't be redeemed for a booking
+// and reports whether there was an error.
+func respondPointsError(c *gin.Context, err error, fallback string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, loyalty.ErrInsufficientPoints), errors.Is(err, loyalty.ErrInvalidRedemption),
+		errors.Is(err, errTooManyFreeHours):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+	return true
+}
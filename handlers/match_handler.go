@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errMatchClosed    = errors.New("this match request is no longer open")
+	errOwnMatch       = errors.New("a team cannot accept its own match request")
+	errNotEligible    = errors.New("your team does not fit this match request")
+	errNoFieldFree    = errors.New("no field is available in the requested windows")
+	errInvalidWindows = errors.New("each window must end in the future, be at least as long as the match, last at most a day and start within 60 days")
+)
+
+// Bounds of the windows of a match request, which acceptance searches for a free slot
+const (
+	maxMatchWindow  = 24 * time.Hour
+	maxMatchHorizon = 60 * 24 * time.Hour
+)
+
+// CreateMatchRequest handles a captain posting an open challenge for their team
+func CreateMatchRequest(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TeamID    uint   `json:"team_id" binding:"required"`
+		MinRating int    `json:"min_rating" binding:"min=0"`
+		MaxRating int    `json:"max_rating" binding:"min=0"`
+		Duration  int    `json:"duration" binding:"required,min=1"`
+		ArenaIDs  []uint `json:"arena_ids"` // Preferred arenas, empty for any
+		Windows   []struct {
+			StartsAt time.Time `json:"starts_at" binding:"required"`
+			EndsAt   time.Time `json:"ends_at" binding:"required"`
+		} `json:"windows" binding:"required,min=1,max=10,dive"` // Bounded, since acceptance searches every slot
+		Message string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.MaxRating > 0 && req.MaxRating < req.MinRating {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_rating is below min_rating"})
+		return
+	}
+	if !requireCaptain(c, req.TeamID, user) {
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, req.TeamID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	request := models.MatchRequest{
+		TeamID:      team.ID,
+		CreatedByID: user.ID,
+		SportType:   team.SportType,
+		MinRating:   req.MinRating,
+		MaxRating:   req.MaxRating,
+		Duration:    req.Duration,
+		Message:     req.Message,
+		Status:      models.MatchRequestOpen,
+	}
+	now := time.Now()
+	for _, window := range req.Windows {
+		length := window.EndsAt.Sub(window.StartsAt)
+		if !window.EndsAt.After(now) || length < time.Duration(req.Duration)*time.Hour || length > maxMatchWindow || window.StartsAt.After(now.Add(maxMatchHorizon)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidWindows.Error()})
+			return
+		}
+		request.Windows = append(request.Windows, models.MatchRequestWindow{StartsAt: window.StartsAt, EndsAt: window.EndsAt})
+	}
+
+	if len(req.ArenaIDs) > 0 {
+		if err := database.DB.Find(&request.Arenas, req.ArenaIDs).Error; err != nil || len(request.Arenas) != len(req.ArenaIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown arena in arena_ids"})
+			return
+		}
+	}
+
+	if err := database.DB.Create(&request).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create match request"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Match request created successfully", "match_request": request})
+}
+
+// ListMatchRequests handles listing open match requests that can still be played,
+// optionally filtered by sport_type
+func ListMatchRequests(c *gin.Context) {
+	query := database.DB.Preload("Team").Preload("Arenas").Preload("Windows").
+		Where("status = ?", models.MatchRequestOpen).
+		Where("id IN (?)", database.DB.Model(&models.MatchRequestWindow{}).Select("match_request_id").Where("ends_at > ?", time.Now()))
+	if sport := c.Query("sport_type"); sport != "" {
+		query = query.Where("sport_type = ?", sport)
+	}
+
+	var requests []models.MatchRequest
+	if err := query.Order("created_at DESC").Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load match requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"match_requests": requests})
+}
+
+// GetMatchRequest handles fetching a match request
+func GetMatchRequest(c *gin.Context) {
+	requestID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var request models.MatchRequest
+	if err := database.DB.Preload("Team").Preload("Arenas").Preload("Windows").First(&request, requestID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match request not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"match_request": request})
+}
+
+// CancelMatchRequest handles the challenging team's captain withdrawing an open match request
+func CancelMatchRequest(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	requestID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var request models.MatchRequest
+	if err := database.DB.First(&request, requestID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match request not found"})
+		return
+	}
+	if !requireCaptain(c, request.TeamID, user) {
+		return
+	}
+
+	result := database.DB.Model(&request).Where("status = ?", models.MatchRequestOpen).Update("status", models.MatchRequestCancelled)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel match request"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": errMatchClosed.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Match request cancelled"})
+}
+
+// AcceptMatchRequest handles a captain accepting a challenge for their team. Acceptance
+// atomically books the first available field in the requested windows for both teams.
+func AcceptMatchRequest(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	requestID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TeamID uint `json:"team_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !requireCaptain(c, req.TeamID, user) {
+		return
+	}
+
+	var opponent models.Team
+	if err := database.DB.First(&opponent, req.TeamID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	var request models.MatchRequest
+	var booking models.Booking
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the request so two teams can't accept it at the same time
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Team").Preload("Arenas").Preload("Windows").
+			First(&request, requestID).Error; err != nil {
+			return err
+		}
+		if request.Status != models.MatchRequestOpen {
+			return errMatchClosed
+		}
+		if request.TeamID == opponent.ID {
+			return errOwnMatch
+		}
+		if !matchEligible(request, opponent) {
+			return errNotEligible
+		}
+
+		var challenger models.User
+		if err := tx.First(&challenger, request.CreatedByID).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if booking, err = bookMatch(tx, request, challenger, opponent); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&request).Updates(map[string]interface{}{
+			"status":           models.MatchRequestMatched,
+			"opponent_team_id": opponent.ID,
+			"booking_id":       booking.ID,
+			"matched_at":       now,
+		}).Error
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match request not found"})
+		return
+	case errors.Is(err, errMatchClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errOwnMatch), errors.Is(err, errNotEligible), errors.Is(err, errNoFieldFree):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept match request"})
+		return
+	}
+
+	// Let both captains know where and when they play
+	when := booking.BookingTime.Format(time.RFC1123)
+	notifyTeamCaptains(request.TeamID, "Your challenge was accepted",
+		fmt.Sprintf("%s accepted your challenge. Booking #%d on %s is waiting for payment.", opponent.Name, booking.ID, when))
+	notifyTeamCaptains(opponent.ID, "Match booked",
+		fmt.Sprintf("You play %s on %s (booking #%d).", request.Team.Name, when, booking.ID))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Match request accepted", "booking": booking})
+}
+
+// matchEligible reports whether the team fits the sport and skill range of the request.
+func matchEligible(request models.MatchRequest, team models.Team) bool {
+	if team.SportType != request.SportType {
+		return false
+	}
+	if request.MinRating > 0 && team.Rating < request.MinRating {
+		return false
+	}
+	if request.MaxRating > 0 && team.Rating > request.MaxRating {
+		return false
+	}
+	return true
+}
+
+// bookMatch books the earliest free slot on a field of the requested sport, in the preferred
+// arenas if any, within the request windows. The booking is billed to the challenger.
+func bookMatch(tx *gorm.DB, request models.MatchRequest, challenger models.User, opponent models.Team) (models.Booking, error) {
+	query := tx.Where("sport_type = ?", request.SportType)
+	if len(request.Arenas) > 0 {
+		arenaIDs := make([]uint, 0, len(request.Arenas))
+		for _, arena := range request.Arenas {
+			arenaIDs = append(arenaIDs, arena.ID)
+		}
+		query = query.Where("arena_id IN ?", arenaIDs)
+	}
+	var fields []models.Field
+	if err := query.Order("arena_id, id").Find(&fields).Error; err != nil {
+		return models.Booking{}, err
+	}
+
+	if len(fields) == 0 || len(request.Windows) == 0 {
+		return models.Booking{}, errNoFieldFree
+	}
+
+	windows := append([]models.MatchRequestWindow(nil), request.Windows...)
+	sort.Slice(windows, func(i, j int) bool { return windows[i].StartsAt.Before(windows[j].StartsAt) })
+
+	// Load the bookings of every candidate field across the windows at once, then look for a
+	// gap in memory. insertBooking checks the chosen slot again under the field lock.
+	from, to := windows[0].StartsAt, windows[0].EndsAt
+	for _, window := range windows[1:] {
+		if window.EndsAt.After(to) {
+			to = window.EndsAt
+		}
+	}
+	taken, err := bookedSlots(tx, fields, from, to)
+	if err != nil {
+		return models.Booking{}, err
+	}
+
+	duration := time.Duration(request.Duration) * time.Hour
+	now := time.Now()
+	for _, window := range windows {
+		for start := window.StartsAt; !start.Add(duration).After(window.EndsAt); start = start.Add(time.Hour) {
+			if start.Before(now) {
+				continue
+			}
+			for _, field := range fields {
+				if overlapsAny(taken[field.ID], start, start.Add(duration)) {
+					continue
+				}
+
+				booking, err := newBooking(challenger, field, start, request.Duration)
+				if err != nil {
+					return models.Booking{}, err
+				}
+				booking.TeamID = &request.TeamID
+				booking.OpponentTeamID = &opponent.ID
+
+				err = insertBooking(tx, &booking)
+				if errors.Is(err, errSlotTaken) {
+					continue // Taken in the meantime, keep looking
+				}
+				return booking, err
+			}
+		}
+	}
+	return models.Booking{}, errNoFieldFree
+}
+
+// bookedSlots returns the active bookings of the fields that overlap the range, by field.
+func bookedSlots(tx *gorm.DB, fields []models.Field, from, to time.Time) (map[uint][]models.Booking, error) {
+	fieldIDs := make([]uint, 0, len(fields))
+	for _, field := range fields {
+		fieldIDs = append(fieldIDs, field.ID)
+	}
+
+	var bookings []models.Booking
+	if err := tx.Select("id", "field_id", "booking_time", "duration").
+		Where("field_id IN ?", fieldIDs).
+		Scopes(models.ActiveBookingsBetween(from, to)).
+		Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+
+	taken := map[uint][]models.Booking{}
+	for _, booking := range bookings {
+		taken[booking.FieldID] = append(taken[booking.FieldID], booking)
+	}
+	return taken, nil
+}
+
+// overlapsAny reports whether any of the bookings overlaps the time between start and end.
+func overlapsAny(bookings []models.Booking, start, end time.Time) bool {
+	for _, booking := range bookings {
+		if booking.BookingTime.Before(end) && booking.BookingTime.Add(time.Duration(booking.Duration)*time.Hour).After(start) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/geo"
+	"sparring-backend/internal/matchmaking"
+	"sparring-backend/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultMaxRatingGap bounds the candidates considered by matchmaking unless specified otherwise
+const defaultMaxRatingGap = 400
+
+// SetTeamAvailability handles replacing the weekly availability windows of a team (captains only)
+func SetTeamAvailability(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	teamID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if !requireCaptain(c, teamID, user) {
+		return
+	}
+
+	var req struct {
+		Windows []struct {
+			Weekday   int    `json:"weekday"`
+			StartTime string `json:"start_time" binding:"required"`
+			EndTime   string `json:"end_time" binding:"required"`
+		} `json:"windows" binding:"max=50,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	windows := []models.TeamAvailability{}
+	for _, window := range req.Windows {
+		if _, err := matchmaking.ParseWindow(window.Weekday, window.StartTime, window.EndTime); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		windows = append(windows, models.TeamAvailability{
+			TeamID:    teamID,
+			Weekday:   window.Weekday,
+			StartTime: window.StartTime,
+			EndTime:   window.EndTime,
+		})
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", teamID).Delete(&models.TeamAvailability{}).Error; err != nil {
+			return err
+		}
+		if len(windows) == 0 {
+			return nil
+		}
+		return tx.Create(&windows).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save availability"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Availability updated successfully", "availability": windows})
+}
+
+// SetTeamPreferredArenas handles replacing the arenas a team prefers to play at (captains only)
+func SetTeamPreferredArenas(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	teamID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if !requireCaptain(c, teamID, user) {
+		return
+	}
+
+	var req struct {
+		ArenaIDs []uint `json:"arena_ids" binding:"max=20"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	arenas := []models.Arena{}
+	if len(req.ArenaIDs) > 0 {
+		if err := database.DB.Find(&arenas, req.ArenaIDs).Error; err != nil || len(arenas) != len(req.ArenaIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown arena in arena_ids"})
+			return
+		}
+	}
+
+	team := models.Team{}
+	team.ID = teamID
+	if err := database.DB.Model(&team).Association("PreferredArenas").Replace(arenas); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preferred arenas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Preferred arenas updated successfully"})
+}
+
+// SuggestOpponents handles suggesting compatible opponents for a team, scored by skill rating
+// proximity, distance between preferred arenas and overlapping availability
+func SuggestOpponents(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	teamID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if teamRole(teamID, user.ID) == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only team members can see suggested opponents"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+	maxGap, err := strconv.Atoi(c.DefaultQuery("max_rating_gap", strconv.Itoa(defaultMaxRatingGap)))
+	if err != nil || maxGap < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_rating_gap must be a positive number"})
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.Preload("PreferredArenas").Preload("Availability").First(&team, teamID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	// Narrow candidates in SQL with the (sport_type, rating) index, excluding teams sharing a player
+	sharedTeams := database.DB.Model(&models.TeamMember{}).Select("team_id").
+		Where("user_id IN (?)", database.DB.Model(&models.TeamMember{}).Select("user_id").Where("team_id = ?", team.ID))
+	var candidates []models.Team
+	if err := database.DB.Preload("PreferredArenas").Preload("Availability").
+		Where("sport_type = ? AND rating BETWEEN ? AND ?", team.SportType, team.Rating-maxGap, team.Rating+maxGap).
+		Where("id NOT IN (?)", sharedTeams).
+		Find(&candidates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load candidates"})
+		return
+	}
+
+	profiles := make([]matchmaking.Profile, 0, len(candidates))
+	names := map[uint]string{}
+	for _, candidate := range candidates {
+		profiles = append(profiles, teamProfile(candidate))
+		names[candidate.ID] = candidate.Name
+	}
+
+	suggestions := matchmaking.Suggest(teamProfile(team), profiles, limit, matchmaking.DefaultWeights)
+	results := make([]gin.H, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		results = append(results, gin.H{
+			"team_id":         suggestion.TeamID,
+			"name":            names[suggestion.TeamID],
+			"score":           suggestion.Score,
+			"rating_gap":      suggestion.RatingGap,
+			"distance_km":     suggestion.DistanceKm,
+			"overlap_minutes": suggestion.OverlapMinutes,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggested_opponents": results})
+}
+
+// teamProfile converts a team, with its preferred arenas and availability loaded, to a matchmaking profile.
+func teamProfile(team models.Team) matchmaking.Profile {
+	profile := matchmaking.Profile{TeamID: team.ID, Rating: team.Rating}
+	for _, arena := range team.PreferredArenas {
+		if arena.Latitude != nil && arena.Longitude != nil {
+			profile.Arenas = append(profile.Arenas, geo.Point{Lat: *arena.Latitude, Lng: *arena.Longitude})
+		}
+	}
+	for _, availability := range team.Availability {
+		if window, err := matchmaking.ParseWindow(availability.Weekday, availability.StartTime, availability.EndTime); err == nil {
+			profile.Windows = append(profile.Windows, window)
+		}
+	}
+	return profile
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/media"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// mediaURLTTL is how long the signed media URLs in responses stay valid
+const mediaURLTTL = time.Hour
+
+// maxUploadBytes returns the upload size limit, MEDIA_MAX_UPLOAD_MB megabytes (10 by default).
+func maxUploadBytes() int64 {
+	if mb, err := strconv.ParseInt(os.Getenv("MEDIA_MAX_UPLOAD_MB"), 10, 64); err == nil && mb > 0 {
+		return mb << 20
+	}
+	return 10 << 20
+}
+
+// UploadArenaMedia handles the owner uploading a photo of an arena, or of one of its fields
+// with the field_id form value. The image is sent as the "file" part of a multipart form;
+// its type is sniffed from the content and a JPEG thumbnail is generated.
+func UploadArenaMedia(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadOwnedArena(c, arenaID, user)
+	if !ok {
+		return
+	}
+
+	// Leave room for the other parts of the form
+	limit := maxUploadBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "The file is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A multipart file named file is required"})
+		return
+	}
+	defer file.Close()
+	if header.Size > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "The file is too large"})
+		return
+	}
+
+	item := models.ArenaMedia{ArenaID: arena.ID}
+	if value := c.PostForm("field_id"); value != "" {
+		fieldID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field_id"})
+			return
+		}
+		var field models.Field
+		if err := database.DB.Where("arena_id = ?", arena.ID).First(&field, fieldID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Field not found in this arena"})
+			return
+		}
+		item.FieldID = &field.ID
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the file"})
+		return
+	}
+	if int64(len(data)) > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "The file is too large"})
+		return
+	}
+
+	img, err := media.Decode(data)
+	if errors.Is(err, media.ErrUnsupportedType) || errors.Is(err, media.ErrTooLarge) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process the image"})
+		return
+	}
+	thumbnail, err := img.Thumbnail(media.ThumbnailSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate the thumbnail"})
+		return
+	}
+
+	name, err := randomToken(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store the image"})
+		return
+	}
+	prefix := "arenas/" + strconv.FormatUint(uint64(arena.ID), 10) + "/" + name
+	item.Key, item.ThumbnailKey = prefix+img.Extension, prefix+"_thumb.jpg"
+	item.ContentType, item.Size, item.Width, item.Height = img.ContentType, int64(len(data)), img.Width, img.Height
+
+	ctx := c.Request.Context()
+	if err := storage.Default.Put(ctx, item.Key, data, item.ContentType); err != nil {
+		log.Printf("Failed to store media %s: %v", item.Key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store the image"})
+		return
+	}
+	if err := storage.Default.Put(ctx, item.ThumbnailKey, thumbnail, "image/jpeg"); err != nil {
+		log.Printf("Failed to store media %s: %v", item.ThumbnailKey, err)
+		deleteMediaObjects(item)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store the image"})
+		return
+	}
+
+	if err := database.DB.Create(&item).Error; err != nil {
+		deleteMediaObjects(item)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save the image"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Image uploaded successfully", "media": signMedia(ctx, []models.ArenaMedia{item})[0]})
+}
+
+// DeleteArenaMedia handles the arena owner removing a photo
+func DeleteArenaMedia(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	mediaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var item models.ArenaMedia
+	if err := database.DB.First(&item, mediaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+	if _, ok := loadOwnedArena(c, item.ArenaID, user); !ok {
+		return
+	}
+
+	if err := database.DB.Delete(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete media"})
+		return
+	}
+	deleteMediaObjects(item)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Media deleted successfully"})
+}
+
+// GetArena handles fetching an arena with its fields and photos
+func GetArena(c *gin.Context) {
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var arena models.Arena
+	if err := database.DB.Preload("Fields").Preload("Media", mediaOrder).First(&arena, arenaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Arena not found"})
+		return
+	}
+	arena.Media = signMedia(c.Request.Context(), arena.Media)
+
+	c.JSON(http.StatusOK, gin.H{"arena": arena})
+}
+
+// ServeLocalMedia serves files of the local storage to holders of a URL signed by it.
+// Other storages hand out URLs of their own, so the route answers 404 for them.
+func ServeLocalMedia(c *gin.Context) {
+	local, ok := storage.Default.(*storage.Local)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !local.Verify(key, c.Query("expires"), c.Query("signature")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired media link"})
+		return
+	}
+	path, err := local.Path(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.File(path)
+}
+
+// mediaOrder lists arena photos in upload order.
+func mediaOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+// signMedia fills in the signed URLs of the media. Items that can't be signed are
+// left without URLs rather than failing the whole response.
+func signMedia(ctx context.Context, items []models.ArenaMedia) []models.ArenaMedia {
+	for i := range items {
+		var err error
+		if items[i].URL, err = storage.Default.SignedURL(ctx, items[i].Key, mediaURLTTL); err != nil {
+			log.Printf("Failed to sign media %d: %v", items[i].ID, err)
+		}
+		if items[i].ThumbnailURL, err = storage.Default.SignedURL(ctx, items[i].ThumbnailKey, mediaURLTTL); err != nil {
+			log.Printf("Failed to sign media %d thumbnail: %v", items[i].ID, err)
+		}
+	}
+	return items
+}
+
+// deleteMediaObjects removes the stored files of the media. Failures are logged, since
+// orphaned objects only waste space.
+func deleteMediaObjects(item models.ArenaMedia) {
+	for _, key := range []string{item.Key, item.ThumbnailKey} {
+		if err := storage.Default.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete media object %s: %v", key, err)
+		}
+	}
+}
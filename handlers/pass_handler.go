@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/notify"
+	"sparring-backend/internal/passes"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errPassNotFound  = errors.New("pass not found")
+	errNoPassApplies = errors.New("none of your passes covers this booking")
+	errPassWithPromo = errors.New("a pass can't be combined with a promo code")
+	errPassCancelled = errors.New("pass is already cancelled")
+)
+
+// CreatePassPlan handles an arena owner adding a membership or session pass to sell
+func CreatePassPlan(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadOwnedArena(c, arenaID, user)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name              string      `json:"name" binding:"required"`
+		Kind              string      `json:"kind" binding:"required"`
+		Price             money.Money `json:"price"`
+		ValidityDays      int         `json:"validity_days" binding:"required"`
+		Credits           int         `json:"credits"`
+		MaxDuration       int         `json:"max_duration"`
+		CancellationHours int         `json:"cancellation_hours"`
+		DaysOfWeek        string      `json:"days_of_week"`
+		StartTime         string      `json:"start_time"`
+		EndTime           string      `json:"end_time"`
+		FieldIDs          []uint      `json:"field_ids"` // Empty for every field of the arena
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan := models.PassPlan{
+		ArenaID:           arena.ID,
+		Name:              req.Name,
+		Kind:              req.Kind,
+		Price:             req.Price,
+		ValidityDays:      req.ValidityDays,
+		Credits:           req.Credits,
+		MaxDuration:       req.MaxDuration,
+		CancellationHours: req.CancellationHours,
+		DaysOfWeek:        req.DaysOfWeek,
+		StartTime:         req.StartTime,
+		EndTime:           req.EndTime,
+		Active:            true,
+	}
+	if err := passes.Validate(&plan, arena.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.FieldIDs) > 0 {
+		if err := database.DB.Where("arena_id = ?", arena.ID).Find(&plan.Fields, req.FieldIDs).Error; err != nil || len(plan.Fields) != len(req.FieldIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown field in field_ids"})
+			return
+		}
+	}
+
+	if err := database.DB.Create(&plan).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pass plan"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Pass plan created successfully", "pass_plan": plan})
+}
+
+// ListPassPlans handles listing the memberships and passes an arena sells. The owner
+// and staff selling passes also see retired plans.
+func ListPassPlans(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var arena models.Arena
+	if err := database.DB.First(&arena, arenaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Arena not found"})
+		return
+	}
+
+	query := database.DB.Preload("Fields").Where("arena_id = ?", arena.ID)
+	if !hasArenaPermission(user, arena, models.StaffSellPasses) {
+		query = query.Where("active = ?", true)
+	}
+	var plans []models.PassPlan
+	if err := query.Order("kind, name, id").Find(&plans).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load pass plans"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pass_plans": plans})
+}
+
+// UpdatePassPlan handles an arena owner retiring a pass plan, or offering it again.
+// The terms of a plan never change, since passes already sold refer to them.
+func UpdatePassPlan(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	planID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Active *bool `json:"active" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var plan models.PassPlan
+	if err := database.DB.First(&plan, planID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pass plan not found"})
+		return
+	}
+	if _, ok := loadOwnedArena(c, plan.ArenaID, user); !ok {
+		return
+	}
+
+	if err := database.DB.Model(&plan).Update("active", *req.Active).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pass plan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pass plan updated successfully", "pass_plan": plan})
+}
+
+// IssuePass handles the owner or staff selling a pass to a customer, identified by email.
+// Passes are paid at the arena; the pass starts on valid_from (today by default).
+func IssuePass(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadStaffArena(c, arenaID, user, models.StaffSellPasses)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		PlanID    uint       `json:"plan_id" binding:"required"`
+		Email     string     `json:"email" binding:"required,email"`
+		ValidFrom *time.Time `json:"valid_from"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var plan models.PassPlan
+	if err := database.DB.Where("arena_id = ? AND active = ?", arena.ID, true).First(&plan, req.PlanID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pass plan not found"})
+		return
+	}
+	var customer models.User
+	if err := database.DB.Where("email = ?", strings.ToLower(req.Email)).First(&customer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No user with this email"})
+		return
+	}
+
+	from := time.Now()
+	if req.ValidFrom != nil {
+		from = *req.ValidFrom
+	}
+	pass := passes.Issue(plan, arena, customer.ID, user.ID, from)
+	if err := database.DB.Create(&pass).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue pass"})
+		return
+	}
+
+	credits := "unlimited bookings"
+	if pass.Credits > 0 {
+		credits = fmt.Sprintf("%d bookings", pass.Credits)
+	}
+	notify.Send(context.Background(), notify.Message{
+		UserID:  customer.ID,
+		Email:   customer.Email,
+		Subject: "Your " + plan.Name + " at " + arena.Name,
+		Body:    fmt.Sprintf("Your %s at %s covers %s until %s. Use it when you book a field.", plan.Name, arena.Name, credits, pass.ValidUntil.Format("2 Jan 2006")),
+	})
+
+	pass.Plan = &plan
+	pass.RemainingCredits = pass.Remaining()
+	c.JSON(http.StatusCreated, gin.H{"message": "Pass issued successfully", "pass": pass})
+}
+
+// ListArenaPasses handles the owner or staff listing the passes sold at an arena,
+// optionally filtered by status
+func ListArenaPasses(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadStaffArena(c, arenaID, user, models.StaffSellPasses)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Preload("Plan").Preload("User").Where("arena_id = ?", arena.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var list []models.Pass
+	if err := query.Order("id DESC").Limit(500).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load passes"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(list))
+	for _, pass := range list {
+		pass.RemainingCredits = pass.Remaining()
+		result = append(result, gin.H{"pass": pass, "customer": gin.H{"id": pass.User.ID, "name": pass.User.Name, "email": pass.User.Email}})
+	}
+	c.JSON(http.StatusOK, gin.H{"passes": result})
+}
+
+// ListMyPasses handles listing the current user's memberships and passes, with the
+// credits left on each
+func ListMyPasses(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var list []models.Pass
+	if err := database.DB.Preload("Plan.Fields").Where("user_id = ?", user.ID).Order("valid_until DESC, id DESC").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load passes"})
+		return
+	}
+	for i := range list {
+		list[i].RemainingCredits = list[i].Remaining()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"passes": list})
+}
+
+// CancelPass handles the owner or staff cancelling a pass, e.g. after refunding it at the
+// desk. Bookings already made with the pass stand.
+func CancelPass(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	passID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var pass models.Pass
+	if err := database.DB.First(&pass, passID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pass not found"})
+		return
+	}
+	if _, ok := loadStaffArena(c, pass.ArenaID, user, models.StaffSellPasses); !ok {
+		return
+	}
+
+	result := database.DB.Model(&pass).Where("status = ?", models.PassStatusActive).Update("status", models.PassStatusCancelled)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel pass"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": errPassCancelled.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pass cancelled"})
+}
+
+// applyPass covers the field time of a booking with one of the user's passes inside a
+// transaction: the given pass, or the first one to expire that covers the booking. The
+// pass row is locked so concurrent bookings can't both use its last credit.
+func applyPass(tx *gorm.DB, passID *uint, user models.User, field models.Field, booking *models.Booking) (*models.PassRedemption, error) {
+	var arena models.Arena
+	if err := tx.First(&arena, field.ArenaID).Error; err != nil {
+		return nil, err
+	}
+
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Plan.Fields").
+		Where("user_id = ? AND arena_id = ? AND status = ?", user.ID, field.ArenaID, models.PassStatusActive)
+	if passID != nil {
+		query = query.Where("id = ?", *passID)
+	} else {
+		query = query.Where("valid_until > ?", booking.BookingTime)
+	}
+	var candidates []models.Pass
+	if err := query.Order("valid_until, id").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	if passID != nil && len(candidates) == 0 {
+		return nil, errPassNotFound
+	}
+
+	var pass *models.Pass
+	reason := errNoPassApplies
+	for i := range candidates {
+		if err := passes.Check(candidates[i], arena, field, booking.BookingTime, booking.Duration); err != nil {
+			if passID != nil {
+				reason = err
+			}
+			continue
+		}
+		pass = &candidates[i]
+		break
+	}
+	if pass == nil {
+		return nil, reason
+	}
+
+	// The pass covers the field time; add-ons are still paid
+	covered := money.Zero(booking.TotalAmount.Currency)
+	for _, line := range booking.Lines {
+		if line.Kind != models.BookingLineHour {
+			continue
+		}
+		var err error
+		if covered, err = covered.Add(line.Amount); err != nil {
+			return nil, err
+		}
+	}
+	total, err := booking.TotalAmount.Sub(covered)
+	if err != nil {
+		return nil, err
+	}
+	booking.TotalAmount = total
+	booking.PassID = &pass.ID
+	booking.Lines = append(booking.Lines, models.BookingLine{
+		Kind:        models.BookingLinePass,
+		Description: pass.Plan.Name,
+		Quantity:    1,
+		UnitPrice:   covered.Neg(),
+		Amount:      covered.Neg(),
+	})
+
+	if err := tx.Model(pass).UpdateColumn("credits_used", gorm.Expr("credits_used + 1")).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.PassRedemption{PassID: pass.ID, Amount: covered}, nil
+}
+
+// restorePassCredit gives the credit a cancelled booking used back to its pass, when the
+// booking was cancelled early enough for the pass plan, or in any case with always.
+// It reports whether a credit was restored.
+func restorePassCredit(tx *gorm.DB, booking models.Booking, now time.Time, always bool) (bool, error) {
+	if booking.PassID == nil {
+		return false, nil
+	}
+
+	var redemption models.PassRedemption
+	err := tx.Where("booking_id = ? AND restored_at IS NULL", booking.ID).First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil // Never redeemed or already restored
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var pass models.Pass
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Plan").First(&pass, redemption.PassID).Error; err != nil {
+		return false, err
+	}
+	if !always && !passes.Restorable(*pass.Plan, booking.BookingTime, now) {
+		return false, nil
+	}
+
+	if err := tx.Model(&pass).Where("credits_used > 0").UpdateColumn("credits_used", gorm.Expr("credits_used - 1")).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&redemption).Update("restored_at", now).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// respondPassError writes the response for a pass that can't be used for a booking and
+// reports whether there was an error.
+func respondPassError(c *gin.Context, err error, fallback string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, errPassNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errNoPassApplies), errors.Is(err, passes.ErrNotActive),
+		errors.Is(err, passes.ErrExhausted), errors.Is(err, passes.ErrNotApplicable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+	return true
+}
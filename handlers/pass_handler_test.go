@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/passes"
+	"sparring-backend/internal/testdb"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// passFixture is an arena with a field, a plan of two credits and a pass of it for user 5.
+type passFixture struct {
+	db    *gorm.DB
+	arena models.Arena
+	field models.Field
+	plan  models.PassPlan
+	pass  models.Pass
+	user  models.User
+}
+
+// passStart is when the fixture passes start, a Monday
+var passStart = time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC)
+
+func newPassFixture(t *testing.T) passFixture {
+	t.Helper()
+	f := passFixture{db: testdb.New(t, &models.Arena{}, &models.Field{}, &models.PassPlan{}, &models.Pass{}, &models.PassRedemption{}, &models.Booking{})}
+	f.arena = models.Arena{Name: "Arena", Location: "Here", Currency: "EUR", Timezone: "UTC", OwnerID: 1}
+	if err := f.db.Create(&f.arena).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.field = models.Field{ArenaID: f.arena.ID, FieldName: "Court 1", SportType: "Tennis"}
+	if err := f.db.Create(&f.field).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.plan = models.PassPlan{ArenaID: f.arena.ID, Name: "2 sessions", Kind: models.PassKindPass, Price: money.New(5000, "EUR"),
+		ValidityDays: 30, Credits: 2, CancellationHours: 24, Active: true}
+	if err := f.db.Create(&f.plan).Error; err != nil {
+		t.Fatal(err)
+	}
+	f.user.ID = 5
+	f.pass = passes.Issue(f.plan, f.arena, f.user.ID, 1, passStart)
+	if err := f.db.Create(&f.pass).Error; err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// booking returns a pending booking of the fixture's field with an hour line of 20.00 and
+// an add-on line of addOn minor units, if any.
+func (f passFixture) booking(start time.Time, addOn int64) *models.Booking {
+	booking := &models.Booking{UserID: f.user.ID, ArenaID: f.arena.ID, FieldID: f.field.ID, BookingTime: start, Duration: 1,
+		TotalAmount: money.New(2000+addOn, "EUR"), Status: models.BookingStatusPending}
+	booking.Lines = []models.BookingLine{{Kind: models.BookingLineHour, Quantity: 1, UnitPrice: money.New(2000, "EUR"), Amount: money.New(2000, "EUR")}}
+	if addOn > 0 {
+		booking.Lines = append(booking.Lines, models.BookingLine{Kind: models.BookingLineAddOn, Quantity: 1, UnitPrice: money.New(addOn, "EUR"), Amount: money.New(addOn, "EUR")})
+	}
+	return booking
+}
+
+// redeem applies the pass to the booking and records it like CreateBooking does.
+func (f passFixture) redeem(t *testing.T, passID *uint, booking *models.Booking) error {
+	t.Helper()
+	redemption, err := applyPass(f.db, passID, f.user, f.field, booking)
+	if err != nil {
+		return err
+	}
+	if err := f.db.Omit("Lines").Create(booking).Error; err != nil {
+		t.Fatal(err)
+	}
+	redemption.BookingID = booking.ID
+	if err := f.db.Create(redemption).Error; err != nil {
+		t.Fatal(err)
+	}
+	return nil
+}
+
+func (f passFixture) creditsUsed(t *testing.T) int {
+	t.Helper()
+	var pass models.Pass
+	if err := f.db.First(&pass, f.pass.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return pass.CreditsUsed
+}
+
+func TestApplyPass(t *testing.T) {
+	f := newPassFixture(t)
+
+	// The pass covers the field time
+	booking := f.booking(passStart.Add(24*time.Hour+18*time.Hour), 0)
+	if err := f.redeem(t, nil, booking); err != nil {
+		t.Fatalf("applyPass() = %v", err)
+	}
+	if !booking.TotalAmount.IsZero() || booking.PassID == nil || *booking.PassID != f.pass.ID {
+		t.Errorf("booking total %v, pass %v; want 0 and pass %d", booking.TotalAmount, booking.PassID, f.pass.ID)
+	}
+
+	// Add-ons are still paid
+	booking = f.booking(passStart.Add(48*time.Hour+18*time.Hour), 500)
+	if err := f.redeem(t, &f.pass.ID, booking); err != nil {
+		t.Fatalf("applyPass() with an add-on = %v", err)
+	}
+	if booking.TotalAmount.Minor != 500 {
+		t.Errorf("booking total %v, want 5.00", booking.TotalAmount)
+	}
+	if used := f.creditsUsed(t); used != 2 {
+		t.Errorf("credits used = %d, want 2", used)
+	}
+
+	// Both credits are used
+	booking = f.booking(passStart.Add(72*time.Hour+18*time.Hour), 0)
+	if err := f.redeem(t, &f.pass.ID, booking); !errors.Is(err, passes.ErrExhausted) {
+		t.Errorf("applyPass() on an exhausted pass = %v, want ErrExhausted", err)
+	}
+	if err := f.redeem(t, nil, booking); !errors.Is(err, errNoPassApplies) {
+		t.Errorf("applyPass() without a pass left = %v, want errNoPassApplies", err)
+	}
+}
+
+func TestApplyPassValidity(t *testing.T) {
+	f := newPassFixture(t)
+
+	if err := f.redeem(t, &f.pass.ID, f.booking(passStart.Add(-time.Hour), 0)); !errors.Is(err, passes.ErrNotActive) {
+		t.Errorf("applyPass() before the pass starts = %v, want ErrNotActive", err)
+	}
+	if err := f.redeem(t, &f.pass.ID, f.booking(f.pass.ValidUntil.Add(-30*time.Minute), 0)); !errors.Is(err, passes.ErrNotActive) {
+		t.Errorf("applyPass() running past the pass = %v, want ErrNotActive", err)
+	}
+	missing := f.pass.ID + 1
+	if err := f.redeem(t, &missing, f.booking(passStart.Add(18*time.Hour), 0)); !errors.Is(err, errPassNotFound) {
+		t.Errorf("applyPass() of another pass = %v, want errPassNotFound", err)
+	}
+
+	// Restricted to another field and to mornings
+	other := models.Field{ArenaID: f.arena.ID, FieldName: "Court 2", SportType: "Tennis"}
+	if err := f.db.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := f.db.Model(&f.plan).Association("Fields").Append(&other); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.redeem(t, &f.pass.ID, f.booking(passStart.Add(18*time.Hour), 0)); !errors.Is(err, passes.ErrNotApplicable) {
+		t.Errorf("applyPass() on an unlisted field = %v, want ErrNotApplicable", err)
+	}
+	if err := f.db.Model(&f.plan).Association("Fields").Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.db.Model(&f.plan).Updates(map[string]interface{}{"start_time": "08:00", "end_time": "12:00"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := f.redeem(t, &f.pass.ID, f.booking(passStart.Add(18*time.Hour), 0)); !errors.Is(err, passes.ErrNotApplicable) {
+		t.Errorf("applyPass() outside the plan's hours = %v, want ErrNotApplicable", err)
+	}
+	if err := f.redeem(t, &f.pass.ID, f.booking(passStart.Add(9*time.Hour), 0)); err != nil {
+		t.Errorf("applyPass() inside the plan's hours = %v", err)
+	}
+}
+
+func TestRestorePassCredit(t *testing.T) {
+	f := newPassFixture(t)
+
+	start := passStart.Add(72 * time.Hour)
+	early := f.booking(start, 0)
+	late := f.booking(start.Add(time.Hour), 0)
+	for _, booking := range []*models.Booking{early, late} {
+		if err := f.redeem(t, nil, booking); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Exactly the plan's 24 hours before the booking still gives the credit back
+	restored, err := restorePassCredit(f.db, *early, start.Add(-24*time.Hour), false)
+	if err != nil || !restored {
+		t.Fatalf("restorePassCredit() at the deadline = %v, %v, want true", restored, err)
+	}
+	if restored, _ := restorePassCredit(f.db, *early, start.Add(-24*time.Hour), false); restored {
+		t.Error("restorePassCredit() restored the same booking twice")
+	}
+
+	// A minute later it's too late, unless the arena cancelled
+	lateStart := late.BookingTime
+	if restored, err := restorePassCredit(f.db, *late, lateStart.Add(-24*time.Hour+time.Minute), false); err != nil || restored {
+		t.Errorf("restorePassCredit() after the deadline = %v, %v, want false", restored, err)
+	}
+	if restored, err := restorePassCredit(f.db, *late, lateStart.Add(-time.Hour), true); err != nil || !restored {
+		t.Errorf("restorePassCredit() always = %v, %v, want true", restored, err)
+	}
+	if used := f.creditsUsed(t); used != 0 {
+		t.Errorf("credits used = %d, want 0", used)
+	}
+
+	if restored, err := restorePassCredit(f.db, models.Booking{}, start, true); err != nil || restored {
+		t.Errorf("restorePassCredit() without a pass = %v, %v, want false", restored, err)
+	}
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/ledger"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/payments"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errDuplicateEvent is returned inside the webhook transaction when the event was already processed
+var errDuplicateEvent = errors.New("event already processed")
+
+// PaymentWebhook handles signed webhook events sent by the payment provider.
+// Events are deduplicated by provider event ID so retries never apply twice.
+func PaymentWebhook(c *gin.Context) {
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	if secret == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook secret not configured"})
+		return
+	}
+
+	// The signature is computed over the raw body, so read it before any decoding
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read request body"})
+		return
+	}
+
+	if !payments.VerifySignature(secret, body, c.GetHeader(payments.SignatureHeader)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	event, err := payments.ParseWebhookEvent(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return processPaymentEvent(tx, event, string(body))
+	})
+
+	switch {
+	case errors.Is(err, errDuplicateEvent):
+		c.JSON(http.StatusOK, gin.H{"message": "Event already processed"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking or payment not found"})
+	case errors.Is(err, money.ErrCurrencyMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Event currency does not match the booking"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process event"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Event processed"})
+	}
+}
+
+// processPaymentEvent records the event and applies it to its booking. A refund applies to
+// the booking its payment was made for, whatever booking the event names.
+func processPaymentEvent(tx *gorm.DB, event *payments.WebhookEvent, payload string) error {
+	bookingID := event.Data.BookingID
+	if event.Type == payments.EventPaymentRefunded {
+		var payment models.Payment
+		if err := tx.Where("provider_ref = ?", event.Data.PaymentID).First(&payment).Error; err != nil {
+			return err
+		}
+		bookingID = payment.BookingID
+	}
+
+	// Record the event first; the unique event ID rejects concurrent retries
+	record := models.PaymentEvent{
+		EventID:     event.ID,
+		Type:        event.Type,
+		BookingID:   bookingID,
+		Payload:     payload,
+		ProcessedAt: time.Now(),
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errDuplicateEvent
+	}
+
+	// Lock the booking row so concurrent events for it are serialized
+	var booking models.Booking
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, bookingID).Error; err != nil {
+		return err
+	}
+
+	return applyPaymentEvent(tx, &booking, event)
+}
+
+// applyPaymentEvent transitions the booking and its payment record for a webhook event.
+// Transitions are guarded by the current status, so a booking is never confirmed or refunded twice.
+func applyPaymentEvent(tx *gorm.DB, booking *models.Booking, event *payments.WebhookEvent) error {
+	amount := event.Amount()
+	if amount.Currency != booking.TotalAmount.Currency {
+		return money.ErrCurrencyMismatch
+	}
+
+	switch event.Type {
+	case payments.EventPaymentSucceeded:
+		payment := models.Payment{
+			BookingID:   booking.ID,
+			Provider:    event.Data.Provider,
+			ProviderRef: event.Data.PaymentID,
+			Amount:      amount,
+			Refunded:    money.Zero(amount.Currency),
+			Status:      models.PaymentStatusSucceeded,
+		}
+		result := tx.Where(models.Payment{ProviderRef: event.Data.PaymentID}).FirstOrCreate(&payment)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil // Another event already reported this payment
+		}
+		if err := postPayment(tx, booking, payment); err != nil {
+			return err
+		}
+
+		// Only the exact amount owed settles the booking or share. Anything else is kept on
+		// record and flagged, so it can be refunded, without touching the booking.
+		owed, err := amountOwed(tx, booking, event.Data.ShareID)
+		if err != nil {
+			return err
+		}
+		if payment.Amount != owed {
+			return flagPayment(tx, &payment, fmt.Sprintf("paid %s but %s was owed", payment.Amount, owed))
+		}
+
+		// Split bookings wait until every share is paid. A payment for a share or booking that
+		// was already settled, covered or cancelled is flagged for refund too.
+		paid, err := settlePaymentShares(tx, booking, event.Data.ShareID, payment)
+		if errors.Is(err, errNothingOwed) {
+			return flagPayment(tx, &payment, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		if !paid {
+			return nil
+		}
+
+		if booking.Status != models.BookingStatusPending {
+			return nil // Cancelled meanwhile, nothing to do
+		}
+		if err := tx.Model(booking).Update("status", models.BookingStatusConfirmed).Error; err != nil {
+			return err
+		}
+		return issueInvoice(tx, booking)
+
+	case payments.EventPaymentRefunded:
+		var payment models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider_ref = ? AND booking_id = ?", event.Data.PaymentID, booking.ID).First(&payment).Error; err != nil {
+			return err
+		}
+		if payment.Status == models.PaymentStatusRefunded {
+			return nil // Already fully refunded
+		}
+		flagged := payment.Status == models.PaymentStatusFlagged
+
+		// A zero amount means a full refund; partial refunds never exceed what was paid
+		outstanding, err := payment.Amount.Sub(payment.Refunded)
+		if err != nil {
+			return err
+		}
+		if amount.IsZero() {
+			amount = outstanding
+		}
+		refund := amount.Min(outstanding)
+		if payment.Refunded, err = payment.Refunded.Add(refund); err != nil {
+			return err
+		}
+		if payment.Refunded.Minor >= payment.Amount.Minor {
+			payment.Status = models.PaymentStatusRefunded
+		}
+		if err := tx.Save(&payment).Error; err != nil {
+			return err
+		}
+		if err := postRefund(tx, booking, payment, refund, event.ID); err != nil {
+			return err
+		}
+
+		if payment.Status != models.PaymentStatusRefunded || flagged {
+			return nil // Partial refund, or a flagged payment that never settled anything: the booking stands
+		}
+
+		var stillPaid int64
+		if err := tx.Model(&models.Payment{}).
+			Where("booking_id = ? AND status = ?", booking.ID, models.PaymentStatusSucceeded).
+			Count(&stillPaid).Error; err != nil {
+			return err
+		}
+		if stillPaid > 0 {
+			// Other shares of a split booking remain paid: the refunded share is owed again
+			// and the booking waits for it
+			return reopenPaymentShare(tx, booking, payment)
+		}
+		if err := tx.Model(&models.PaymentShare{}).Where("payment_id = ?", payment.ID).
+			Update("status", models.PaymentShareRefunded).Error; err != nil {
+			return err
+		}
+		if booking.Status != models.BookingStatusConfirmed && booking.Status != models.BookingStatusCancelled {
+			return nil // Never paid or already refunded
+		}
+		if err := tx.Model(booking).Update("status", models.BookingStatusRefunded).Error; err != nil {
+			return err
+		}
+		if err := releasePenalties(tx, *booking); err != nil {
+			return err
+		}
+		// Refunded by the arena, the customer keeps their pass credit and points
+		if _, err := restorePassCredit(tx, *booking, time.Now(), true); err != nil {
+			return err
+		}
+		_, err = restorePoints(tx, *booking, time.Now())
+		return err
+	}
+
+	// Unknown event types are recorded but otherwise ignored
+	return nil
+}
+
+// reopenPaymentShare makes the shares settled or covered by a refunded payment pending
+// again, and moves a confirmed booking back to pending until they are paid.
+func reopenPaymentShare(tx *gorm.DB, booking *models.Booking, payment models.Payment) error {
+	result := tx.Model(&models.PaymentShare{}).Where("payment_id = ?", payment.ID).
+		Updates(map[string]interface{}{"status": models.PaymentSharePending, "payment_id": nil, "paid_at": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 || booking.Status != models.BookingStatusConfirmed {
+		return nil
+	}
+	booking.Status = models.BookingStatusPending
+	return tx.Model(booking).Update("status", booking.Status).Error
+}
+
+// flagPayment marks a payment that can't settle its booking, with the reason, for an admin
+// to refund it.
+func flagPayment(tx *gorm.DB, payment *models.Payment, reason string) error {
+	payment.Status, payment.FlagReason = models.PaymentStatusFlagged, reason
+	return tx.Model(payment).Updates(map[string]interface{}{"status": payment.Status, "flag_reason": reason}).Error
+}
+
+// postPayment records the owner's share of a booking payment and the platform commission
+// in the ledger, at the arena's current commission rate.
+func postPayment(tx *gorm.DB, booking *models.Booking, payment models.Payment) error {
+	var arena models.Arena
+	if err := tx.First(&arena, booking.ArenaID).Error; err != nil {
+		return err
+	}
+	txn := ledger.PaymentTransaction(payment, arena, ledger.CommissionBps(arena))
+	return ledger.Post(tx, &txn)
+}
+
+// postRefund records a refund in the ledger, taking it back from the owner's balance and
+// the commission in proportion.
+func postRefund(tx *gorm.DB, booking *models.Booking, payment models.Payment, refund money.Money, eventID string) error {
+	var arena models.Arena
+	if err := tx.First(&arena, booking.ArenaID).Error; err != nil {
+		return err
+	}
+	commission, err := ledger.RefundCommission(tx, payment, refund, payment.Status == models.PaymentStatusRefunded)
+	if err != nil {
+		return err
+	}
+	txn := ledger.RefundTransaction("refund:"+eventID, payment, arena, refund, commission)
+	return ledger.Post(tx, &txn)
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"sparring-backend/internal/ledger"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/payments"
+	"sparring-backend/internal/testdb"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// paymentDB returns a database with the tables the payment webhook touches.
+func paymentDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testdb.New(t,
+		&models.Arena{}, &models.Booking{}, &models.BookingLine{}, &models.Payment{}, &models.PaymentEvent{},
+		&models.PaymentShare{}, &models.Invoice{}, &models.InvoiceLine{}, &models.AccountPenalty{},
+		&models.LedgerTransaction{}, &models.LedgerPosting{}, &models.PassRedemption{}, &models.PointsEntry{},
+		&models.PointsLotUse{},
+	)
+}
+
+// pendingBooking creates an arena of the owner and a pending booking there for amount.
+func pendingBooking(t *testing.T, db *gorm.DB, ownerID uint, amount int64) models.Booking {
+	t.Helper()
+	arena := models.Arena{Name: "Arena", Location: "Here", Currency: "EUR", Timezone: "UTC", OwnerID: ownerID}
+	if err := db.Create(&arena).Error; err != nil {
+		t.Fatal(err)
+	}
+	booking := models.Booking{UserID: 9, ArenaID: arena.ID, FieldID: 1, BookingTime: time.Now().Add(48 * time.Hour), Duration: 1,
+		TotalAmount: money.New(amount, "EUR"), Discount: money.Zero("EUR"), Status: models.BookingStatusPending}
+	if err := db.Create(&booking).Error; err != nil {
+		t.Fatal(err)
+	}
+	return booking
+}
+
+// sendEvent processes a webhook event like PaymentWebhook does.
+func sendEvent(t *testing.T, db *gorm.DB, id, kind string, bookingID uint, paymentRef string, amount int64, shareID uint) error {
+	t.Helper()
+	event := &payments.WebhookEvent{ID: id, Type: kind}
+	event.Data.BookingID = bookingID
+	event.Data.PaymentID = paymentRef
+	event.Data.Provider = "test"
+	event.Data.Amount = amount
+	event.Data.Currency = "EUR"
+	event.Data.ShareID = shareID
+	return db.Transaction(func(tx *gorm.DB) error {
+		return processPaymentEvent(tx, event, "{}")
+	})
+}
+
+// bookingStatus returns the current status of the booking.
+func bookingStatus(t *testing.T, db *gorm.DB, id uint) string {
+	t.Helper()
+	var booking models.Booking
+	if err := db.First(&booking, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return booking.Status
+}
+
+// ownerBalance returns what the platform owes the owner in euro cents.
+func ownerBalance(t *testing.T, db *gorm.DB, ownerID uint) int64 {
+	t.Helper()
+	balances, err := ledger.OwnerBalances(db, ownerID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return balances["EUR"]
+}
+
+func TestRefundAppliesToThePaymentBooking(t *testing.T) {
+	db := paymentDB(t)
+	paid := pendingBooking(t, db, 1, 2000)
+	other := pendingBooking(t, db, 2, 3000)
+	if err := sendEvent(t, db, "evt_1", payments.EventPaymentSucceeded, paid.ID, "pi_1", 2000, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := sendEvent(t, db, "evt_2", payments.EventPaymentSucceeded, other.ID, "pi_2", 3000, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The refund of pi_1 names the other booking
+	if err := sendEvent(t, db, "evt_3", payments.EventPaymentRefunded, other.ID, "pi_1", 0, 0); err != nil {
+		t.Fatalf("refund event = %v", err)
+	}
+	if status := bookingStatus(t, db, paid.ID); status != models.BookingStatusRefunded {
+		t.Errorf("paid booking is %s, want refunded", status)
+	}
+	if status := bookingStatus(t, db, other.ID); status != models.BookingStatusConfirmed {
+		t.Errorf("other booking is %s, want confirmed", status)
+	}
+	if balance := ownerBalance(t, db, 1); balance != 0 {
+		t.Errorf("first owner balance = %d, want 0", balance)
+	}
+	if balance := ownerBalance(t, db, 2); balance != 2700 {
+		t.Errorf("second owner balance = %d, want 2700", balance)
+	}
+
+	var event models.PaymentEvent
+	db.Where("event_id = ?", "evt_3").First(&event)
+	if event.BookingID != paid.ID {
+		t.Errorf("refund event recorded for booking %d, want %d", event.BookingID, paid.ID)
+	}
+}
+
+// splitBooking creates a pending booking of 30.00 split into three shares of 10.00.
+func splitBooking(t *testing.T, db *gorm.DB) (models.Booking, []models.PaymentShare) {
+	t.Helper()
+	booking := pendingBooking(t, db, 1, 3000)
+	shares := make([]models.PaymentShare, 3)
+	for i := range shares {
+		shares[i] = models.PaymentShare{BookingID: booking.ID, UserID: uint(10 + i), Amount: money.New(1000, "EUR"), Status: models.PaymentSharePending}
+		if err := db.Create(&shares[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return booking, shares
+}
+
+func shareStatuses(t *testing.T, db *gorm.DB, bookingID uint) []string {
+	t.Helper()
+	var shares []models.PaymentShare
+	if err := db.Where("booking_id = ?", bookingID).Order("id").Find(&shares).Error; err != nil {
+		t.Fatal(err)
+	}
+	statuses := make([]string, len(shares))
+	for i, share := range shares {
+		statuses[i] = share.Status
+	}
+	return statuses
+}
+
+func TestRefundOfAShareReopensIt(t *testing.T) {
+	db := paymentDB(t)
+	booking, shares := splitBooking(t, db)
+	for i, share := range shares {
+		ref := "pi_" + strconv.Itoa(i)
+		if err := sendEvent(t, db, "evt_"+ref, payments.EventPaymentSucceeded, booking.ID, ref, 1000, share.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if status := bookingStatus(t, db, booking.ID); status != models.BookingStatusConfirmed {
+		t.Fatalf("booking is %s once every share is paid, want confirmed", status)
+	}
+
+	if err := sendEvent(t, db, "evt_refund", payments.EventPaymentRefunded, booking.ID, "pi_1", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if status := bookingStatus(t, db, booking.ID); status != models.BookingStatusPending {
+		t.Errorf("booking is %s after a share was refunded, want pending", status)
+	}
+	want := []string{models.PaymentSharePaid, models.PaymentSharePending, models.PaymentSharePaid}
+	for i, status := range shareStatuses(t, db, booking.ID) {
+		if status != want[i] {
+			t.Errorf("share %d is %s, want %s", i, status, want[i])
+		}
+	}
+}
+
+func TestRefundOfAWholeBookingPaymentReopensTheSharesItCovered(t *testing.T) {
+	db := paymentDB(t)
+	booking, shares := splitBooking(t, db)
+	if err := sendEvent(t, db, "evt_share", payments.EventPaymentSucceeded, booking.ID, "pi_share", 1000, shares[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	// The captain pays the rest of the booking at once
+	if err := sendEvent(t, db, "evt_rest", payments.EventPaymentSucceeded, booking.ID, "pi_rest", 2000, 0); err != nil {
+		t.Fatal(err)
+	}
+	if status := bookingStatus(t, db, booking.ID); status != models.BookingStatusConfirmed {
+		t.Fatalf("booking is %s once paid, want confirmed", status)
+	}
+
+	// The first share stays paid, the payment covering the others is refunded
+	if err := sendEvent(t, db, "evt_refund", payments.EventPaymentRefunded, booking.ID, "pi_rest", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if status := bookingStatus(t, db, booking.ID); status != models.BookingStatusPending {
+		t.Errorf("booking is %s after the covering payment was refunded, want pending", status)
+	}
+	want := []string{models.PaymentSharePaid, models.PaymentSharePending, models.PaymentSharePending}
+	for i, status := range shareStatuses(t, db, booking.ID) {
+		if status != want[i] {
+			t.Errorf("share %d is %s, want %s", i, status, want[i])
+		}
+	}
+	owed, err := amountOwed(db, &booking, 0)
+	if err != nil || owed.Minor != 2000 {
+		t.Errorf("amount owed = %v, %v, want 20.00", owed, err)
+	}
+}
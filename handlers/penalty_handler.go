@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListMyPenalties handles listing the penalties charged to the current user's account,
+// outstanding ones first
+func ListMyPenalties(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var penalties []models.AccountPenalty
+	if err := database.DB.Where("user_id = ?", user.ID).Order("settled, id DESC").Find(&penalties).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load penalties"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"penalties": penalties})
+}
+
+// chargePenalties adds the customer's outstanding penalties from no-shows at the arena to a
+// new booking there, as penalty lines, so they are paid with it. It returns the penalties
+// to mark as charged once the booking is saved.
+func chargePenalties(tx *gorm.DB, booking *models.Booking) ([]models.AccountPenalty, error) {
+	var penalties []models.AccountPenalty
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Joins("JOIN bookings ON bookings.id = account_penalties.booking_id").
+		Where("account_penalties.user_id = ? AND account_penalties.settled = ? AND bookings.arena_id = ?", booking.UserID, false, booking.ArenaID).
+		Where("account_penalties.amount_currency = ? AND account_penalties.amount_minor > 0", booking.TotalAmount.Currency).
+		Order("account_penalties.id").
+		Find(&penalties).Error; err != nil {
+		return nil, err
+	}
+
+	for _, penalty := range penalties {
+		total, err := booking.TotalAmount.Add(penalty.Amount)
+		if err != nil {
+			return nil, err
+		}
+		booking.TotalAmount = total
+		booking.Lines = append(booking.Lines, models.BookingLine{
+			Kind:        models.BookingLinePenalty,
+			Description: fmt.Sprintf("No-show penalty for booking #%d", penalty.BookingID),
+			Quantity:    1,
+			UnitPrice:   penalty.Amount,
+			Amount:      penalty.Amount,
+		})
+	}
+	return penalties, nil
+}
+
+// markPenaltiesCharged records the booking the penalties were added to.
+func markPenaltiesCharged(tx *gorm.DB, penalties []models.AccountPenalty, bookingID uint) error {
+	if len(penalties) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(penalties))
+	for _, penalty := range penalties {
+		ids = append(ids, penalty.ID)
+	}
+	return tx.Model(&models.AccountPenalty{}).Where("id IN ?", ids).
+		Updates(map[string]interface{}{"settled": true, "charged_booking_id": bookingID}).Error
+}
+
+// releasePenalties makes the penalties charged to a booking that was cancelled or refunded
+// outstanding again, so the customer's next booking at the arena carries them.
+func releasePenalties(tx *gorm.DB, booking models.Booking) error {
+	return tx.Model(&models.AccountPenalty{}).Where("charged_booking_id = ?", booking.ID).
+		Updates(map[string]interface{}{"settled": false, "charged_booking_id": nil}).Error
+}
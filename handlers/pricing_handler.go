@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/pricing"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePricingRule handles adding a pricing rule to an arena owned by the current user
+func CreatePricingRule(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadOwnedArena(c, arenaID, user)
+	if !ok {
+		return
+	}
+
+	var rule models.PricingRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule.ArenaID = arena.ID
+
+	// A field specific rule must target a field of this arena
+	if rule.FieldID != nil {
+		var count int64
+		database.DB.Model(&models.Field{}).Where("id = ? AND arena_id = ?", *rule.FieldID, arena.ID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field not found in this arena"})
+			return
+		}
+	}
+
+	if err := pricing.ValidateRule(&rule, arena.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pricing rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Pricing rule created successfully", "rule": rule})
+}
+
+// ListPricingRules handles listing the pricing rules of an arena
+func ListPricingRules(c *gin.Context) {
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	rules, err := loadPricingRules(arenaID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load pricing rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// DeletePricingRule handles removing a pricing rule from an arena owned by the current user
+func DeletePricingRule(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	ruleID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var rule models.PricingRule
+	if err := database.DB.First(&rule, ruleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pricing rule not found"})
+		return
+	}
+	if _, ok := loadOwnedArena(c, rule.ArenaID, user); !ok {
+		return
+	}
+
+	if err := database.DB.Delete(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete pricing rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pricing rule deleted successfully"})
+}
+
+// QuoteField handles previewing the price of a field for a start time and duration,
+// with the add-ons listed in add_ons ("add_on_id:quantity,...")
+func QuoteField(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	fieldID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must be an RFC 3339 timestamp"})
+		return
+	}
+	duration, err := strconv.Atoi(c.DefaultQuery("duration", "1"))
+	if err != nil || duration < 1 || duration > pricing.MaxDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": pricing.ErrInvalidDuration.Error()})
+		return
+	}
+
+	var field models.Field
+	if err := database.DB.First(&field, fieldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Field not found"})
+		return
+	}
+
+	quote, err := quoteBooking(field, start, duration, user)
+	if errors.Is(err, pricing.ErrInvalidDuration) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to quote field"})
+		return
+	}
+
+	if value := c.Query("add_ons"); value != "" {
+		if err := quoteAddOns(quote, field.ArenaID, value); err != nil {
+			if errors.Is(err, errInvalidAddOnList) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			respondAddOnError(c, err, "Failed to quote add-ons")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quote": quote})
+}
+
+// quoteBooking prices a booking of the field for the user with the arena's pricing rules.
+func quoteBooking(field models.Field, start time.Time, duration int, user models.User) (*pricing.Quote, error) {
+	var arena models.Arena
+	if err := database.DB.First(&arena, field.ArenaID).Error; err != nil {
+		return nil, err
+	}
+
+	rules, err := loadPricingRules(arena.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := isMember(user, arena.ID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	return pricing.QuoteField(arena, field, rules, start, duration, pricing.Options{
+		Member: member,
+	})
+}
+
+// loadPricingRules returns every pricing rule of the arena, field specific ones included.
+func loadPricingRules(arenaID uint) ([]models.PricingRule, error) {
+	var rules []models.PricingRule
+	err := database.DB.Where("arena_id = ?", arenaID).Order("priority DESC, id").Find(&rules).Error
+	return rules, err
+}
+
+// isMember reports whether member pricing applies to the user at the arena at the given
+// time: members of the platform and holders of a membership of the arena active then.
+func isMember(user models.User, arenaID uint, at time.Time) (bool, error) {
+	if user.Role == "member" {
+		return true, nil
+	}
+	var count int64
+	err := database.DB.Model(&models.Pass{}).
+		Where("user_id = ? AND arena_id = ? AND kind = ? AND status = ?", user.ID, arenaID, models.PassKindMembership, models.PassStatusActive).
+		Where("valid_from <= ? AND valid_until > ?", at, at).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/pricing"
+	"sparring-backend/internal/promo"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errPromoNotFound = errors.New("promo code not found")
+
+// CreatePromoCode handles creating a promo code (admins only)
+func CreatePromoCode(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok || !requireAdmin(c, user) {
+		return
+	}
+
+	var req struct {
+		Code           string      `json:"code" binding:"required"`
+		Kind           string      `json:"kind" binding:"required"`
+		PercentBps     int64       `json:"percent_bps"`
+		Amount         money.Money `json:"amount"`
+		ValidFrom      *time.Time  `json:"valid_from"`
+		ValidUntil     *time.Time  `json:"valid_until"`
+		MaxUses        int         `json:"max_uses"`
+		MaxUsesPerUser int         `json:"max_uses_per_user"`
+		SportTypes     string      `json:"sport_types"`
+		ArenaIDs       []uint      `json:"arena_ids"` // Restrict the code to these arenas
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code := models.PromoCode{
+		Code:           req.Code,
+		Kind:           req.Kind,
+		PercentBps:     req.PercentBps,
+		Amount:         req.Amount,
+		ValidFrom:      req.ValidFrom,
+		ValidUntil:     req.ValidUntil,
+		MaxUses:        req.MaxUses,
+		MaxUsesPerUser: req.MaxUsesPerUser,
+		SportTypes:     req.SportTypes,
+	}
+	if err := promo.Validate(&code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.ArenaIDs) > 0 {
+		if err := database.DB.Find(&code.Arenas, req.ArenaIDs).Error; err != nil || len(code.Arenas) != len(req.ArenaIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown arena in arena_ids"})
+			return
+		}
+	}
+
+	// Check if the code already exists
+	var existing models.PromoCode
+	if err := database.DB.Where("code = ?", code.Code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Promo code already exists"})
+		return
+	}
+
+	if err := database.DB.Create(&code).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promo code"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Promo code created successfully", "promo_code": code})
+}
+
+// ValidatePromoCode handles checking a promo code against a prospective booking
+func ValidatePromoCode(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Code        string    `json:"code" binding:"required"`
+		FieldID     uint      `json:"field_id" binding:"required"`
+		BookingTime time.Time `json:"booking_time" binding:"required"`
+		Duration    int       `json:"duration" binding:"required,min=1,max=24"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var field models.Field
+	if err := database.DB.First(&field, req.FieldID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Field not found"})
+		return
+	}
+
+	quote, err := quoteBooking(field, req.BookingTime, req.Duration, user)
+	if errors.Is(err, pricing.ErrInvalidDuration) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to quote field"})
+		return
+	}
+
+	code, discount, err := evaluatePromoCode(database.DB, req.Code, user, field, quote.Total)
+	if respondPromoError(c, err, "Failed to check promo code") {
+		return
+	}
+	total, _ := quote.Total.Sub(discount)
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":    true,
+		"code":     code.Code,
+		"subtotal": quote.Total,
+		"discount": discount,
+		"total":    total,
+	})
+}
+
+// evaluatePromoCode loads the code and computes its discount for the user and field.
+// Inside a transaction the code row is locked so usage limits hold under concurrency.
+func evaluatePromoCode(tx *gorm.DB, rawCode string, user models.User, field models.Field, subtotal money.Money) (models.PromoCode, money.Money, error) {
+	var code models.PromoCode
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Arenas").
+		Where("code = ?", promo.NormalizeCode(rawCode)).
+		First(&code).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return code, money.Money{}, errPromoNotFound
+	}
+	if err != nil {
+		return code, money.Money{}, err
+	}
+
+	var userUses int64
+	if err := tx.Model(&models.PromoRedemption{}).
+		Where("promo_code_id = ? AND user_id = ?", code.ID, user.ID).
+		Count(&userUses).Error; err != nil {
+		return code, money.Money{}, err
+	}
+
+	if err := promo.Check(code, field, time.Now(), int(userUses)); err != nil {
+		return code, money.Money{}, err
+	}
+
+	discount, err := promo.Discount(code, subtotal)
+	return code, discount, err
+}
+
+// applyPromoCode redeems a promo code on a booking that is about to be created.
+// It must run in the same transaction as the booking insert; the returned
+// redemption is saved once the booking has an ID.
+func applyPromoCode(tx *gorm.DB, rawCode string, user models.User, field models.Field, booking *models.Booking) (*models.PromoRedemption, error) {
+	code, discount, err := evaluatePromoCode(tx, rawCode, user, field, booking.TotalAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := booking.TotalAmount.Sub(discount)
+	if err != nil {
+		return nil, err
+	}
+	booking.TotalAmount = total
+	booking.Discount = discount
+	booking.PromoCodeID = &code.ID
+	booking.Lines = append(booking.Lines, models.BookingLine{
+		Kind:        models.BookingLineDiscount,
+		Description: "Promo code " + code.Code,
+		Quantity:    1,
+		UnitPrice:   discount.Neg(),
+		Amount:      discount.Neg(),
+	})
+
+	if err := tx.Model(&code).UpdateColumn("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.PromoRedemption{
+		PromoCodeID: code.ID,
+		UserID:      user.ID,
+		Discount:    discount,
+	}, nil
+}
+
+// releasePromoCode gives back the redemption of the promo code used on a cancelled booking,
+// so it counts neither toward the code's total uses nor the customer's.
+func releasePromoCode(tx *gorm.DB, booking models.Booking) error {
+	if booking.PromoCodeID == nil {
+		return nil
+	}
+	result := tx.Where("booking_id = ? AND promo_code_id = ?", booking.ID, *booking.PromoCodeID).Delete(&models.PromoRedemption{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return tx.Model(&models.PromoCode{}).
+		Where("id = ? AND used_count > 0", *booking.PromoCodeID).
+		UpdateColumn("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+// respondPromoError writes the response for a promo code that cannot be used
+// and reports whether there was an error.
+func respondPromoError(c *gin.Context, err error, fallback string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, errPromoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+	case errors.Is(err, promo.ErrNotActive), errors.Is(err, promo.ErrExhausted),
+		errors.Is(err, promo.ErrUserLimit), errors.Is(err, promo.ErrNotApplicable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/promo"
+	"sparring-backend/internal/testdb"
+	"testing"
+	"time"
+)
+
+func TestReleasePromoCode(t *testing.T) {
+	db := testdb.New(t, &models.Arena{}, &models.Field{}, &models.PromoCode{}, &models.PromoRedemption{}, &models.Booking{})
+	field := models.Field{ArenaID: 1, FieldName: "Court 1", SportType: "Tennis"}
+	if err := db.Create(&field).Error; err != nil {
+		t.Fatal(err)
+	}
+	code := models.PromoCode{Code: "ONCE", Kind: models.PromoKindPercentage, PercentBps: 1000, MaxUses: 1, MaxUsesPerUser: 1}
+	if err := db.Create(&code).Error; err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{}
+	user.ID = 5
+
+	book := func() (models.Booking, error) {
+		booking := models.Booking{UserID: user.ID, ArenaID: 1, FieldID: field.ID, BookingTime: time.Now().Add(24 * time.Hour), Duration: 1,
+			TotalAmount: money.New(2000, "EUR"), Status: models.BookingStatusPending}
+		redemption, err := applyPromoCode(db, "once", user, field, &booking)
+		if err != nil {
+			return booking, err
+		}
+		if err := db.Omit("Lines").Create(&booking).Error; err != nil {
+			t.Fatal(err)
+		}
+		redemption.BookingID = booking.ID
+		if err := db.Create(redemption).Error; err != nil {
+			t.Fatal(err)
+		}
+		return booking, nil
+	}
+
+	booking, err := book()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booking.TotalAmount.Minor != 1800 {
+		t.Fatalf("total = %d, want 1800", booking.TotalAmount.Minor)
+	}
+	if _, err := book(); !errors.Is(err, promo.ErrExhausted) && !errors.Is(err, promo.ErrUserLimit) {
+		t.Fatalf("second redemption: err = %v, want the code to be used up", err)
+	}
+
+	if err := releasePromoCode(db, booking); err != nil {
+		t.Fatal(err)
+	}
+	// Releasing twice must not give back a use that was never taken
+	if err := releasePromoCode(db, booking); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(&code, code.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if code.UsedCount != 0 {
+		t.Errorf("used count = %d, want 0", code.UsedCount)
+	}
+	if _, err := book(); err != nil {
+		t.Errorf("redeeming after the cancellation: %v", err)
+	}
+}
@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/reporting"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// paidBookingStatuses are the statuses of bookings that were paid for and held their field
+var paidBookingStatuses = []string{
+	models.BookingStatusConfirmed,
+	models.BookingStatusCheckedIn,
+	models.BookingStatusCompleted,
+	models.BookingStatusNoShow,
+}
+
+// reportScope is the arena, date range and optional field a report covers
+type reportScope struct {
+	arena   models.Arena
+	rng     reporting.Range
+	fieldID *uint
+}
+
+// bookings returns a query on the bookings starting in the scope.
+func (s reportScope) bookings() *gorm.DB {
+	query := database.DB.Model(&models.Booking{}).
+		Where("arena_id = ? AND booking_time >= ? AND booking_time < ?", s.arena.ID, s.rng.Start, s.rng.End)
+	if s.fieldID != nil {
+		query = query.Where("field_id = ?", *s.fieldID)
+	}
+	return query
+}
+
+// GetArenaReportSummary handles the arena owner's overview of a date range: bookings by status,
+// cancellation and no-show rates, revenue and occupancy, in total and per field
+func GetArenaReportSummary(c *gin.Context) {
+	scope, ok := loadReportScope(c)
+	if !ok {
+		return
+	}
+
+	var statuses []struct {
+		Status string
+		Count  int64
+	}
+	if err := scope.bookings().Select("status, COUNT(*) AS count").Group("status").Scan(&statuses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return
+	}
+	counts := map[string]int64{}
+	var total int64
+	for _, row := range statuses {
+		counts[row.Status] = row.Count
+		total += row.Count
+	}
+
+	var fields []struct {
+		FieldID      uint
+		Bookings     int64
+		BookedHours  int64
+		RevenueMinor int64
+	}
+	if err := scope.bookings().
+		Select("field_id, COUNT(*) AS bookings, SUM(duration) AS booked_hours, SUM(total_amount_minor) AS revenue_minor").
+		Where("status IN ?", paidBookingStatuses).
+		Group("field_id").Order("field_id").
+		Scan(&fields).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return
+	}
+
+	heatmap, ok := occupancyHeatmap(c, scope)
+	if !ok {
+		return
+	}
+	booked, capacity := heatmap.Totals()
+	hoursInRange := int64(scope.rng.End.Sub(scope.rng.Start) / time.Hour)
+
+	var revenue int64
+	perField := []gin.H{}
+	for _, field := range fields {
+		revenue += field.RevenueMinor
+		perField = append(perField, gin.H{
+			"field_id":       field.FieldID,
+			"bookings":       field.Bookings,
+			"booked_hours":   field.BookedHours,
+			"occupancy_rate": reporting.Rate(field.BookedHours, hoursInRange),
+			"revenue":        money.New(field.RevenueMinor, scope.arena.Currency),
+		})
+	}
+
+	cancelled := counts[models.BookingStatusCancelled] + counts[models.BookingStatusRefunded]
+	played := counts[models.BookingStatusCheckedIn] + counts[models.BookingStatusCompleted] + counts[models.BookingStatusNoShow]
+	c.JSON(http.StatusOK, gin.H{
+		"from":              scope.rng.Start,
+		"to":                scope.rng.End,
+		"bookings":          total,
+		"by_status":         counts,
+		"cancellation_rate": reporting.Rate(cancelled, total),
+		"no_show_rate":      reporting.Rate(counts[models.BookingStatusNoShow], played),
+		"revenue":           money.New(revenue, scope.arena.Currency),
+		"booked_hours":      booked,
+		"capacity_hours":    capacity,
+		"occupancy_rate":    reporting.Rate(int64(booked), int64(capacity)),
+		"fields":            perField,
+	})
+}
+
+// GetArenaOccupancy handles the arena owner's occupancy heatmap: the share of field hours
+// booked for every local weekday and hour of the range, as JSON or CSV with ?format=csv
+func GetArenaOccupancy(c *gin.Context) {
+	scope, ok := loadReportScope(c)
+	if !ok {
+		return
+	}
+	heatmap, ok := occupancyHeatmap(c, scope)
+	if !ok {
+		return
+	}
+	cells := heatmap.Cells()
+
+	if c.Query("format") == "csv" {
+		rows := make([][]string, 0, len(cells))
+		for _, cell := range cells {
+			rows = append(rows, []string{
+				time.Weekday(cell.Weekday).String(),
+				fmt.Sprintf("%02d:00", cell.Hour),
+				strconv.Itoa(cell.BookedHours),
+				strconv.Itoa(cell.CapacityHours),
+				strconv.FormatFloat(cell.Rate, 'f', 4, 64),
+			})
+		}
+		writeCSV(c, fmt.Sprintf("arena-%d-occupancy.csv", scope.arena.ID), []string{"weekday", "hour", "booked_hours", "capacity_hours", "rate"}, rows)
+		return
+	}
+
+	booked, capacity := heatmap.Totals()
+	c.JSON(http.StatusOK, gin.H{
+		"from":           scope.rng.Start,
+		"to":             scope.rng.End,
+		"occupancy_rate": reporting.Rate(int64(booked), int64(capacity)),
+		"cells":          cells,
+	})
+}
+
+// GetArenaRevenue handles the arena owner's revenue of paid bookings grouped by day, week
+// or month (period, day by default), as JSON or CSV with ?format=csv
+func GetArenaRevenue(c *gin.Context) {
+	scope, ok := loadReportScope(c)
+	if !ok {
+		return
+	}
+	format, err := reporting.PeriodFormat(c.DefaultQuery("period", "day"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Shift to the arena's local time. The offset at the start of the range is used for the
+	// whole range, so bookings around a DST change may land in the neighbouring period.
+	_, offset := scope.rng.Start.Zone()
+
+	var rows []struct {
+		Period       string
+		Bookings     int64
+		BookedHours  int64
+		RevenueMinor int64
+	}
+	if err := scope.bookings().
+		Select("DATE_FORMAT(DATE_ADD(booking_time, INTERVAL ? SECOND), ?) AS period, COUNT(*) AS bookings, SUM(duration) AS booked_hours, SUM(total_amount_minor) AS revenue_minor", offset, format).
+		Where("status IN ?", paidBookingStatuses).
+		Group("period").Order("period").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		records := make([][]string, 0, len(rows))
+		for _, row := range rows {
+			records = append(records, []string{
+				row.Period,
+				strconv.FormatInt(row.Bookings, 10),
+				strconv.FormatInt(row.BookedHours, 10),
+				money.New(row.RevenueMinor, scope.arena.Currency).Decimal(),
+				scope.arena.Currency,
+			})
+		}
+		writeCSV(c, fmt.Sprintf("arena-%d-revenue.csv", scope.arena.ID), []string{"period", "bookings", "booked_hours", "revenue", "currency"}, records)
+		return
+	}
+
+	periods := []gin.H{}
+	total := money.Zero(scope.arena.Currency)
+	for _, row := range rows {
+		revenue := money.New(row.RevenueMinor, scope.arena.Currency)
+		total, _ = total.Add(revenue)
+		periods = append(periods, gin.H{"period": row.Period, "bookings": row.Bookings, "booked_hours": row.BookedHours, "revenue": revenue})
+	}
+	c.JSON(http.StatusOK, gin.H{"from": scope.rng.Start, "to": scope.rng.End, "total": total, "periods": periods})
+}
+
+// GetArenaTopCustomers handles the arena owner's best customers by revenue over the range,
+// limit of them (10 by default), as JSON or CSV with ?format=csv
+func GetArenaTopCustomers(c *gin.Context) {
+	scope, ok := loadReportScope(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	var rows []struct {
+		UserID       uint
+		Bookings     int64
+		BookedHours  int64
+		RevenueMinor int64
+	}
+	if err := scope.bookings().
+		Select("user_id, COUNT(*) AS bookings, SUM(duration) AS booked_hours, SUM(total_amount_minor) AS revenue_minor").
+		Where("status IN ?", paidBookingStatuses).
+		Group("user_id").Order("revenue_minor DESC, bookings DESC, user_id").Limit(limit).
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return
+	}
+
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.UserID)
+	}
+	var users []models.User
+	if err := database.DB.Find(&users, ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load customers"})
+		return
+	}
+	byID := map[uint]models.User{}
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	if c.Query("format") == "csv" {
+		records := make([][]string, 0, len(rows))
+		for _, row := range rows {
+			records = append(records, []string{
+				strconv.FormatUint(uint64(row.UserID), 10),
+				byID[row.UserID].Name,
+				byID[row.UserID].Email,
+				strconv.FormatInt(row.Bookings, 10),
+				strconv.FormatInt(row.BookedHours, 10),
+				money.New(row.RevenueMinor, scope.arena.Currency).Decimal(),
+				scope.arena.Currency,
+			})
+		}
+		writeCSV(c, fmt.Sprintf("arena-%d-top-customers.csv", scope.arena.ID), []string{"user_id", "name", "email", "bookings", "booked_hours", "revenue", "currency"}, records)
+		return
+	}
+
+	customers := []gin.H{}
+	for _, row := range rows {
+		customers = append(customers, gin.H{
+			"user_id":      row.UserID,
+			"name":         byID[row.UserID].Name,
+			"email":        byID[row.UserID].Email,
+			"bookings":     row.Bookings,
+			"booked_hours": row.BookedHours,
+			"revenue":      money.New(row.RevenueMinor, scope.arena.Currency),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"from": scope.rng.Start, "to": scope.rng.End, "customers": customers})
+}
+
+// loadReportScope checks the current user owns the arena and parses the from, to and
+// field_id filters, writing an error response when they are invalid.
+func loadReportScope(c *gin.Context) (reportScope, bool) {
+	user, ok := currentUser(c)
+	if !ok {
+		return reportScope{}, false
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return reportScope{}, false
+	}
+	arena, ok := loadOwnedArena(c, arenaID, user)
+	if !ok {
+		return reportScope{}, false
+	}
+
+	rng, err := reporting.ParseRange(c.Query("from"), c.Query("to"), arena.Zone(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return reportScope{}, false
+	}
+	scope := reportScope{arena: arena, rng: rng}
+
+	if value := c.Query("field_id"); value != "" {
+		fieldID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid field_id"})
+			return reportScope{}, false
+		}
+		var count int64
+		database.DB.Model(&models.Field{}).Where("id = ? AND arena_id = ?", fieldID, arena.ID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Field not found in this arena"})
+			return reportScope{}, false
+		}
+		id := uint(fieldID)
+		scope.fieldID = &id
+	}
+	return scope, true
+}
+
+// occupancyHeatmap counts the hours of paid bookings on the scope's fields. Bookings inside
+// the range are counted per local start hour and duration by the database; the few running
+// over either end of the range, including those that started the day before, are added
+// one by one for the hours inside it.
+func occupancyHeatmap(c *gin.Context, scope reportScope) (*reporting.Heatmap, bool) {
+	fields := int64(1)
+	if scope.fieldID == nil {
+		database.DB.Model(&models.Field{}).Where("arena_id = ?", scope.arena.ID).Count(&fields)
+	}
+	heatmap := reporting.NewHeatmap(scope.rng, int(fields), scope.arena.Zone())
+
+	query := func() *gorm.DB {
+		query := database.DB.Model(&models.Booking{}).
+			Where("arena_id = ? AND status IN ?", scope.arena.ID, paidBookingStatuses)
+		if scope.fieldID != nil {
+			query = query.Where("field_id = ?", *scope.fieldID)
+		}
+		return query
+	}
+
+	// As in the revenue report, the offset at the start of the range is used for the whole
+	// range, so bookings around a DST change may land in the neighbouring hour.
+	_, offset := scope.rng.Start.Zone()
+	var slots []struct {
+		Weekday  int
+		Hour     int
+		Duration int
+		Count    int
+	}
+	if err := query().
+		Select("DAYOFWEEK(DATE_ADD(booking_time, INTERVAL ? SECOND)) - 1 AS weekday, "+
+			"HOUR(DATE_ADD(booking_time, INTERVAL ? SECOND)) AS hour, duration, COUNT(*) AS count", offset, offset).
+		Where("booking_time >= ? AND DATE_ADD(booking_time, INTERVAL duration HOUR) <= ?", scope.rng.Start, scope.rng.End).
+		Group("weekday, hour, duration").
+		Scan(&slots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return nil, false
+	}
+	for _, slot := range slots {
+		heatmap.AddHours(slot.Weekday, slot.Hour, slot.Duration, slot.Count)
+	}
+
+	var edges []models.Booking
+	if err := query().Select("booking_time, duration").
+		Where("booking_time >= ? AND booking_time < ?", scope.rng.Start.Add(-24*time.Hour), scope.rng.End).
+		Where("booking_time < ? OR DATE_ADD(booking_time, INTERVAL duration HOUR) > ?", scope.rng.Start, scope.rng.End).
+		Find(&edges).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return nil, false
+	}
+	for _, booking := range edges {
+		heatmap.Add(booking.BookingTime, booking.Duration)
+	}
+	return heatmap, true
+}
+
+// writeCSV sends the rows as a CSV attachment. Cells are escaped with reporting.CSVCell, so names
+// and descriptions typed by users can't run as spreadsheet formulas.
+func writeCSV(c *gin.Context, filename string, header []string, rows [][]string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(header)
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = reporting.CSVCell(cell)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+}
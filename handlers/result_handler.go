@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/rating"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errNotAMatch        = errors.New("results can only be reported for bookings between two teams")
+	errMatchNotPlayed   = errors.New("results can only be reported once the match has been played")
+	errSportMismatch    = errors.New("both teams must play the same sport to be rated")
+	errResultFinal      = errors.New("this result has already been confirmed")
+	errResultNotPending = errors.New("this result is not waiting for confirmation")
+	errOwnResult        = errors.New("only a captain of the other team who doesn't also captain the reporting team can respond to this result")
+	errNotCaptain       = errors.New("only captains of the teams playing can do this")
+)
+
+// ReportMatchResult handles a captain of either team reporting the score of a played match.
+// When the other team already reported the same score, the result is confirmed; a different
+// score puts the result in dispute until one of the captains reports again.
+func ReportMatchResult(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	bookingID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		HomeScore *int `json:"home_score" binding:"required,min=0"`
+		AwayScore *int `json:"away_score" binding:"required,min=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var result models.MatchResult
+	var reportingTeamID uint
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		if booking.TeamID == nil || booking.OpponentTeamID == nil {
+			return errNotAMatch
+		}
+		if booking.Status != models.BookingStatusCheckedIn && booking.Status != models.BookingStatusCompleted {
+			return errMatchNotPlayed
+		}
+
+		// The reporter must captain one of the teams playing
+		switch {
+		case teamRole(*booking.TeamID, user.ID) == models.TeamRoleCaptain:
+			reportingTeamID = *booking.TeamID
+		case teamRole(*booking.OpponentTeamID, user.ID) == models.TeamRoleCaptain:
+			reportingTeamID = *booking.OpponentTeamID
+		default:
+			return errNotCaptain
+		}
+
+		err := tx.Where("booking_id = ?", booking.ID).First(&result).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			var home, away models.Team
+			if err := tx.First(&home, *booking.TeamID).Error; err != nil {
+				return err
+			}
+			if err := tx.First(&away, *booking.OpponentTeamID).Error; err != nil {
+				return err
+			}
+			if home.SportType != away.SportType {
+				return errSportMismatch
+			}
+			result = models.MatchResult{
+				BookingID:        booking.ID,
+				HomeTeamID:       home.ID,
+				AwayTeamID:       away.ID,
+				SportType:        home.SportType,
+				HomeScore:        *req.HomeScore,
+				AwayScore:        *req.AwayScore,
+				ReportedByTeamID: reportingTeamID,
+				ReportedByID:     user.ID,
+				Status:           models.MatchResultPending,
+			}
+			return tx.Create(&result).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case result.Status == models.MatchResultConfirmed:
+			return errResultFinal
+		case result.Status == models.MatchResultPending && result.ReportedByTeamID != reportingTeamID &&
+			result.HomeScore == *req.HomeScore && result.AwayScore == *req.AwayScore:
+			// Both captains agree on the score
+			return confirmMatchResult(tx, &result)
+		case result.Status == models.MatchResultPending && result.ReportedByTeamID != reportingTeamID:
+			result.Status = models.MatchResultDisputed
+			result.DisputeReason = "The teams reported different scores"
+		default:
+			// The reporter corrects their own report, or reopens a disputed result
+			result.Status = models.MatchResultPending
+			result.DisputeReason = ""
+			result.ReportedByTeamID = reportingTeamID
+			result.ReportedByID = user.ID
+		}
+		result.HomeScore, result.AwayScore = *req.HomeScore, *req.AwayScore
+		return tx.Save(&result).Error
+	})
+	if respondResultError(c, err, "Booking") {
+		return
+	}
+
+	notifyResultUpdate(result, reportingTeamID)
+	c.JSON(http.StatusOK, gin.H{"message": "Result reported", "result": result})
+}
+
+// GetMatchResult handles retrieving the result of a match booking
+func GetMatchResult(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	bookingID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	if err := database.DB.Preload("Arena").First(&booking, bookingID).Error; err != nil || !canViewBooking(user, booking) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	var result models.MatchResult
+	if err := database.DB.Where("booking_id = ?", booking.ID).First(&result).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No result has been reported for this booking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// ConfirmMatchResult handles the captain of the team that didn't report a result confirming
+// it, which updates the ratings of both teams
+func ConfirmMatchResult(c *gin.Context) {
+	respondToMatchResult(c, func(tx *gorm.DB, result *models.MatchResult) error {
+		return confirmMatchResult(tx, result)
+	})
+}
+
+// DisputeMatchResult handles the captain of the team that didn't report a result disputing it
+func DisputeMatchResult(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"required,max=500"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondToMatchResult(c, func(tx *gorm.DB, result *models.MatchResult) error {
+		result.Status = models.MatchResultDisputed
+		result.DisputeReason = req.Reason
+		return tx.Save(result).Error
+	})
+}
+
+// respondToMatchResult loads a pending result for the captain of the team that has to answer it,
+// applies the response and notifies the reporting team.
+func respondToMatchResult(c *gin.Context, respond func(tx *gorm.DB, result *models.MatchResult) error) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	resultID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var result models.MatchResult
+	var respondingTeamID uint
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&result, resultID).Error; err != nil {
+			return err
+		}
+		respondingTeamID = result.HomeTeamID
+		if result.ReportedByTeamID == result.HomeTeamID {
+			respondingTeamID = result.AwayTeamID
+		}
+		// A user captaining both teams could otherwise confirm their own report
+		if result.ReportedByID == user.ID || teamRole(result.ReportedByTeamID, user.ID) == models.TeamRoleCaptain {
+			return errOwnResult
+		}
+		if teamRole(respondingTeamID, user.ID) != models.TeamRoleCaptain {
+			return errNotCaptain
+		}
+		if result.Status != models.MatchResultPending {
+			return errResultNotPending
+		}
+		return respond(tx, &result)
+	})
+	if respondResultError(c, err, "Match result") {
+		return
+	}
+
+	notifyResultUpdate(result, respondingTeamID)
+	c.JSON(http.StatusOK, gin.H{"message": "Result " + result.Status, "result": result})
+}
+
+// confirmMatchResult marks the result as confirmed and applies the Elo update to both teams.
+func confirmMatchResult(tx *gorm.DB, result *models.MatchResult) error {
+	// Lock the teams in ID order so concurrent confirmations can't deadlock or lose updates
+	var teams []models.Team
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Order("id").
+		Find(&teams, []uint{result.HomeTeamID, result.AwayTeamID}).Error; err != nil {
+		return err
+	}
+	if len(teams) != 2 {
+		return gorm.ErrRecordNotFound
+	}
+	home, away := teams[0], teams[1]
+	if home.ID != result.HomeTeamID {
+		home, away = away, home
+	}
+
+	newHome, newAway := rating.Update(home.Rating, away.Rating, rating.Outcome(result.HomeScore, result.AwayScore))
+	changes := []models.RatingChange{
+		{TeamID: home.ID, MatchResultID: result.ID, OpponentTeamID: away.ID, SportType: result.SportType,
+			RatingBefore: home.Rating, RatingAfter: newHome, Delta: newHome - home.Rating},
+		{TeamID: away.ID, MatchResultID: result.ID, OpponentTeamID: home.ID, SportType: result.SportType,
+			RatingBefore: away.Rating, RatingAfter: newAway, Delta: newAway - away.Rating},
+	}
+	for _, change := range changes {
+		if err := tx.Model(&models.Team{}).Where("id = ?", change.TeamID).Update("rating", change.RatingAfter).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Create(&changes).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result.Status = models.MatchResultConfirmed
+	result.DisputeReason = ""
+	result.ConfirmedAt = &now
+	return tx.Save(result).Error
+}
+
+// respondResultError writes the response for a failed result operation on the named resource,
+// returning false when err is nil.
+func respondResultError(c *gin.Context, err error, resource string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": resource + " not found"})
+	case errors.Is(err, errNotCaptain), errors.Is(err, errOwnResult):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, errResultFinal), errors.Is(err, errResultNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errNotAMatch), errors.Is(err, errMatchNotPlayed), errors.Is(err, errSportMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save match result"})
+	}
+	return true
+}
+
+// notifyResultUpdate tells the captains of the team that didn't act about the new state of the result.
+func notifyResultUpdate(result models.MatchResult, actingTeamID uint) {
+	otherTeamID := result.HomeTeamID
+	if actingTeamID == result.HomeTeamID {
+		otherTeamID = result.AwayTeamID
+	}
+	score := fmt.Sprintf("%d-%d", result.HomeScore, result.AwayScore)
+
+	switch result.Status {
+	case models.MatchResultPending:
+		notifyTeamCaptains(otherTeamID, "Confirm your match result",
+			fmt.Sprintf("A score of %s was reported for booking #%d. Confirm or dispute it.", score, result.BookingID))
+	case models.MatchResultDisputed:
+		notifyTeamCaptains(otherTeamID, "Match result disputed",
+			fmt.Sprintf("The %s result for booking #%d is disputed: %s", score, result.BookingID, result.DisputeReason))
+	case models.MatchResultConfirmed:
+		notifyTeamCaptains(otherTeamID, "Match result confirmed",
+			fmt.Sprintf("The %s result for booking #%d is confirmed and ratings were updated.", score, result.BookingID))
+	}
+}
+
+// GetTeamRatingHistory handles listing the rating changes of a team, most recent first
+func GetTeamRatingHistory(c *gin.Context) {
+	teamID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.First(&team, teamID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	var changes []models.RatingChange
+	if err := database.DB.Where("team_id = ?", team.ID).Order("created_at DESC, id DESC").Limit(limit).Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load rating history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"team_id": team.ID, "sport_type": team.SportType, "rating": team.Rating, "history": changes})
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListArenas handles listing arenas with their fields, photos and average rating, optionally filtered
+// by sport_type and min_rating and sorted by rating with sort=rating
+func ListArenas(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a positive number"})
+		return
+	}
+
+	query := database.DB.Preload("Fields").Preload("Media", mediaOrder).Limit(limit).Offset(offset)
+	if sportType := c.Query("sport_type"); sportType != "" {
+		query = query.Where("id IN (?)", database.DB.Model(&models.Field{}).Select("arena_id").Where("sport_type = ?", sportType))
+	}
+	if minRating := c.Query("min_rating"); minRating != "" {
+		value, err := strconv.ParseFloat(minRating, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_rating"})
+			return
+		}
+		query = query.Where("rating_average >= ?", value)
+	}
+	if c.Query("sort") == "rating" {
+		query = query.Order("rating_average DESC, rating_count DESC, id")
+	} else {
+		query = query.Order("id")
+	}
+
+	var arenas []models.Arena
+	if err := query.Find(&arenas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load arenas"})
+		return
+	}
+	for i := range arenas {
+		arenas[i].Media = signMedia(c.Request.Context(), arenas[i].Media)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"arenas": arenas})
+}
+
+// CreateReview handles a user reviewing an arena, or one of its fields, where they have a
+// completed booking. Reviewing the same arena or field again updates the earlier review.
+func CreateReview(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+		Text    string `json:"text" binding:"max=5000"`
+		FieldID *uint  `json:"field_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var arena models.Arena
+	if err := database.DB.First(&arena, arenaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Arena not found"})
+		return
+	}
+
+	// Only customers who actually played there can review
+	played := database.DB.Model(&models.Booking{}).
+		Where("arena_id = ? AND user_id = ? AND status = ?", arena.ID, user.ID, models.BookingStatusCompleted)
+	if req.FieldID != nil {
+		played = played.Where("field_id = ?", *req.FieldID)
+	}
+	var completed int64
+	if err := played.Count(&completed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check bookings"})
+		return
+	}
+	if completed == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only review places where you have a completed booking"})
+		return
+	}
+
+	fieldKey := uint(0)
+	if req.FieldID != nil {
+		fieldKey = *req.FieldID
+	}
+
+	var review models.Review
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("arena_id = ? AND user_id = ? AND field_key = ?", arena.ID, user.ID, fieldKey).
+			First(&review).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		review.ArenaID = arena.ID
+		review.FieldID = req.FieldID
+		review.FieldKey = fieldKey
+		review.UserID = user.ID
+		review.Rating = req.Rating
+		review.Text = req.Text
+		if err := tx.Save(&review).Error; err != nil {
+			return err
+		}
+		return updateArenaRating(tx, arena.ID)
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Lost a race with another request creating the same review
+		c.JSON(http.StatusConflict, gin.H{"error": "You already reviewed this, try again to update your review"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review saved successfully", "review": review})
+}
+
+// ListArenaReviews handles listing the visible reviews of an arena, optionally for one field_id
+func ListArenaReviews(c *gin.Context) {
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var arena models.Arena
+	if err := database.DB.First(&arena, arenaID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Arena not found"})
+		return
+	}
+
+	query := database.DB.Preload("User").Where("arena_id = ? AND hidden = ?", arena.ID, false).Order("created_at DESC")
+	if fieldID := c.Query("field_id"); fieldID != "" {
+		query = query.Where("field_id = ?", fieldID)
+	}
+	var reviews []models.Review
+	if err := query.Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reviews"})
+		return
+	}
+
+	views := make([]gin.H, 0, len(reviews))
+	for _, review := range reviews {
+		views = append(views, gin.H{
+			"id":          review.ID,
+			"field_id":    review.FieldID,
+			"author":      review.User.Name,
+			"rating":      review.Rating,
+			"text":        review.Text,
+			"owner_reply": review.OwnerReply,
+			"replied_at":  review.RepliedAt,
+			"created_at":  review.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rating_average": arena.RatingAverage, "rating_count": arena.RatingCount, "reviews": views})
+}
+
+// ReplyToReview handles the arena owner publicly replying to a review
+func ReplyToReview(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	reviewID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reply string `json:"reply" binding:"required,max=5000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var review models.Review
+	if err := database.DB.First(&review, reviewID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if _, ok := loadOwnedArena(c, review.ArenaID, user); !ok {
+		return
+	}
+
+	now := time.Now()
+	review.OwnerReply = req.Reply
+	review.RepliedAt = &now
+	if err := database.DB.Model(&review).Updates(map[string]interface{}{"owner_reply": review.OwnerReply, "replied_at": now}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reply"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reply saved successfully", "review": review})
+}
+
+// ReportReview handles a user flagging a review as abusive for moderation
+func ReportReview(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	reviewID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required,max=500"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var review models.Review
+	if err := database.DB.First(&review, reviewID).Error; err != nil || review.Hidden {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+
+	report := models.ReviewReport{
+		ReviewID:   review.ID,
+		ReporterID: user.ID,
+		Reason:     req.Reason,
+		Status:     models.ReportOpen,
+	}
+	// Reporting the same review twice is a no-op
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&report).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review reported, a moderator will look at it"})
+}
+
+// ListReviewReports handles listing the open review reports, oldest first (admins only)
+func ListReviewReports(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if !requireAdmin(c, user) {
+		return
+	}
+
+	var reports []models.ReviewReport
+	if err := database.DB.Preload("Review").Where("status = ?", models.ReportOpen).Order("created_at").Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+// HideReview handles a moderator hiding an abusive review, which upholds its open reports (admins only)
+func HideReview(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"required,max=500"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	moderateReview(c, true, req.Reason, models.ReportUpheld)
+}
+
+// RestoreReview handles a moderator making a review visible again, which dismisses its open reports (admins only)
+func RestoreReview(c *gin.Context) {
+	moderateReview(c, false, "", models.ReportDismissed)
+}
+
+// moderateReview sets the visibility of a review, resolves its open reports and updates the arena rating.
+func moderateReview(c *gin.Context, hidden bool, reason, resolution string) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if !requireAdmin(c, user) {
+		return
+	}
+	reviewID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var review models.Review
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, reviewID).Error; err != nil {
+			return err
+		}
+		review.Hidden = hidden
+		review.HiddenReason = reason
+		if err := tx.Model(&review).Updates(map[string]interface{}{"hidden": hidden, "hidden_reason": reason}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.ReviewReport{}).
+			Where("review_id = ? AND status = ?", review.ID, models.ReportOpen).
+			Updates(map[string]interface{}{"status": resolution, "resolved_by": user.ID, "resolved_at": now}).Error; err != nil {
+			return err
+		}
+		return updateArenaRating(tx, review.ArenaID)
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review updated", "review": review})
+}
+
+// updateArenaRating recomputes the rating average and count of an arena from its visible reviews.
+func updateArenaRating(tx *gorm.DB, arenaID uint) error {
+	var aggregate struct {
+		Count   int
+		Average float64
+	}
+	if err := tx.Model(&models.Review{}).
+		Select("COUNT(*) AS count, COALESCE(AVG(rating), 0) AS average").
+		Where("arena_id = ? AND hidden = ?", arenaID, false).
+		Scan(&aggregate).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Arena{}).Where("id = ?", arenaID).Updates(map[string]interface{}{
+		"rating_average": math.Round(aggregate.Average*100) / 100,
+		"rating_count":   aggregate.Count,
+	}).Error
+}
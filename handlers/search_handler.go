@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/geo"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/search"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSearchRadiusKm bounds proximity searches
+const maxSearchRadiusKm = 500
+
+// SearchArenas handles finding arenas within radius_km of lat/lng, closest first, with their
+// fields matching the sport and, when available_at is given, free for duration hours from then.
+// Candidates are narrowed with geohash prefixes before exact distances are computed.
+func SearchArenas(c *gin.Context) {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	center := geo.Point{Lat: lat, Lng: lng}
+	if errLat != nil || errLng != nil || !center.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lng must be valid coordinates"})
+		return
+	}
+	radius, err := strconv.ParseFloat(c.DefaultQuery("radius_km", "10"), 64)
+	if err != nil || radius <= 0 || radius > maxSearchRadiusKm {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "radius_km must be between 0 and 500"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+	sport := c.Query("sport")
+
+	var availableAt time.Time
+	duration := 1
+	if value := c.Query("available_at"); value != "" {
+		if availableAt, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "available_at must be an RFC 3339 time"})
+			return
+		}
+		if duration, err = strconv.Atoi(c.DefaultQuery("duration", "1")); err != nil || duration < 1 || duration > 24 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be between 1 and 24 hours"})
+			return
+		}
+	}
+
+	// Each prefix is a range scan on the geohash index
+	query := database.DB.Model(&models.Arena{}).Where("geohash <> ''")
+	prefixes := database.DB
+	for _, prefix := range geo.CoveringGeohashes(center, radius) {
+		prefixes = prefixes.Or("geohash LIKE ?", prefix+"%")
+	}
+	query = query.Where(prefixes)
+	if sport != "" {
+		query = query.Where("id IN (?)", database.DB.Model(&models.Field{}).Select("arena_id").Where("sport_type = ?", sport))
+	}
+	fieldQuery := func(db *gorm.DB) *gorm.DB {
+		if sport != "" {
+			return db.Where("sport_type = ?", sport)
+		}
+		return db
+	}
+
+	var arenas []models.Arena
+	if err := query.Preload("Fields", fieldQuery).Find(&arenas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search arenas"})
+		return
+	}
+
+	// Keep the arenas actually within the radius, closest first
+	type hit struct {
+		arena    models.Arena
+		distance float64
+	}
+	var hits []hit
+	for _, arena := range arenas {
+		distance := geo.DistanceKm(center, geo.Point{Lat: *arena.Latitude, Lng: *arena.Longitude})
+		if distance <= radius {
+			hits = append(hits, hit{arena: arena, distance: distance})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].distance < hits[j].distance })
+
+	var busy map[uint]bool
+	if !availableAt.IsZero() {
+		var fieldIDs []uint
+		for _, h := range hits {
+			for _, field := range h.arena.Fields {
+				fieldIDs = append(fieldIDs, field.ID)
+			}
+		}
+		if busy, err = busyFields(fieldIDs, availableAt, duration); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability"})
+			return
+		}
+	}
+
+	results := []gin.H{}
+	for _, h := range hits {
+		fields := []models.Field{}
+		for _, field := range h.arena.Fields {
+			if !busy[field.ID] {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		results = append(results, gin.H{
+			"id":             h.arena.ID,
+			"name":           h.arena.Name,
+			"location":       h.arena.Location,
+			"latitude":       h.arena.Latitude,
+			"longitude":      h.arena.Longitude,
+			"rating_average": h.arena.RatingAverage,
+			"rating_count":   h.arena.RatingCount,
+			"distance_km":    math.Round(h.distance*100) / 100,
+			"fields":         fields,
+		})
+		if len(results) == limit {
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"arenas": results})
+}
+
+// busyFields returns which of the fields have an active booking overlapping the given time.
+func busyFields(fieldIDs []uint, start time.Time, duration int) (map[uint]bool, error) {
+	busy := map[uint]bool{}
+	if len(fieldIDs) == 0 {
+		return busy, nil
+	}
+	end := start.Add(time.Duration(duration) * time.Hour)
+
+	var ids []uint
+	err := database.DB.Model(&models.Booking{}).Distinct("field_id").
+		Where("field_id IN ?", fieldIDs).
+		Scopes(models.ActiveBookingsBetween(start, end)).
+		Pluck("field_id", &ids).Error
+	for _, id := range ids {
+		busy[id] = true
+	}
+	return busy, err
+}
+
+// SearchText handles full-text search over arena names, locations, descriptions and field
+// sports, tolerating typos and partial words. Results are ordered by relevance.
+func SearchText(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" || len(query) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q must be between 1 and 200 characters"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	hits, err := search.Default.Search(c.Request.Context(), query, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+	if len(hits) == 0 {
+		c.JSON(http.StatusOK, gin.H{"results": []gin.H{}})
+		return
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ArenaID
+	}
+	var arenas []models.Arena
+	if err := database.DB.Preload("Fields").Find(&arenas, ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load arenas"})
+		return
+	}
+	byID := map[uint]models.Arena{}
+	for _, arena := range arenas {
+		byID[arena.ID] = arena
+	}
+
+	// Keep the relevance order, skipping arenas deleted since they were indexed
+	results := make([]gin.H, 0, len(hits))
+	for _, hit := range hits {
+		arena, ok := byID[hit.ArenaID]
+		if !ok {
+			continue
+		}
+		results = append(results, gin.H{
+			"id":             arena.ID,
+			"name":           arena.Name,
+			"location":       arena.Location,
+			"description":    arena.Description,
+			"rating_average": arena.RatingAverage,
+			"rating_count":   arena.RatingCount,
+			"fields":         arena.Fields,
+			"score":          math.Round(hit.Score*1000) / 1000,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/notify"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errNotTeamBooking  = errors.New("only team bookings can be split")
+	errNotPending      = errors.New("only bookings waiting for payment can be split")
+	errAlreadySplit    = errors.New("this booking is already split or partly paid")
+	errNotTeamMember   = errors.New("shares can only go to members of the team")
+	errNothingToCover  = errors.New("every share of this booking is already paid")
+	errNotBookingOwner = errors.New("only a captain of the booking's team can do this")
+	errNothingOwed     = errors.New("the booking or share was already paid or is no longer waiting for payment")
+)
+
+// SplitBooking handles a captain splitting a pending team booking into equal shares among
+// the team members, or the members listed in user_ids. Each member pays their own share.
+func SplitBooking(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	bookingID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserIDs []uint `json:"user_ids" binding:"max=50"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var booking models.Booking
+	var shares []models.PaymentShare
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockSplittableBooking(tx, bookingID, user, &booking); err != nil {
+			return err
+		}
+
+		var existing, payments int64
+		if err := tx.Model(&models.PaymentShare{}).Where("booking_id = ?", booking.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Payment{}).Where("booking_id = ?", booking.ID).Count(&payments).Error; err != nil {
+			return err
+		}
+		if existing > 0 || payments > 0 {
+			return errAlreadySplit
+		}
+
+		var members []uint
+		if err := tx.Model(&models.TeamMember{}).Where("team_id = ?", *booking.TeamID).Order("id").Pluck("user_id", &members).Error; err != nil {
+			return err
+		}
+		payers := members
+		if len(req.UserIDs) > 0 {
+			isMember := map[uint]bool{}
+			for _, id := range members {
+				isMember[id] = true
+			}
+			payers = nil
+			seen := map[uint]bool{}
+			for _, id := range req.UserIDs {
+				if !isMember[id] {
+					return errNotTeamMember
+				}
+				if !seen[id] {
+					seen[id] = true
+					payers = append(payers, id)
+				}
+			}
+		}
+
+		// Allocation hands leftover cents to the first shares so the total is exact
+		for i, amount := range booking.TotalAmount.Allocate(len(payers)) {
+			shares = append(shares, models.PaymentShare{
+				BookingID: booking.ID,
+				UserID:    payers[i],
+				Amount:    amount,
+				Status:    models.PaymentSharePending,
+			})
+		}
+		return tx.Create(&shares).Error
+	})
+	if respondSplitError(c, err) {
+		return
+	}
+
+	for _, share := range shares {
+		if share.UserID != user.ID {
+			notifyPaymentShare(share, "Your share of a team booking",
+				fmt.Sprintf("%s split booking #%d. Your share is %s.", user.Name, booking.ID, share.Amount))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking split successfully", "shares": shares})
+}
+
+// GetBookingSplit handles listing the payment shares of a booking with what is still outstanding
+func GetBookingSplit(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	bookingID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	if err := database.DB.Preload("Arena").First(&booking, bookingID).Error; err != nil || !canViewBooking(user, booking) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	var shares []models.PaymentShare
+	if err := database.DB.Preload("User").Where("booking_id = ?", booking.ID).Order("id").Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load shares"})
+		return
+	}
+
+	outstanding := money.Zero(booking.TotalAmount.Currency)
+	views := make([]gin.H, 0, len(shares))
+	for _, share := range shares {
+		if share.Status == models.PaymentSharePending {
+			outstanding, _ = outstanding.Add(share.Amount)
+		}
+		views = append(views, gin.H{
+			"id":      share.ID,
+			"user_id": share.UserID,
+			"name":    share.User.Name,
+			"amount":  share.Amount,
+			"status":  share.Status,
+			"paid_at": share.PaidAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"booking_id": booking.ID, "total_amount": booking.TotalAmount, "outstanding": outstanding, "shares": views})
+}
+
+// CoverBookingSplit handles a captain taking over the shares that haven't been paid yet.
+// They are replaced by a single share for the captain, who pays it to confirm the booking.
+func CoverBookingSplit(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	bookingID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var share models.PaymentShare
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		if err := lockSplittableBooking(tx, bookingID, user, &booking); err != nil {
+			return err
+		}
+
+		var unpaid []models.PaymentShare
+		if err := tx.Where("booking_id = ? AND status = ?", booking.ID, models.PaymentSharePending).Find(&unpaid).Error; err != nil {
+			return err
+		}
+		if len(unpaid) == 0 {
+			return errNothingToCover
+		}
+
+		remainder := money.Zero(booking.TotalAmount.Currency)
+		ids := make([]uint, 0, len(unpaid))
+		for _, s := range unpaid {
+			var err error
+			if remainder, err = remainder.Add(s.Amount); err != nil {
+				return err
+			}
+			ids = append(ids, s.ID)
+		}
+		if err := tx.Model(&models.PaymentShare{}).Where("id IN ?", ids).Update("status", models.PaymentShareCovered).Error; err != nil {
+			return err
+		}
+
+		share = models.PaymentShare{
+			BookingID: booking.ID,
+			UserID:    user.ID,
+			Amount:    remainder,
+			Status:    models.PaymentSharePending,
+		}
+		return tx.Create(&share).Error
+	})
+	if respondSplitError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Remaining shares covered, pay this share to confirm the booking", "share": share})
+}
+
+// ListMyPaymentShares handles listing the shares the current user still has to pay
+func ListMyPaymentShares(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var shares []models.PaymentShare
+	if err := database.DB.Where("user_id = ? AND status = ?", user.ID, models.PaymentSharePending).
+		Order("created_at").Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load shares"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+// lockSplittableBooking locks a pending team booking for a captain of its team.
+func lockSplittableBooking(tx *gorm.DB, bookingID uint, user models.User, booking *models.Booking) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(booking, bookingID).Error; err != nil {
+		return err
+	}
+	if booking.TeamID == nil {
+		return errNotTeamBooking
+	}
+	if teamRole(*booking.TeamID, user.ID) != models.TeamRoleCaptain {
+		return errNotBookingOwner
+	}
+	if booking.Status != models.BookingStatusPending {
+		return errNotPending
+	}
+	return nil
+}
+
+// amountOwed returns what a payment must amount to for the share, or without a share what is
+// left of the booking: its total less the shares already paid.
+func amountOwed(tx *gorm.DB, booking *models.Booking, shareID uint) (money.Money, error) {
+	if shareID != 0 {
+		var share models.PaymentShare
+		if err := tx.Where("id = ? AND booking_id = ?", shareID, booking.ID).First(&share).Error; err != nil {
+			return money.Money{}, err
+		}
+		return share.Amount, nil
+	}
+
+	var paid int64
+	if err := tx.Model(&models.PaymentShare{}).
+		Where("booking_id = ? AND status = ?", booking.ID, models.PaymentSharePaid).
+		Select("COALESCE(SUM(amount_minor), 0)").Scan(&paid).Error; err != nil {
+		return money.Money{}, err
+	}
+	return booking.TotalAmount.Sub(money.New(paid, booking.TotalAmount.Currency))
+}
+
+// settlePaymentShares records what a successful payment settles and reports whether the booking
+// is fully paid. A payment without a share pays the whole booking, covering the unpaid shares.
+// It fails with errNothingOwed when the share or booking doesn't wait for a payment anymore.
+func settlePaymentShares(tx *gorm.DB, booking *models.Booking, shareID uint, payment models.Payment) (bool, error) {
+	if shareID == 0 {
+		if booking.Status != models.BookingStatusPending {
+			return false, errNothingOwed
+		}
+		// Record the payment on the shares it covers, so a refund of it reopens them
+		err := tx.Model(&models.PaymentShare{}).
+			Where("booking_id = ? AND status = ?", booking.ID, models.PaymentSharePending).
+			Updates(map[string]interface{}{"status": models.PaymentShareCovered, "payment_id": payment.ID, "paid_at": time.Now()}).Error
+		return err == nil, err
+	}
+
+	var share models.PaymentShare
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND booking_id = ?", shareID, booking.ID).First(&share).Error; err != nil {
+		return false, err
+	}
+	if share.Status != models.PaymentSharePending {
+		return false, errNothingOwed
+	}
+	if err := tx.Model(&share).Updates(map[string]interface{}{
+		"status":     models.PaymentSharePaid,
+		"payment_id": payment.ID,
+		"paid_at":    time.Now(),
+	}).Error; err != nil {
+		return false, err
+	}
+
+	var unpaid int64
+	err := tx.Model(&models.PaymentShare{}).
+		Where("booking_id = ? AND status = ?", booking.ID, models.PaymentSharePending).
+		Count(&unpaid).Error
+	return unpaid == 0, err
+}
+
+// respondSplitError writes the response for a failed split operation, returning false when err is nil.
+func respondSplitError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+	case errors.Is(err, errNotBookingOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, errNotPending), errors.Is(err, errAlreadySplit), errors.Is(err, errNothingToCover):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errNotTeamBooking), errors.Is(err, errNotTeamMember):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking split"})
+	}
+	return true
+}
+
+// notifyPaymentShare sends a message about a share to the member who owes it.
+func notifyPaymentShare(share models.PaymentShare, subject, body string) {
+	var member models.User
+	if err := database.DB.First(&member, share.UserID).Error; err != nil {
+		return
+	}
+	notify.Send(context.Background(), notify.Message{
+		UserID:  member.ID,
+		Email:   member.Email,
+		Subject: subject,
+		Body:    body,
+	})
+}
@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/notify"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateStaffInvitation handles an arena owner inviting someone by email to the arena's staff
+// with the given permissions, all of them by default
+func CreateStaffInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadOwnedArena(c, arenaID, user)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Email          string   `json:"email" binding:"required,email"`
+		Permissions    []string `json:"permissions"`
+		ExpiresInHours int      `json:"expires_in_hours" binding:"omitempty,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	permissions, ok := staffPermissions(c, req.Permissions)
+	if !ok {
+		return
+	}
+
+	ttl := defaultInvitationTTL
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	token, err := randomToken(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate invitation token"})
+		return
+	}
+
+	invitation := models.StaffInvitation{
+		ArenaID:     arena.ID,
+		Email:       strings.ToLower(req.Email),
+		Permissions: permissions,
+		Token:       token,
+		InvitedByID: user.ID,
+		Status:      models.InvitationPending,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := database.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	var invitee models.User
+	database.DB.Where("email = ?", invitation.Email).First(&invitee)
+	notify.Send(context.Background(), notify.Message{
+		UserID:  invitee.ID,
+		Email:   invitation.Email,
+		Subject: "You're invited to the staff of " + arena.Name,
+		Body:    fmt.Sprintf("%s invited you to join the staff of %s. Accept the invitation with token %s before %s.", user.Name, arena.Name, token, invitation.ExpiresAt.Format(time.RFC1123)),
+	})
+
+	invitation.Arena = arena
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Invitation created successfully",
+		"invitation": invitation,
+		"accept_url": "/api/staff-invitations/" + token + "/accept",
+	})
+}
+
+// ListArenaStaff handles an arena owner listing the staff of the arena and the pending invitations
+func ListArenaStaff(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadOwnedArena(c, arenaID, user)
+	if !ok {
+		return
+	}
+
+	var staff []models.ArenaStaff
+	if err := database.DB.Preload("User").Where("arena_id = ?", arena.ID).Order("id").Find(&staff).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load staff"})
+		return
+	}
+	var invitations []models.StaffInvitation
+	if err := database.DB.Where("arena_id = ? AND status = ? AND expires_at > ?", arena.ID, models.InvitationPending, time.Now()).
+		Order("id").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load invitations"})
+		return
+	}
+
+	members := []gin.H{}
+	for _, member := range staff {
+		members = append(members, gin.H{
+			"user_id":     member.UserID,
+			"name":        member.User.Name,
+			"email":       member.User.Email,
+			"permissions": member.Permissions,
+			"since":       member.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"staff": members, "invitations": invitations})
+}
+
+// UpdateArenaStaff handles an arena owner changing the permissions of a staff member
+func UpdateArenaStaff(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	staffUserID, ok := paramID(c, "user_id")
+	if !ok {
+		return
+	}
+	if _, ok := loadOwnedArena(c, arenaID, user); !ok {
+		return
+	}
+
+	var req struct {
+		Permissions []string `json:"permissions" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	permissions, ok := staffPermissions(c, req.Permissions)
+	if !ok {
+		return
+	}
+
+	var staff models.ArenaStaff
+	if err := database.DB.Where("arena_id = ? AND user_id = ?", arenaID, staffUserID).First(&staff).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Staff member not found"})
+		return
+	}
+	if err := database.DB.Model(&staff).Update("permissions", permissions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update staff member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Staff member updated successfully", "staff": staff})
+}
+
+// RemoveArenaStaff handles an arena owner removing a staff member, or a staff member leaving
+func RemoveArenaStaff(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	staffUserID, ok := paramID(c, "user_id")
+	if !ok {
+		return
+	}
+	if staffUserID != user.ID {
+		if _, ok := loadOwnedArena(c, arenaID, user); !ok {
+			return
+		}
+	}
+
+	result := database.DB.Where("arena_id = ? AND user_id = ?", arenaID, staffUserID).Delete(&models.ArenaStaff{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove staff member"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Staff member not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Staff member removed"})
+}
+
+// ListMyStaffArenas handles listing the arenas the current user works at, with their permissions
+func ListMyStaffArenas(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var staff []models.ArenaStaff
+	if err := database.DB.Preload("Arena").Where("user_id = ?", user.ID).Order("id").Find(&staff).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load arenas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"staff": staff})
+}
+
+// ListMyStaffInvitations handles listing the pending staff invitations addressed to the current user
+func ListMyStaffInvitations(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var invitations []models.StaffInvitation
+	if err := database.DB.Preload("Arena").
+		Where("email = ? AND status = ? AND expires_at > ?", strings.ToLower(user.Email), models.InvitationPending, time.Now()).
+		Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+// AcceptStaffInvitation handles the current user joining the staff of an arena. Accepting
+// an invitation to an arena they already work at replaces their permissions.
+func AcceptStaffInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	invitation, ok := loadUsableStaffInvitation(c, user)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		staff := models.ArenaStaff{ArenaID: invitation.ArenaID, UserID: user.ID}
+		if err := tx.Where(staff).Assign(models.ArenaStaff{Permissions: invitation.Permissions}).FirstOrCreate(&staff).Error; err != nil {
+			return err
+		}
+		return tx.Model(&invitation).Update("status", models.InvitationAccepted).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join the staff"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation accepted", "arena_id": invitation.ArenaID})
+}
+
+// DeclineStaffInvitation handles the current user declining a staff invitation addressed to them
+func DeclineStaffInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	invitation, ok := loadUsableStaffInvitation(c, user)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Model(&invitation).Update("status", models.InvitationDeclined).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+// RevokeStaffInvitation handles an arena owner revoking a pending staff invitation
+func RevokeStaffInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var invitation models.StaffInvitation
+	if err := database.DB.Where("token = ?", c.Param("token")).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if _, ok := loadOwnedArena(c, invitation.ArenaID, user); !ok {
+		return
+	}
+
+	if err := database.DB.Model(&invitation).Update("status", models.InvitationRevoked).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// ListArenaBookings handles the arena owner or staff listing the bookings of the arena,
+// optionally between from and to (RFC 3339) and with a status
+func ListArenaBookings(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	arenaID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	arena, ok := loadStaffArena(c, arenaID, user, models.StaffViewBookings)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Preload("Field").Preload("Lines").Preload("AddOns.AddOn").Where("arena_id = ?", arena.ID)
+	for param, condition := range map[string]string{"from": "booking_time >= ?", "to": "booking_time < ?"} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 timestamp"})
+				return
+			}
+			query = query.Where(condition, t)
+		}
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var bookings []models.Booking
+	if err := query.Order("booking_time").Limit(500).Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bookings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bookings": bookings})
+}
+
+// staffPermissions validates requested permissions and joins them for storage, writing a 400
+// response for unknown ones. No permissions at all means every permission.
+func staffPermissions(c *gin.Context, requested []string) (string, bool) {
+	if len(requested) == 0 {
+		return strings.Join(models.StaffPermissions, ","), true
+	}
+	for _, permission := range requested {
+		if !slices.Contains(models.StaffPermissions, permission) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission " + permission})
+			return "", false
+		}
+	}
+	var permissions []string
+	for _, permission := range models.StaffPermissions {
+		if slices.Contains(requested, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	return strings.Join(permissions, ","), true
+}
+
+// loadUsableStaffInvitation loads the staff invitation from the URL token and checks the user can answer it.
+func loadUsableStaffInvitation(c *gin.Context, user models.User) (models.StaffInvitation, bool) {
+	var invitation models.StaffInvitation
+	if err := database.DB.Where("token = ?", c.Param("token")).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return invitation, false
+	}
+	if invitation.Status != models.InvitationPending || time.Now().After(invitation.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Invitation is no longer valid"})
+		return invitation, false
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This invitation is addressed to someone else"})
+		return invitation, false
+	}
+	return invitation, true
+}
@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/notify"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultInvitationTTL is how long invitations stay valid unless specified otherwise
+const defaultInvitationTTL = 7 * 24 * time.Hour
+
+// CreateTeam handles creating a team with the current user as its captain
+func CreateTeam(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name      string `json:"name" binding:"required"`
+		SportType string `json:"sport_type" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	team := models.Team{
+		Name:      req.Name,
+		SportType: req.SportType,
+		Members:   []models.TeamMember{{UserID: user.ID, Role: models.TeamRoleCaptain}},
+	}
+	if err := database.DB.Create(&team).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create team"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Team created successfully", "team": teamView(team)})
+}
+
+// GetTeam handles fetching a team with its members
+func GetTeam(c *gin.Context) {
+	teamID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var team models.Team
+	if err := database.DB.Preload("Members.User").First(&team, teamID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"team": teamView(team)})
+}
+
+// ListMyTeams handles listing the teams of the current user
+func ListMyTeams(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var teams []models.Team
+	if err := database.DB.Preload("Members.User").
+		Where("id IN (?)", database.DB.Model(&models.TeamMember{}).Select("team_id").Where("user_id = ?", user.ID)).
+		Find(&teams).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load teams"})
+		return
+	}
+
+	views := []gin.H{}
+	for _, team := range teams {
+		views = append(views, teamView(team))
+	}
+	c.JSON(http.StatusOK, gin.H{"teams": views})
+}
+
+// UpdateTeamMember handles changing the role of a team member (captains only)
+func UpdateTeamMember(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	teamID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := paramID(c, "user_id")
+	if !ok {
+		return
+	}
+	if !requireCaptain(c, teamID, user) {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required,oneof=captain member"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var member models.TeamMember
+		if err := tx.Where("team_id = ? AND user_id = ?", teamID, memberID).First(&member).Error; err != nil {
+			return err
+		}
+		if member.Role == models.TeamRoleCaptain && req.Role != models.TeamRoleCaptain {
+			if err := ensureAnotherCaptain(tx, teamID, memberID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&member).Update("role", req.Role).Error
+	})
+	if respondTeamMemberError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team member updated successfully"})
+}
+
+// RemoveTeamMember handles a captain removing a member, or a member leaving the team
+func RemoveTeamMember(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	teamID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := paramID(c, "user_id")
+	if !ok {
+		return
+	}
+	if memberID != user.ID && !requireCaptain(c, teamID, user) {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var member models.TeamMember
+		if err := tx.Where("team_id = ? AND user_id = ?", teamID, memberID).First(&member).Error; err != nil {
+			return err
+		}
+		if member.Role == models.TeamRoleCaptain {
+			if err := ensureAnotherCaptain(tx, teamID, memberID); err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&member).Error
+	})
+	if respondTeamMemberError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team member removed successfully"})
+}
+
+// errLastCaptain is returned when a change would leave a team without a captain
+var errLastCaptain = errors.New("a team must keep at least one captain")
+
+// ensureAnotherCaptain checks the team has a captain other than the given user.
+func ensureAnotherCaptain(tx *gorm.DB, teamID, userID uint) error {
+	var captains int64
+	if err := tx.Model(&models.TeamMember{}).
+		Where("team_id = ? AND role = ? AND user_id <> ?", teamID, models.TeamRoleCaptain, userID).
+		Count(&captains).Error; err != nil {
+		return err
+	}
+	if captains == 0 {
+		return errLastCaptain
+	}
+	return nil
+}
+
+// respondTeamMemberError writes the response for a failed membership change and reports whether it did.
+func respondTeamMemberError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team member not found"})
+	case errors.Is(err, errLastCaptain):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update team member"})
+	}
+	return true
+}
+
+// CreateTeamInvitation handles a captain inviting someone to the team, either by email
+// or, when no email is given, as a shareable invite link
+func CreateTeamInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	teamID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if !requireCaptain(c, teamID, user) {
+		return
+	}
+
+	var req struct {
+		Email          string `json:"email" binding:"omitempty,email"`
+		ExpiresInHours int    `json:"expires_in_hours" binding:"omitempty,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ttl := defaultInvitationTTL
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	token, err := randomToken(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate invitation token"})
+		return
+	}
+
+	invitation := models.TeamInvitation{
+		TeamID:      teamID,
+		Email:       strings.ToLower(req.Email),
+		Token:       token,
+		InvitedByID: user.ID,
+		Status:      models.InvitationPending,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := database.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	if invitation.Email != "" {
+		var team models.Team
+		database.DB.First(&team, teamID)
+		var invitee models.User
+		database.DB.Where("email = ?", invitation.Email).First(&invitee)
+		notify.Send(context.Background(), notify.Message{
+			UserID:  invitee.ID,
+			Email:   invitation.Email,
+			Subject: "You're invited to join " + team.Name,
+			Body:    fmt.Sprintf("%s invited you to join %s. Accept the invitation with token %s before %s.", user.Name, team.Name, token, invitation.ExpiresAt.Format(time.RFC1123)),
+		})
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Invitation created successfully",
+		"invitation": invitation,
+		"accept_url": "/api/invitations/" + token + "/accept",
+	})
+}
+
+// ListMyInvitations handles listing the pending invitations addressed to the current user
+func ListMyInvitations(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var invitations []models.TeamInvitation
+	if err := database.DB.Preload("Team").
+		Where("email = ? AND status = ? AND expires_at > ?", strings.ToLower(user.Email), models.InvitationPending, time.Now()).
+		Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+// AcceptInvitation handles the current user joining a team through an invitation
+func AcceptInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	invitation, ok := loadUsableInvitation(c, user)
+	if !ok {
+		return
+	}
+
+	if teamRole(invitation.TeamID, user.ID) != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already a member of this team"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		member := models.TeamMember{TeamID: invitation.TeamID, UserID: user.ID, Role: models.TeamRoleMember}
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+		// Invite links stay usable; personal invitations are consumed
+		if invitation.Email == "" {
+			return nil
+		}
+		return tx.Model(&invitation).Update("status", models.InvitationAccepted).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join team"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation accepted", "team_id": invitation.TeamID})
+}
+
+// DeclineInvitation handles the current user declining an invitation addressed to them
+func DeclineInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	invitation, ok := loadUsableInvitation(c, user)
+	if !ok {
+		return
+	}
+	if invitation.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invite links cannot be declined"})
+		return
+	}
+
+	if err := database.DB.Model(&invitation).Update("status", models.InvitationDeclined).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+// RevokeInvitation handles a captain revoking a pending invitation or invite link
+func RevokeInvitation(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var invitation models.TeamInvitation
+	if err := database.DB.Where("token = ?", c.Param("token")).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if !requireCaptain(c, invitation.TeamID, user) {
+		return
+	}
+
+	if err := database.DB.Model(&invitation).Update("status", models.InvitationRevoked).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// loadUsableInvitation loads the invitation from the URL token and checks the user can answer it.
+func loadUsableInvitation(c *gin.Context, user models.User) (models.TeamInvitation, bool) {
+	var invitation models.TeamInvitation
+	if err := database.DB.Where("token = ?", c.Param("token")).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return invitation, false
+	}
+	if invitation.Status != models.InvitationPending || time.Now().After(invitation.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Invitation is no longer valid"})
+		return invitation, false
+	}
+	if invitation.Email != "" && !strings.EqualFold(invitation.Email, user.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This invitation is addressed to someone else"})
+		return invitation, false
+	}
+	return invitation, true
+}
+
+// teamRole returns the role of the user in the team, or "" if they are not a member.
+func teamRole(teamID, userID uint) string {
+	var member models.TeamMember
+	if err := database.DB.Where("team_id = ? AND user_id = ?", teamID, userID).First(&member).Error; err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+// requireCaptain checks the user captains the team, writing a 403 response otherwise.
+func requireCaptain(c *gin.Context, teamID uint, user models.User) bool {
+	if teamRole(teamID, user.ID) != models.TeamRoleCaptain {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only team captains can do this"})
+		return false
+	}
+	return true
+}
+
+// teamIDsOf returns the IDs of the teams the user is a member of.
+func teamIDsOf(userID uint) []uint {
+	var ids []uint
+	database.DB.Model(&models.TeamMember{}).Where("user_id = ?", userID).Pluck("team_id", &ids)
+	return ids
+}
+
+// teamView renders a team with its members' public details only.
+func teamView(team models.Team) gin.H {
+	members := []gin.H{}
+	for _, member := range team.Members {
+		members = append(members, gin.H{
+			"user_id": member.UserID,
+			"name":    member.User.Name,
+			"role":    member.Role,
+		})
+	}
+	return gin.H{
+		"id":         team.ID,
+		"name":       team.Name,
+		"sport_type": team.SportType,
+		"rating":     team.Rating,
+		"members":    members,
+	}
+}
+
+// notifyTeamCaptains sends a notification to every captain of the team.
+func notifyTeamCaptains(teamID uint, subject, body string) {
+	var captains []models.TeamMember
+	database.DB.Preload("User").Where("team_id = ? AND role = ?", teamID, models.TeamRoleCaptain).Find(&captains)
+	for _, captain := range captains {
+		notify.Send(context.Background(), notify.Message{
+			UserID:  captain.UserID,
+			Email:   captain.User.Email,
+			Subject: subject,
+			Body:    body,
+		})
+	}
+}
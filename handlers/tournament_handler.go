@@ -0,0 +1,798 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sparring-backend/internal/database"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/tournament"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// schedulingHorizon bounds how far after its nominal round start a fixture may be booked
+const schedulingHorizon = 14 * 24 * time.Hour
+
+var (
+	errRegistrationClosed  = errors.New("registration for this tournament is closed")
+	errTournamentFull      = errors.New("this tournament is full")
+	errAlreadyRegistered   = errors.New("this team is already registered")
+	errWrongSport          = errors.New("the team does not play the sport of this tournament")
+	errNotEnoughTeams      = errors.New("at least two teams must be registered to start")
+	errTournamentNotActive = errors.New("this tournament is not in progress")
+	errNoTournamentSlot    = errors.New("no field is free for a fixture within two weeks of its round")
+)
+
+// CreateTournament handles an arena owner creating a tournament open for registration.
+// Double elimination ends with a single grand final: there is no bracket reset when the
+// losers bracket winner beats the unbeaten winners bracket winner.
+func CreateTournament(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ArenaID            uint      `json:"arena_id" binding:"required"`
+		Name               string    `json:"name" binding:"required,max=255"`
+		SportType          string    `json:"sport_type" binding:"required"`
+		Format             string    `json:"format" binding:"required"`
+		StartsAt           time.Time `json:"starts_at" binding:"required"`
+		MatchDuration      int       `json:"match_duration" binding:"required,min=1,max=8"`
+		RoundIntervalHours int       `json:"round_interval_hours" binding:"omitempty,min=1"`
+		MaxTeams           int       `json:"max_teams" binding:"required,min=2,max=64"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	arena, ok := loadOwnedArena(c, req.ArenaID, user)
+	if !ok {
+		return
+	}
+
+	switch req.Format {
+	case tournament.RoundRobin, tournament.SingleElimination, tournament.DoubleElimination:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": tournament.ErrUnknownFormat.Error()})
+		return
+	}
+	if !req.StartsAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "starts_at must be in the future"})
+		return
+	}
+	// One round a day unless specified otherwise, and rounds can't overlap
+	if req.RoundIntervalHours == 0 {
+		req.RoundIntervalHours = 24
+	}
+	if req.RoundIntervalHours < req.MatchDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "round_interval_hours must be at least the match duration"})
+		return
+	}
+
+	var fields int64
+	database.DB.Model(&models.Field{}).Where("arena_id = ? AND sport_type = ?", arena.ID, req.SportType).Count(&fields)
+	if fields == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The arena has no field for this sport"})
+		return
+	}
+
+	event := models.Tournament{
+		ArenaID:            arena.ID,
+		Name:               req.Name,
+		SportType:          req.SportType,
+		Format:             req.Format,
+		StartsAt:           req.StartsAt,
+		MatchDuration:      req.MatchDuration,
+		RoundIntervalHours: req.RoundIntervalHours,
+		MaxTeams:           req.MaxTeams,
+		Status:             models.TournamentRegistration,
+	}
+	if err := database.DB.Create(&event).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tournament"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tournament created successfully", "tournament": event})
+}
+
+// ListTournaments handles listing tournaments, optionally filtered by arena_id, sport_type and status
+func ListTournaments(c *gin.Context) {
+	query := database.DB.Order("starts_at")
+	if arenaID := c.Query("arena_id"); arenaID != "" {
+		query = query.Where("arena_id = ?", arenaID)
+	}
+	if sportType := c.Query("sport_type"); sportType != "" {
+		query = query.Where("sport_type = ?", sportType)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var events []models.Tournament
+	if err := query.Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tournaments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tournaments": events})
+}
+
+// GetTournament handles retrieving a tournament with its registered teams
+func GetTournament(c *gin.Context) {
+	tournamentID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var event models.Tournament
+	if err := database.DB.Preload("Teams", func(db *gorm.DB) *gorm.DB { return db.Order("seed, id") }).
+		Preload("Teams.Team").First(&event, tournamentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tournament": event})
+}
+
+// RegisterTournamentTeam handles a captain registering their team while registration is open
+func RegisterTournamentTeam(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	tournamentID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TeamID uint `json:"team_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !requireCaptain(c, req.TeamID, user) {
+		return
+	}
+
+	var entry models.TournamentTeam
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the tournament so concurrent registrations can't exceed the team limit
+		var event models.Tournament
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, tournamentID).Error; err != nil {
+			return err
+		}
+		if event.Status != models.TournamentRegistration {
+			return errRegistrationClosed
+		}
+
+		var team models.Team
+		if err := tx.First(&team, req.TeamID).Error; err != nil {
+			return err
+		}
+		if team.SportType != event.SportType {
+			return errWrongSport
+		}
+
+		var registered, existing int64
+		if err := tx.Model(&models.TournamentTeam{}).Where("tournament_id = ?", event.ID).Count(&registered).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TournamentTeam{}).Where("tournament_id = ? AND team_id = ?", event.ID, team.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errAlreadyRegistered
+		}
+		if int(registered) >= event.MaxTeams {
+			return errTournamentFull
+		}
+
+		entry = models.TournamentTeam{TournamentID: event.ID, TeamID: team.ID}
+		return tx.Create(&entry).Error
+	})
+	if respondTournamentError(c, err, "Failed to register team") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team registered successfully", "registration": entry})
+}
+
+// WithdrawTournamentTeam handles a captain withdrawing their team, or the arena owner
+// removing a team, while registration is open
+func WithdrawTournamentTeam(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	tournamentID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	teamID, ok := paramID(c, "team_id")
+	if !ok {
+		return
+	}
+
+	var event models.Tournament
+	if err := database.DB.Preload("Arena").First(&event, tournamentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		return
+	}
+	if event.Arena.OwnerID != user.ID && !requireCaptain(c, teamID, user) {
+		return
+	}
+	if event.Status != models.TournamentRegistration {
+		c.JSON(http.StatusConflict, gin.H{"error": errRegistrationClosed.Error()})
+		return
+	}
+
+	result := database.DB.Where("tournament_id = ? AND team_id = ?", event.ID, teamID).Delete(&models.TournamentTeam{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw team"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Team is not registered"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team withdrawn"})
+}
+
+// StartTournament handles the arena owner closing registration. Teams are seeded by rating,
+// the fixtures are generated and every fixture whose teams are known is booked on a field.
+// Fixtures no field is free for stay unscheduled and are listed in the response.
+func StartTournament(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	tournamentID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var event models.Tournament
+	if err := database.DB.First(&event, tournamentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		return
+	}
+	if _, ok := loadOwnedArena(c, event.ArenaID, user); !ok {
+		return
+	}
+
+	var scheduled, unscheduled []models.Fixture
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Arena").First(&event, tournamentID).Error; err != nil {
+			return err
+		}
+		if event.Status != models.TournamentRegistration {
+			return errRegistrationClosed
+		}
+
+		var entries []models.TournamentTeam
+		if err := tx.Preload("Team").Where("tournament_id = ?", event.ID).Order("id").Find(&entries).Error; err != nil {
+			return err
+		}
+		if len(entries) < 2 {
+			return errNotEnoughTeams
+		}
+
+		// Best rated teams get the top seeds, earlier registrations break ties
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Team.Rating > entries[j].Team.Rating })
+		teamIDs := make([]uint, len(entries))
+		for i := range entries {
+			entries[i].Seed = i + 1
+			teamIDs[i] = entries[i].TeamID
+			if err := tx.Model(&entries[i]).Update("seed", entries[i].Seed).Error; err != nil {
+				return err
+			}
+		}
+
+		matches, err := tournament.Generate(event.Format, teamIDs)
+		if err != nil {
+			return err
+		}
+		fixtures := make([]models.Fixture, len(matches))
+		for i, match := range matches {
+			fixtures[i] = models.Fixture{TournamentID: event.ID, Bracket: match.Bracket, Round: match.Round, Position: match.Position}
+			syncFixture(&fixtures[i], match)
+		}
+		if err := tx.Create(&fixtures).Error; err != nil {
+			return err
+		}
+
+		// Link the bracket now that the fixtures have IDs, saved along with the scheduling below
+		all := make([]int, len(fixtures))
+		for i, match := range matches {
+			all[i] = i
+			if match.WinnerTo != nil {
+				fixtures[i].NextFixtureID = optionalID(fixtures[match.WinnerTo.Match].ID)
+				fixtures[i].NextSlot = slotName(match.WinnerTo.Slot)
+			}
+			if match.LoserTo != nil {
+				fixtures[i].LoserNextFixtureID = optionalID(fixtures[match.LoserTo.Match].ID)
+				fixtures[i].LoserNextSlot = slotName(match.LoserTo.Slot)
+			}
+		}
+		if scheduled, unscheduled, err = scheduleFixtures(tx, event, fixtures, matches, all); err != nil {
+			return err
+		}
+
+		event.Status = models.TournamentInProgress
+		return tx.Model(&event).Update("status", event.Status).Error
+	})
+	if respondTournamentError(c, err, "Failed to start tournament") {
+		return
+	}
+
+	notifyFixturesScheduled(event, scheduled)
+	c.JSON(http.StatusOK, gin.H{"message": "Tournament started", "tournament": event, "unscheduled_fixtures": unscheduled})
+}
+
+// bracketOrder is the order fixtures of the same round are listed in
+var bracketOrder = map[string]int{
+	tournament.BracketLeague:  0,
+	tournament.BracketWinners: 1,
+	tournament.BracketLosers:  2,
+	tournament.BracketFinal:   3,
+}
+
+// ListFixtures handles listing the fixtures of a tournament by bracket and round, with their bookings
+func ListFixtures(c *gin.Context) {
+	tournamentID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var fixtures []models.Fixture
+	if err := database.DB.Preload("Booking").Where("tournament_id = ?", tournamentID).
+		Order("round, position").
+		Find(&fixtures).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load fixtures"})
+		return
+	}
+	sort.SliceStable(fixtures, func(i, j int) bool {
+		if fixtures[i].Round != fixtures[j].Round {
+			return fixtures[i].Round < fixtures[j].Round
+		}
+		return bracketOrder[fixtures[i].Bracket] < bracketOrder[fixtures[j].Bracket]
+	})
+
+	c.JSON(http.StatusOK, gin.H{"fixtures": fixtures})
+}
+
+// standingView is a standings row with the team name
+type standingView struct {
+	tournament.Standing
+	Name string `json:"name"`
+}
+
+// GetTournamentStandings handles computing the standings table of a tournament from its results
+func GetTournamentStandings(c *gin.Context) {
+	tournamentID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var event models.Tournament
+	if err := database.DB.Preload("Teams", func(db *gorm.DB) *gorm.DB { return db.Order("seed, id") }).
+		Preload("Teams.Team").First(&event, tournamentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		return
+	}
+
+	var fixtures []models.Fixture
+	if err := database.DB.Where("tournament_id = ? AND status = ?", event.ID, models.FixtureCompleted).Find(&fixtures).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load results"})
+		return
+	}
+
+	standings, names := tournamentStandings(event, fixtures)
+	rows := make([]standingView, len(standings))
+	for i, standing := range standings {
+		rows[i] = standingView{Standing: standing, Name: names[standing.TeamID]}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tournament_id": event.ID, "status": event.Status, "champion_team_id": event.ChampionTeamID, "standings": rows})
+}
+
+// RecordFixtureResult handles the arena owner entering the score of a fixture. Winners and
+// losers move through the bracket and fixtures whose teams become known are booked.
+func RecordFixtureResult(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	tournamentID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	fixtureID, ok := paramID(c, "fixture_id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		HomeScore *int `json:"home_score" binding:"required,min=0"`
+		AwayScore *int `json:"away_score" binding:"required,min=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var event models.Tournament
+	if err := database.DB.First(&event, tournamentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		return
+	}
+	if _, ok := loadOwnedArena(c, event.ArenaID, user); !ok {
+		return
+	}
+
+	var scheduled, unscheduled []models.Fixture
+	var fixture models.Fixture
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the tournament so results are applied to the bracket one at a time
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Arena").First(&event, tournamentID).Error; err != nil {
+			return err
+		}
+		if event.Status != models.TournamentInProgress {
+			return errTournamentNotActive
+		}
+
+		var fixtures []models.Fixture
+		if err := tx.Where("tournament_id = ?", event.ID).Order("id").Find(&fixtures).Error; err != nil {
+			return err
+		}
+		index := -1
+		for i := range fixtures {
+			if fixtures[i].ID == fixtureID {
+				index = i
+			}
+		}
+		if index < 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		matches := fixtureMatches(fixtures)
+		changed, err := tournament.Record(matches, index, *req.HomeScore, *req.AwayScore)
+		if err != nil {
+			return err
+		}
+		fixtures[index].HomeScore, fixtures[index].AwayScore = req.HomeScore, req.AwayScore
+
+		// Fixtures left without a field earlier get another try with the ones that became playable
+		changed = withUnscheduled(changed, fixtures, matches)
+		if scheduled, unscheduled, err = scheduleFixtures(tx, event, fixtures, matches, changed); err != nil {
+			return err
+		}
+		fixture = fixtures[index]
+
+		if !tournament.Finished(matches) {
+			return nil
+		}
+		event.Status = models.TournamentCompleted
+		if event.Format == tournament.RoundRobin {
+			var entries []models.TournamentTeam
+			if err := tx.Preload("Team").Where("tournament_id = ?", event.ID).Order("seed, id").Find(&entries).Error; err != nil {
+				return err
+			}
+			event.Teams = entries
+			standings, _ := tournamentStandings(event, fixtures)
+			event.ChampionTeamID = &standings[0].TeamID
+		} else if champion := tournament.Champion(matches); champion != 0 {
+			event.ChampionTeamID = &champion
+		}
+		return tx.Model(&event).Updates(map[string]interface{}{"status": event.Status, "champion_team_id": event.ChampionTeamID}).Error
+	})
+	if respondTournamentError(c, err, "Failed to record result") {
+		return
+	}
+
+	notifyFixturesScheduled(event, scheduled)
+	c.JSON(http.StatusOK, gin.H{"message": "Result recorded", "fixture": fixture, "tournament_status": event.Status, "unscheduled_fixtures": unscheduled})
+}
+
+// ScheduleTournamentFixtures handles the arena owner retrying to book a field for the fixtures
+// of a tournament in progress that were left unscheduled, e.g. after freeing up a field.
+func ScheduleTournamentFixtures(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	tournamentID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var event models.Tournament
+	if err := database.DB.First(&event, tournamentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		return
+	}
+	if _, ok := loadOwnedArena(c, event.ArenaID, user); !ok {
+		return
+	}
+
+	var scheduled, unscheduled []models.Fixture
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Arena").First(&event, tournamentID).Error; err != nil {
+			return err
+		}
+		if event.Status != models.TournamentInProgress {
+			return errTournamentNotActive
+		}
+
+		var fixtures []models.Fixture
+		if err := tx.Where("tournament_id = ?", event.ID).Order("id").Find(&fixtures).Error; err != nil {
+			return err
+		}
+		matches := fixtureMatches(fixtures)
+		var err error
+		scheduled, unscheduled, err = scheduleFixtures(tx, event, fixtures, matches, withUnscheduled(nil, fixtures, matches))
+		return err
+	})
+	if respondTournamentError(c, err, "Failed to schedule fixtures") {
+		return
+	}
+
+	notifyFixturesScheduled(event, scheduled)
+	c.JSON(http.StatusOK, gin.H{"message": "Fixtures scheduled", "scheduled_fixtures": scheduled, "unscheduled_fixtures": unscheduled})
+}
+
+// withUnscheduled adds the fixtures that are ready to be played but have no booking yet to the
+// indexes, in order.
+func withUnscheduled(indexes []int, fixtures []models.Fixture, matches []tournament.Match) []int {
+	seen := map[int]bool{}
+	for _, i := range indexes {
+		seen[i] = true
+	}
+	for i := range fixtures {
+		if !seen[i] && matches[i].Ready() && fixtures[i].BookingID == nil {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// scheduleFixtures saves the fixtures at the given indexes after their matches changed,
+// booking a field for those that became playable. It returns the fixtures it booked and
+// those left unscheduled because no field was free for them, which stay pending.
+func scheduleFixtures(tx *gorm.DB, event models.Tournament, fixtures []models.Fixture, matches []tournament.Match, indexes []int) ([]models.Fixture, []models.Fixture, error) {
+	var fields []models.Field
+	if err := tx.Where("arena_id = ? AND sport_type = ?", event.ArenaID, event.SportType).Order("id").Find(&fields).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var scheduled, unscheduled []models.Fixture
+	for _, i := range indexes {
+		fixture := &fixtures[i]
+		syncFixture(fixture, matches[i])
+		if matches[i].Ready() && fixture.BookingID == nil {
+			booking, err := bookFixture(tx, event, fields, *fixture)
+			switch {
+			case errors.Is(err, errNoTournamentSlot):
+				unscheduled = append(unscheduled, *fixture)
+			case err != nil:
+				return nil, nil, err
+			default:
+				fixture.BookingID = &booking.ID
+				fixture.Status = models.FixtureScheduled
+				scheduled = append(scheduled, *fixture)
+			}
+		}
+		if err := tx.Omit(clause.Associations).Save(fixture).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return scheduled, unscheduled, nil
+}
+
+// bookFixture books the earliest free field from the start of the fixture's round, at a time
+// both teams are free. The bookings of the fields and teams over the scheduling horizon are
+// loaded at once and searched in memory. Tournament bookings are confirmed, held by the
+// arena owner and marked with the tournament, so they are never paid, cancelled by the
+// customer or turned into no-shows.
+func bookFixture(tx *gorm.DB, event models.Tournament, fields []models.Field, fixture models.Fixture) (models.Booking, error) {
+	if len(fields) == 0 {
+		return models.Booking{}, errNoTournamentSlot
+	}
+	earliest := event.StartsAt.Add(time.Duration((fixture.Round-1)*event.RoundIntervalHours) * time.Hour)
+	if now := time.Now().Truncate(time.Hour).Add(time.Hour); earliest.Before(now) {
+		earliest = now
+	}
+	latest := earliest.Add(schedulingHorizon)
+	duration := time.Duration(event.MatchDuration) * time.Hour
+
+	taken, err := bookedSlots(tx, fields, earliest, latest.Add(duration))
+	if err != nil {
+		return models.Booking{}, err
+	}
+	var teamBookings []models.Booking
+	teams := []uint{*fixture.HomeTeamID, *fixture.AwayTeamID}
+	if err := tx.Select("id", "booking_time", "duration").
+		Where("(team_id IN ? OR opponent_team_id IN ?)", teams, teams).
+		Scopes(models.ActiveBookingsBetween(earliest, latest.Add(duration))).
+		Find(&teamBookings).Error; err != nil {
+		return models.Booking{}, err
+	}
+
+	zero := money.Zero(event.Arena.Currency)
+	for start := earliest; start.Before(latest); start = start.Add(time.Hour) {
+		end := start.Add(duration)
+		if overlapsAny(teamBookings, start, end) {
+			continue
+		}
+		for _, field := range fields {
+			if overlapsAny(taken[field.ID], start, end) {
+				continue
+			}
+			booking := models.Booking{
+				UserID:         event.Arena.OwnerID,
+				TeamID:         fixture.HomeTeamID,
+				OpponentTeamID: fixture.AwayTeamID,
+				TournamentID:   &event.ID,
+				ArenaID:        event.ArenaID,
+				FieldID:        field.ID,
+				BookingTime:    start,
+				Duration:       event.MatchDuration,
+				TotalAmount:    zero,
+				Discount:       zero,
+				Status:         models.BookingStatusConfirmed,
+			}
+			err := insertBooking(tx, &booking)
+			if errors.Is(err, errSlotTaken) {
+				continue
+			}
+			return booking, err
+		}
+	}
+	return models.Booking{}, errNoTournamentSlot
+}
+
+// fixtureMatches converts the fixtures of a tournament, ordered by ID, to bracket matches.
+func fixtureMatches(fixtures []models.Fixture) []tournament.Match {
+	index := map[uint]int{}
+	for i, fixture := range fixtures {
+		index[fixture.ID] = i
+	}
+
+	matches := make([]tournament.Match, len(fixtures))
+	for i, fixture := range fixtures {
+		match := tournament.Match{
+			Bracket:  fixture.Bracket,
+			Round:    fixture.Round,
+			Position: fixture.Position,
+			Home:     idValue(fixture.HomeTeamID),
+			Away:     idValue(fixture.AwayTeamID),
+			Winner:   idValue(fixture.WinnerTeamID),
+			Loser:    idValue(fixture.LoserTeamID),
+			Done:     fixture.Status == models.FixtureCompleted || fixture.Status == models.FixtureBye,
+		}
+		if fixture.NextFixtureID != nil {
+			match.WinnerTo = &tournament.Link{Match: index[*fixture.NextFixtureID], Slot: slotOf(fixture.NextSlot)}
+		}
+		if fixture.LoserNextFixtureID != nil {
+			match.LoserTo = &tournament.Link{Match: index[*fixture.LoserNextFixtureID], Slot: slotOf(fixture.LoserNextSlot)}
+		}
+		matches[i] = match
+	}
+	return matches
+}
+
+// syncFixture copies the teams and state of a bracket match to its fixture.
+func syncFixture(fixture *models.Fixture, match tournament.Match) {
+	fixture.HomeTeamID = optionalID(match.Home)
+	fixture.AwayTeamID = optionalID(match.Away)
+	fixture.WinnerTeamID = optionalID(match.Winner)
+	fixture.LoserTeamID = optionalID(match.Loser)
+	switch {
+	case match.Done && fixture.HomeScore != nil:
+		fixture.Status = models.FixtureCompleted
+	case match.Done:
+		fixture.Status = models.FixtureBye
+	case fixture.BookingID != nil:
+		fixture.Status = models.FixtureScheduled
+	default:
+		fixture.Status = models.FixturePending
+	}
+}
+
+// tournamentStandings ranks the registered teams, loaded in seed order, on the completed fixtures.
+// It also returns the team names by ID.
+func tournamentStandings(event models.Tournament, fixtures []models.Fixture) ([]tournament.Standing, map[uint]string) {
+	teamIDs := make([]uint, 0, len(event.Teams))
+	names := map[uint]string{}
+	for _, entry := range event.Teams {
+		teamIDs = append(teamIDs, entry.TeamID)
+		names[entry.TeamID] = entry.Team.Name
+	}
+
+	var results []tournament.Result
+	for _, fixture := range fixtures {
+		if fixture.HomeScore == nil || fixture.AwayScore == nil || fixture.HomeTeamID == nil || fixture.AwayTeamID == nil {
+			continue
+		}
+		results = append(results, tournament.Result{
+			Home:      *fixture.HomeTeamID,
+			Away:      *fixture.AwayTeamID,
+			HomeScore: *fixture.HomeScore,
+			AwayScore: *fixture.AwayScore,
+		})
+	}
+	return tournament.Standings(teamIDs, results), names
+}
+
+// notifyFixturesScheduled tells the captains of both teams when and where their fixtures are played.
+func notifyFixturesScheduled(event models.Tournament, fixtures []models.Fixture) {
+	for _, fixture := range fixtures {
+		var booking models.Booking
+		if err := database.DB.Preload("Field").First(&booking, fixture.BookingID).Error; err != nil {
+			continue
+		}
+		body := fmt.Sprintf("Your %s fixture (round %d) is on %s at %s, %s.", event.Name, fixture.Round,
+			booking.BookingTime.In(event.Arena.Zone()).Format(time.RFC1123), event.Arena.Name, booking.Field.FieldName)
+		notifyTeamCaptains(*fixture.HomeTeamID, "Tournament fixture scheduled", body)
+		notifyTeamCaptains(*fixture.AwayTeamID, "Tournament fixture scheduled", body)
+	}
+}
+
+// respondTournamentError writes the response for a failed tournament operation, returning false when err is nil.
+func respondTournamentError(c *gin.Context, err error, fallback string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, errRegistrationClosed), errors.Is(err, errTournamentFull), errors.Is(err, errAlreadyRegistered),
+		errors.Is(err, errTournamentNotActive), errors.Is(err, tournament.ErrNotPlayable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errWrongSport), errors.Is(err, errNotEnoughTeams),
+		errors.Is(err, tournament.ErrDrawNotAllowed), errors.Is(err, tournament.ErrTooFewTeams):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+	return true
+}
+
+func slotName(slot tournament.Slot) string {
+	if slot == tournament.Away {
+		return "away"
+	}
+	return "home"
+}
+
+func slotOf(name string) tournament.Slot {
+	if name == "away" {
+		return tournament.Away
+	}
+	return tournament.Home
+}
+
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func idValue(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
@@ -4,18 +4,21 @@ import (
 	"net/http"
 	"sparring-backend/auth"
 	"sparring-backend/internal/database"
+	"sparring-backend/internal/loyalty"
 	"sparring-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // RegisterUser handles user registration
 func RegisterUser(c *gin.Context) {
 	var req struct {
-		Name     string `json:"name" binding:"required"`
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required,min=6"`
+		Name         string `json:"name" binding:"required"`
+		Email        string `json:"email" binding:"required,email"`
+		Password     string `json:"password" binding:"required,min=6"`
+		ReferralCode string `json:"referral_code"` // Code of the friend who referred the user, if any
 	}
 
 	// Validate the request body
@@ -38,15 +41,44 @@ func RegisterUser(c *gin.Context) {
 		return
 	}
 
-	// Create a new user
-	newUser := models.User{
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: string(hashedPassword),
-		Role:     "user", // Default role
+	// Look up the referrer before creating anything
+	var referrer models.User
+	if req.ReferralCode != "" {
+		if err := database.DB.Where("referral_code = ?", loyalty.NormalizeCode(req.ReferralCode)).First(&referrer).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown referral code"})
+			return
+		}
+	}
+
+	referralCode, err := loyalty.NewReferralCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate referral code"})
+		return
 	}
 
-	if err := database.DB.Create(&newUser).Error; err != nil {
+	// Create a new user
+	newUser := models.User{
+		Name:         req.Name,
+		Email:        req.Email,
+		Password:     string(hashedPassword),
+		Role:         "user", // Default role
+		ReferralCode: &referralCode,
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		if referrer.ID == 0 {
+			return nil
+		}
+		return tx.Create(&models.Referral{
+			ReferrerID: referrer.ID,
+			ReferredID: newUser.ID,
+			Status:     models.ReferralPending,
+		}).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Could not register user",
 			"details": err.Error(), // Include the detailed error message
@@ -4,9 +4,10 @@ import (
 	"log"
 	"os"
 	"sparring-backend/internal/models"
+
+	"github.com/joho/godotenv"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"github.com/joho/godotenv"
 )
 
 var DB *gorm.DB
@@ -32,19 +33,111 @@ func InitDB() error {
 	dsn := DB_USER + ":" + DB_PASSWORD + "@tcp(" + DB_HOST + ":" + DB_PORT + ")/" + DB_NAME + "?charset=" + DB_CHARSET + "&parseTime=True&loc=" + DB_LOC
 
 	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true}) // Unique violations become gorm.ErrDuplicatedKey
 	if err != nil {
 		log.Printf("Failed to connect to the database: %v", err)
 		return err
 	}
 
+	// Prepare reviews for their unique index before it is created
+	if err := migrateReviewAuthors(); err != nil {
+		log.Printf("Failed to migrate reviews: %v", err)
+		return err
+	}
+
 	// Automigrate the models
-	err = DB.AutoMigrate(&models.User{}, &models.Arena{}, &models.Booking{}) // Add more models here
+	err = DB.AutoMigrate(
+		// Users, arenas and bookings
+		&models.User{},
+		&models.Arena{},
+		&models.Field{},
+		&models.Booking{},
+		&models.CheckInNonce{},
+		&models.AccountPenalty{},
+		&models.JobLease{},
+
+		// Pricing, promo codes and invoices
+		&models.PricingRule{},
+		&models.BookingLine{},
+		&models.PromoCode{},
+		&models.PromoRedemption{},
+		&models.Invoice{},
+		&models.InvoiceLine{},
+
+		// Payments
+		&models.Payment{},
+		&models.PaymentEvent{},
+		&models.PaymentShare{},
+
+		// Teams, matches and tournaments
+		&models.Team{},
+		&models.TeamMember{},
+		&models.TeamInvitation{},
+		&models.MatchRequest{},
+		&models.MatchRequestWindow{},
+		&models.TeamAvailability{},
+		&models.MatchResult{},
+		&models.RatingChange{},
+		&models.Tournament{},
+		&models.TournamentTeam{},
+		&models.Fixture{},
+
+		// Reviews, favorites and watches
+		&models.Review{},
+		&models.ReviewReport{},
+		&models.Favorite{},
+		&models.SavedSearch{},
+		&models.FieldWatch{},
+
+		// Arena media, add-ons and staff
+		&models.ArenaMedia{},
+		&models.AddOn{},
+		&models.BookingAddOn{},
+		&models.ArenaStaff{},
+		&models.StaffInvitation{},
+
+		// Owner ledger and payouts
+		&models.LedgerTransaction{},
+		&models.LedgerPosting{},
+		&models.Payout{},
+
+		// Memberships, passes and loyalty points
+		&models.PassPlan{},
+		&models.Pass{},
+		&models.PassRedemption{},
+		&models.PointsEntry{},
+		&models.PointsLotUse{},
+		&models.Referral{},
+	)
 	if err != nil {
 		log.Printf("Failed to migrate database: %v", err)
 		return err
 	}
 
+	// Convert amounts stored before the money type was introduced
+	if err := migrateLegacyMoney(); err != nil {
+		log.Printf("Failed to migrate legacy amounts: %v", err)
+		return err
+	}
+
+	// Index arenas placed on the map before proximity search existed
+	if err := migrateArenaGeohashes(); err != nil {
+		log.Printf("Failed to compute arena geohashes: %v", err)
+		return err
+	}
+
+	// Record payments received before the owner ledger existed
+	if err := migrateLedger(); err != nil {
+		log.Printf("Failed to post earlier payments to the ledger: %v", err)
+		return err
+	}
+
+	// Give users registered before the referral program their referral code
+	if err := migrateReferralCodes(); err != nil {
+		log.Printf("Failed to create referral codes: %v", err)
+		return err
+	}
+
 	log.Println("Database connected and migrated successfully.")
 	return nil
 }
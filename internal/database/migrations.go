@@ -0,0 +1,196 @@
+package database
+
+import (
+	"log"
+	"sparring-backend/internal/geo"
+	"sparring-backend/internal/ledger"
+	"sparring-backend/internal/loyalty"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// legacyMoneyColumns lists the float columns replaced by money.Money minor unit columns
+var legacyMoneyColumns = []struct {
+	model  interface{}
+	table  string
+	column string
+	prefix string
+}{
+	{&models.Field{}, "fields", "price_per_hr", "price_per_hr_"},
+	{&models.Booking{}, "bookings", "total_amount", "total_amount_"},
+	{&models.Payment{}, "payments", "amount", "amount_"},
+}
+
+// legacyCurrencyColumns lists the money columns added after the amounts they go with, whose
+// currency is copied from those amounts on rows saved before they existed
+var legacyCurrencyColumns = []struct {
+	table  string
+	prefix string
+	from   string
+}{
+	{"payments", "refunded_", "amount_"},
+	{"bookings", "discount_", "total_amount_"},
+}
+
+// migrateLegacyMoney converts float amounts left over from before the money type
+// into minor units of the default currency, then drops the float columns. Amounts added
+// later get the currency of the amount they go with, and payments refunded before refunds
+// were tracked count as refunded in full.
+func migrateLegacyMoney() error {
+	for _, legacy := range legacyMoneyColumns {
+		if !DB.Migrator().HasColumn(legacy.model, legacy.column) {
+			continue
+		}
+
+		err := DB.Exec(
+			"UPDATE "+legacy.table+" SET "+legacy.prefix+"minor = ROUND("+legacy.column+" * 100), "+legacy.prefix+"currency = ? WHERE "+legacy.column+" IS NOT NULL",
+			money.DefaultCurrency,
+		).Error
+		if err != nil {
+			return err
+		}
+		if err := DB.Migrator().DropColumn(legacy.model, legacy.column); err != nil {
+			return err
+		}
+		log.Printf("Migrated %s.%s to minor units", legacy.table, legacy.column)
+	}
+
+	for _, legacy := range legacyCurrencyColumns {
+		result := DB.Exec(
+			"UPDATE " + legacy.table + " SET " + legacy.prefix + "currency = " + legacy.from + "currency, " + legacy.prefix + "minor = COALESCE(" + legacy.prefix + "minor, 0)" +
+				" WHERE (" + legacy.prefix + "currency IS NULL OR " + legacy.prefix + "currency = '') AND " + legacy.from + "currency IS NOT NULL",
+		)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Copied the %scurrency of %d %s", legacy.from, result.RowsAffected, legacy.table)
+		}
+	}
+
+	result := DB.Unscoped().Model(&models.Payment{}).
+		Where("status = ? AND refunded_minor = 0 AND amount_minor > 0", models.PaymentStatusRefunded).
+		UpdateColumn("refunded_minor", gorm.Expr("amount_minor"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Marked %d earlier refunded payments as refunded in full", result.RowsAffected)
+	}
+	return nil
+}
+
+// migrateArenaGeohashes computes the geohash of arenas placed on the map before geohashes were stored.
+func migrateArenaGeohashes() error {
+	var arenas []models.Arena
+	if err := DB.Where("latitude IS NOT NULL AND longitude IS NOT NULL AND (geohash IS NULL OR geohash = '')").Find(&arenas).Error; err != nil {
+		return err
+	}
+	for _, arena := range arenas {
+		hash := geo.Geohash(geo.Point{Lat: *arena.Latitude, Lng: *arena.Longitude}, geo.GeohashPrecision)
+		if err := DB.Model(&arena).Update("geohash", hash).Error; err != nil {
+			return err
+		}
+	}
+	if len(arenas) > 0 {
+		log.Printf("Computed geohashes of %d arenas", len(arenas))
+	}
+	return nil
+}
+
+// migrateLedger posts the payments, and their refunds, received before the ledger existed,
+// so owner balances reconcile with the payment records.
+func migrateLedger() error {
+	var payments []models.Payment
+	if err := DB.Joins("LEFT JOIN ledger_transactions ON ledger_transactions.reference = CONCAT('payment:', payments.id)").
+		Where("ledger_transactions.id IS NULL").
+		Find(&payments).Error; err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			var booking models.Booking
+			if err := tx.Unscoped().First(&booking, payment.BookingID).Error; err != nil {
+				return err
+			}
+			var arena models.Arena
+			if err := tx.Unscoped().First(&arena, booking.ArenaID).Error; err != nil {
+				return err
+			}
+			return ledger.PostLegacyPayment(tx, payment, arena)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(payments) > 0 {
+		log.Printf("Posted %d earlier payments to the ledger", len(payments))
+	}
+	return nil
+}
+
+// migrateReferralCodes gives a referral code to the users registered before referrals existed.
+func migrateReferralCodes() error {
+	var users []models.User
+	if err := DB.Where("referral_code IS NULL").Find(&users).Error; err != nil {
+		return err
+	}
+	for _, user := range users {
+		code, err := loyalty.NewReferralCode()
+		if err != nil {
+			return err
+		}
+		if err := DB.Model(&user).Update("referral_code", code).Error; err != nil {
+			return err
+		}
+	}
+	if len(users) > 0 {
+		log.Printf("Created referral codes for %d users", len(users))
+	}
+	return nil
+}
+
+// migrateReviewAuthors fills the field key of reviews saved before reviews were unique per
+// user, arena and field, and keeps only the latest review of each, so the unique index can
+// be created. It runs before the models are migrated.
+func migrateReviewAuthors() error {
+	migrator := DB.Migrator()
+	if !migrator.HasTable(&models.Review{}) || migrator.HasColumn(&models.Review{}, "FieldKey") {
+		return nil
+	}
+
+	if err := migrator.AddColumn(&models.Review{}, "FieldKey"); err != nil {
+		return err
+	}
+	if err := DB.Exec("UPDATE reviews SET field_key = COALESCE(field_id, 0)").Error; err != nil {
+		return err
+	}
+	result := DB.Exec("DELETE older FROM reviews older JOIN reviews newer ON newer.arena_id = older.arena_id " +
+		"AND newer.user_id = older.user_id AND newer.field_key = older.field_key AND newer.id > older.id")
+	if result.Error != nil {
+		return result.Error
+	}
+	if migrator.HasIndex(&models.Review{}, "idx_review_arena_user") {
+		if err := migrator.DropIndex(&models.Review{}, "idx_review_arena_user"); err != nil {
+			return err
+		}
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	// Drop the reports of the removed reviews and recount the ratings
+	if err := DB.Exec("DELETE FROM review_reports WHERE review_id NOT IN (SELECT id FROM reviews)").Error; err != nil {
+		return err
+	}
+	visible := "FROM reviews WHERE reviews.arena_id = arenas.id AND reviews.hidden = false AND reviews.deleted_at IS NULL"
+	if err := DB.Exec("UPDATE arenas SET rating_count = (SELECT COUNT(*) " + visible + "), " +
+		"rating_average = COALESCE((SELECT ROUND(AVG(rating), 2) " + visible + "), 0)").Error; err != nil {
+		return err
+	}
+	log.Printf("Removed %d duplicate reviews", result.RowsAffected)
+	return nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/testdb"
+	"testing"
+)
+
+func TestMigrateLegacyMoneyBackfillsCurrencies(t *testing.T) {
+	DB = testdb.New(t, &models.Field{}, &models.Booking{}, &models.Payment{})
+	defer func() { DB = nil }()
+
+	// Rows saved before refunds and discounts were tracked
+	for _, query := range []string{
+		"INSERT INTO payments (booking_id, provider, provider_ref, amount_minor, amount_currency, status) VALUES (1, 'test', 'pi_1', 2000, 'EUR', 'succeeded')",
+		"INSERT INTO payments (booking_id, provider, provider_ref, amount_minor, amount_currency, status) VALUES (2, 'test', 'pi_2', 3000, 'EUR', 'refunded')",
+		"INSERT INTO bookings (user_id, arena_id, field_id, booking_time, duration, total_amount_minor, total_amount_currency, status) VALUES (1, 1, 1, '2024-01-08 18:00:00', 1, 2000, 'EUR', 'confirmed')",
+	} {
+		if err := DB.Exec(query).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ { // Runs on every start
+		if err := migrateLegacyMoney(); err != nil {
+			t.Fatalf("migrateLegacyMoney() #%d = %v", i+1, err)
+		}
+	}
+
+	var payments []models.Payment
+	DB.Order("id").Find(&payments)
+	want := []money.Money{money.New(0, "EUR"), money.New(3000, "EUR")}
+	for i, payment := range payments {
+		if payment.Refunded != want[i] {
+			t.Errorf("payment %s refunded = %v, want %v", payment.ProviderRef, payment.Refunded, want[i])
+		}
+		if _, err := payment.Amount.Sub(payment.Refunded); err != nil {
+			t.Errorf("payment %s outstanding: %v", payment.ProviderRef, err)
+		}
+	}
+
+	var booking models.Booking
+	DB.First(&booking)
+	if booking.Discount != money.Zero("EUR") {
+		t.Errorf("booking discount = %+v, want 0 EUR", booking.Discount)
+	}
+}
@@ -0,0 +1,133 @@
+package geo
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// destination returns the point distanceKm away from p along the bearing, in degrees from north.
+func destination(p Point, bearing, distanceKm float64) Point {
+	lat1, lng1, theta, delta := radians(p.Lat), radians(p.Lng), radians(bearing), distanceKm/earthRadiusKm
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(theta))
+	lng2 := lng1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(lat1), math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+	lng := math.Mod(lng2*180/math.Pi+540, 360) - 180
+	return Point{Lat: lat2 * 180 / math.Pi, Lng: lng}
+}
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Point
+		want float64
+	}{
+		{"same point", Point{-6.2, 106.8}, Point{-6.2, 106.8}, 0},
+		{"one degree of latitude", Point{0, 0}, Point{1, 0}, 111.195},
+		{"across the antimeridian", Point{0, 179.9}, Point{0, -179.9}, 22.239},
+		{"half way around the equator", Point{0, 0}, Point{0, 180}, 20015.087},
+		{"pole to pole", Point{90, 0}, Point{-90, 0}, 20015.087},
+		{"longitude doesn't matter at the pole", Point{90, 0}, Point{90, 120}, 0},
+		{"Jakarta to Bandung", Point{-6.2088, 106.8456}, Point{-6.9175, 107.6191}, 116.3},
+	}
+	for _, tt := range tests {
+		if got := DistanceKm(tt.a, tt.b); math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("%s: DistanceKm(%v, %v) = %.3f, want %.3f", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		p    Point
+		want bool
+	}{
+		{Point{0, 0}, true},
+		{Point{90, 180}, true},
+		{Point{-90, -180}, true},
+		{Point{90.1, 0}, false},
+		{Point{0, -180.1}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Valid(); got != tt.want {
+			t.Errorf("%v.Valid() = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestGeohash(t *testing.T) {
+	tests := []struct {
+		p         Point
+		precision int
+		want      string
+	}{
+		{Point{57.64911, 10.40744}, 11, "u4pruydqqvj"},
+		{Point{42.6, -5.6}, 5, "ezs42"},
+		{Point{0, 0}, 5, "s0000"},
+		{Point{-90, -180}, 5, "00000"},
+		{Point{90, 180}, 5, "zzzzz"},
+		{Point{0, 179.99}, 3, "xbp"},
+		{Point{0, -179.99}, 3, "800"},
+		{Point{-6.2088, 106.8456}, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := Geohash(tt.p, tt.precision); got != tt.want {
+			t.Errorf("Geohash(%v, %d) = %q, want %q", tt.p, tt.precision, got, tt.want)
+		}
+	}
+
+	// Shorter geohashes are prefixes of longer ones
+	p := Point{-6.2088, 106.8456}
+	full := Geohash(p, GeohashPrecision)
+	for precision := 1; precision < GeohashPrecision; precision++ {
+		if got := Geohash(p, precision); !strings.HasPrefix(full, got) {
+			t.Errorf("Geohash(%v, %d) = %q is not a prefix of %q", p, precision, got, full)
+		}
+	}
+}
+
+func TestCoveringGeohashes(t *testing.T) {
+	tests := []struct {
+		name     string
+		center   Point
+		radiusKm float64
+	}{
+		{"city", Point{-6.2088, 106.8456}, 5},
+		{"small radius", Point{51.5, -0.12}, 0.5},
+		{"west of the antimeridian", Point{-17.8, 179.99}, 10},
+		{"east of the antimeridian", Point{-17.8, -179.99}, 10},
+		{"on the equator and the antimeridian", Point{0, 180}, 25},
+		{"near the north pole", Point{89.9, 30}, 5},
+		{"near the south pole", Point{-89.5, -150}, 20},
+		{"on the pole", Point{90, 0}, 10},
+		{"high latitude", Point{78.2, 15.6}, 15},
+	}
+	for _, tt := range tests {
+		hashes := CoveringGeohashes(tt.center, tt.radiusKm)
+		if len(hashes) == 0 || len(hashes) > 9 {
+			t.Errorf("%s: %d cells, want 1 to 9", tt.name, len(hashes))
+			continue
+		}
+		covered := func(p Point) bool {
+			hash := Geohash(p, GeohashPrecision)
+			for _, prefix := range hashes {
+				if strings.HasPrefix(hash, prefix) {
+					return true
+				}
+			}
+			return false
+		}
+		// Every point inside the circle must fall in one of the cells
+		for _, fraction := range []float64{0, 0.5, 0.99} {
+			for bearing := 0.0; bearing < 360; bearing += 15 {
+				p := destination(tt.center, bearing, fraction*tt.radiusKm)
+				if !covered(p) {
+					t.Errorf("%s: %v, %.1f km from the center, is outside cells %v", tt.name, p, fraction*tt.radiusKm, hashes)
+				}
+			}
+		}
+	}
+
+	if got := CoveringGeohashes(Point{0, 0}, 10000); len(got) != 1 || got[0] != "" {
+		t.Errorf("radius larger than a cell: %q, want a single empty prefix", got)
+	}
+}
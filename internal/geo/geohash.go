@@ -0,0 +1,90 @@
+package geo
+
+import (
+	"math"
+	"strings"
+)
+
+// GeohashPrecision is the length of the geohashes stored for arenas, about 1 m across
+const GeohashPrecision = 9
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash encodes the point as a geohash of the given length. Points close to each
+// other share a prefix, which lets an ordinary index find nearby points.
+func Geohash(p Point, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+
+	var hash strings.Builder
+	bits, value, even := 0, 0, true
+	for hash.Len() < precision {
+		// Bits alternate between longitude and latitude, starting with longitude
+		r, coordinate := &latRange, p.Lat
+		if even {
+			r, coordinate = &lngRange, p.Lng
+		}
+		mid := (r[0] + r[1]) / 2
+		value <<= 1
+		if coordinate >= mid {
+			value |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+
+		if bits++; bits == 5 {
+			hash.WriteByte(geohashAlphabet[value])
+			bits, value = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// cellSize returns the height and width in degrees of a geohash cell of the given length.
+func cellSize(precision int) (float64, float64) {
+	lngBits := (5*precision + 1) / 2
+	latBits := 5 * precision / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lngBits))
+}
+
+// CoveringGeohashes returns geohash prefixes whose cells together cover the circle of the
+// given radius around the center: the cell of the center and its eight neighbours, at the
+// longest length whose cells are still at least radiusKm across.
+func CoveringGeohashes(center Point, radiusKm float64) []string {
+	kmPerDegree := earthRadiusKm * math.Pi / 180
+	// Longitude degrees shrink towards the poles; use the latitude edge closest to a pole
+	cosLat := math.Cos(radians(math.Min(90, math.Abs(center.Lat)+radiusKm/kmPerDegree)))
+
+	precision := 0
+	for next := 1; next <= GeohashPrecision; next++ {
+		height, width := cellSize(next)
+		if height*kmPerDegree < radiusKm || width*kmPerDegree*cosLat < radiusKm {
+			break
+		}
+		precision = next
+	}
+	if precision == 0 {
+		return []string{""} // The circle is larger than a top level cell, search everywhere
+	}
+
+	height, width := cellSize(precision)
+	seen := map[string]bool{}
+	var hashes []string
+	for dLat := -1; dLat <= 1; dLat++ {
+		lat := center.Lat + float64(dLat)*height
+		if lat < -90 || lat > 90 {
+			continue
+		}
+		for dLng := -1; dLng <= 1; dLng++ {
+			lng := math.Mod(center.Lng+float64(dLng)*width+540, 360) - 180
+			hash := Geohash(Point{Lat: lat, Lng: lng}, precision)
+			if !seen[hash] {
+				seen[hash] = true
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+	return hashes
+}
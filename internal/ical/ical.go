@@ -0,0 +1,109 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProdID identifies this application in generated calendars
+const ProdID = "-//Sparring Backend//Bookings//EN"
+
+// Event statuses (RFC 5545 section 3.8.1.11)
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Event is a VEVENT entry
+type Event struct {
+	UID         string
+	Stamp       time.Time // Last modification, used as DTSTAMP
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Location    string
+	Description string
+	Status      string
+}
+
+// Calendar is a VCALENDAR object
+type Calendar struct {
+	Name   string // Shown by calendar apps for subscribed feeds
+	Events []Event
+}
+
+// Encode renders the calendar as RFC 5545 text.
+func (cal *Calendar) Encode() []byte {
+	var b bytes.Buffer
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+ProdID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	if cal.Name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escapeText(cal.Name))
+	}
+
+	for _, event := range cal.Events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+event.UID)
+		writeLine(&b, "DTSTAMP:"+formatTime(event.Stamp))
+		writeLine(&b, "DTSTART:"+formatTime(event.Start))
+		writeLine(&b, "DTEND:"+formatTime(event.End))
+		writeLine(&b, "SUMMARY:"+escapeText(event.Summary))
+		if event.Location != "" {
+			writeLine(&b, "LOCATION:"+escapeText(event.Location))
+		}
+		if event.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.Status != "" {
+			writeLine(&b, "STATUS:"+event.Status)
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+	return b.Bytes()
+}
+
+// formatTime formats a time as a UTC date-time, e.g. 20240131T180000Z.
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeText escapes a TEXT property value (RFC 5545 section 3.3.11).
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// writeLine writes a content line, folding it at 75 octets without splitting
+// UTF-8 sequences, and terminates it with CRLF.
+func writeLine(b *bytes.Buffer, line string) {
+	const limit = 75
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ") // Continuation lines start with a space, which counts toward the limit
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+}
+
+// UID builds a globally unique identifier for an object of this application.
+func UID(kind string, id uint) string {
+	return fmt.Sprintf("%s-%d@sparring-backend", kind, id)
+}
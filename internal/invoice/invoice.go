@@ -0,0 +1,53 @@
+package invoice
+
+import (
+	"fmt"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"time"
+)
+
+// Number formats the invoice number of an arena's nth invoice.
+func Number(arenaID uint, sequence int) string {
+	return fmt.Sprintf("INV-%d-%06d", arenaID, sequence)
+}
+
+// Build creates the invoice of a paid booking. Booking lines must be loaded.
+// Prices are tax inclusive, so the tax is extracted from the booking total.
+func Build(booking models.Booking, arena models.Arena, sequence int, issuedAt time.Time) models.Invoice {
+	tax := IncludedTax(booking.TotalAmount, arena.TaxRateBps)
+	subtotal, _ := booking.TotalAmount.Sub(tax)
+
+	inv := models.Invoice{
+		ArenaID:    arena.ID,
+		Sequence:   sequence,
+		Number:     Number(arena.ID, sequence),
+		BookingID:  booking.ID,
+		UserID:     booking.UserID,
+		IssuedAt:   issuedAt,
+		Subtotal:   subtotal,
+		TaxName:    arena.TaxName,
+		TaxRateBps: arena.TaxRateBps,
+		Tax:        tax,
+		Total:      booking.TotalAmount,
+	}
+	for _, line := range booking.Lines {
+		inv.Lines = append(inv.Lines, models.InvoiceLine{
+			Description: line.Description,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			Amount:      line.Amount,
+		})
+	}
+	return inv
+}
+
+// IncludedTax returns the tax contained in a tax inclusive amount, rounded half up.
+func IncludedTax(total money.Money, rateBps int64) money.Money {
+	if rateBps <= 0 || total.Minor <= 0 {
+		return money.Zero(total.Currency)
+	}
+	divisor := 10000 + rateBps
+	net := (total.Minor*10000*2 + divisor) / (2 * divisor)
+	return money.New(total.Minor-net, total.Currency)
+}
@@ -0,0 +1,192 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"sparring-backend/internal/models"
+	"strconv"
+)
+
+// A4 page size and layout in PDF points
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	marginLeft   = 50.0
+	marginTop    = 60.0
+	marginBottom = 60.0
+	lineHeight   = 16.0
+)
+
+// Table column positions
+const (
+	colDescription = marginLeft
+	colQuantity    = 340.0
+	colUnitPrice   = 380.0
+	colAmount      = 470.0
+)
+
+// pdfDocument is a minimal PDF writer producing text-only pages with the
+// built-in Helvetica fonts, which is all an invoice needs.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64 // Current baseline, from the bottom of the page
+}
+
+// newPage starts a new page and moves the cursor to its top.
+func (d *pdfDocument) newPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pageHeight - marginTop
+}
+
+// text draws a string with its baseline at (x, y). Font is "F1" (regular) or "F2" (bold).
+func (d *pdfDocument) text(x, y float64, font string, size float64, s string) {
+	fmt.Fprintf(d.page, "BT /%s %s Tf %s %s Td %s Tj ET\n", font, num(size), num(x), num(y), pdfString(s))
+}
+
+// rule draws a horizontal line across the page at y.
+func (d *pdfDocument) rule(y float64) {
+	fmt.Fprintf(d.page, "0.5 w %s %s m %s %s l S\n", num(marginLeft), num(y), num(pageWidth-marginLeft), num(y))
+}
+
+// nextLine moves the cursor down, starting a new page when the bottom margin is reached.
+func (d *pdfDocument) nextLine() {
+	d.y -= lineHeight
+	if d.y < marginBottom {
+		d.newPage()
+	}
+}
+
+// bytes serializes the document.
+func (d *pdfDocument) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// Objects 1 to 4 are the catalog, the page tree and the two fonts; pages follow in pairs
+	kids := ""
+	for i := range d.pages {
+		kids += fmt.Sprintf("%d 0 R ", 5+i*2)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(pageWidth), num(pageHeight), 6+i*2))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// num formats a coordinate without needless decimals.
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// pdfString encodes s as a PDF literal string. Characters outside Latin-1 are replaced.
+func pdfString(s string) string {
+	var b bytes.Buffer
+	b.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 32 || r > 255:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// RenderPDF renders the invoice as a PDF document. The invoice lines must be loaded.
+func RenderPDF(inv models.Invoice, arena models.Arena, customer models.User) []byte {
+	doc := &pdfDocument{}
+	doc.newPage()
+
+	// Header
+	doc.text(marginLeft, doc.y, "F2", 18, arena.Name)
+	doc.text(colUnitPrice, doc.y, "F2", 18, "INVOICE")
+	doc.nextLine()
+	doc.text(marginLeft, doc.y, "F1", 10, arena.Location)
+	doc.text(colUnitPrice, doc.y, "F1", 10, inv.Number)
+	doc.nextLine()
+	doc.text(colUnitPrice, doc.y, "F1", 10, "Issued "+inv.IssuedAt.In(arena.Zone()).Format("2 Jan 2006"))
+	doc.nextLine()
+	doc.nextLine()
+
+	// Customer
+	doc.text(marginLeft, doc.y, "F2", 10, "Billed to")
+	doc.nextLine()
+	doc.text(marginLeft, doc.y, "F1", 10, customer.Name)
+	doc.nextLine()
+	doc.text(marginLeft, doc.y, "F1", 10, customer.Email)
+	doc.nextLine()
+	doc.text(marginLeft, doc.y, "F1", 10, fmt.Sprintf("Booking #%d", inv.BookingID))
+	doc.nextLine()
+	doc.nextLine()
+
+	// Line items
+	doc.text(colDescription, doc.y, "F2", 10, "Description")
+	doc.text(colQuantity, doc.y, "F2", 10, "Qty")
+	doc.text(colUnitPrice, doc.y, "F2", 10, "Unit price")
+	doc.text(colAmount, doc.y, "F2", 10, "Amount")
+	doc.rule(doc.y - 5)
+	doc.nextLine()
+	for _, line := range inv.Lines {
+		doc.text(colDescription, doc.y, "F1", 9, truncate(line.Description, 60))
+		doc.text(colQuantity, doc.y, "F1", 9, strconv.Itoa(line.Quantity))
+		doc.text(colUnitPrice, doc.y, "F1", 9, line.UnitPrice.String())
+		doc.text(colAmount, doc.y, "F1", 9, line.Amount.String())
+		doc.nextLine()
+	}
+	doc.rule(doc.y + lineHeight - 5)
+
+	// Totals
+	doc.text(colUnitPrice, doc.y, "F1", 10, "Subtotal")
+	doc.text(colAmount, doc.y, "F1", 10, inv.Subtotal.String())
+	doc.nextLine()
+	doc.text(colUnitPrice, doc.y, "F1", 10, fmt.Sprintf("%s %s%%", inv.TaxName, percent(inv.TaxRateBps)))
+	doc.text(colAmount, doc.y, "F1", 10, inv.Tax.String())
+	doc.nextLine()
+	doc.text(colUnitPrice, doc.y, "F2", 10, "Total paid")
+	doc.text(colAmount, doc.y, "F2", 10, inv.Total.String())
+	doc.nextLine()
+	doc.nextLine()
+	doc.text(marginLeft, doc.y, "F1", 9, "Prices include tax. Thank you for your booking.")
+
+	return doc.bytes()
+}
+
+// percent formats basis points as a percentage, e.g. 1150 as "11.5".
+func percent(bps int64) string {
+	return strconv.FormatFloat(float64(bps)/100, 'f', -1, 64)
+}
@@ -0,0 +1,133 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"os"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/scheduler"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// batchSize bounds how many bookings a job run transitions
+const batchSize = 200
+
+// BookingConfig configures the booking lifecycle jobs
+type BookingConfig struct {
+	Interval         time.Duration // How often the jobs run
+	NoShowGrace      time.Duration // Time after the start before an un-checked-in booking is a no-show, 0 disables no-shows
+	NoShowPenaltyBps int64         // Penalty charged on no-shows, in basis points of the booking total, 0 disables it
+}
+
+// BookingConfigFromEnv reads the booking job configuration from environment variables,
+// falling back to a 15 minute grace period and no penalty.
+func BookingConfigFromEnv() BookingConfig {
+	cfg := BookingConfig{
+		Interval:    5 * time.Minute,
+		NoShowGrace: 15 * time.Minute,
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("NO_SHOW_GRACE_MINUTES")); err == nil && minutes >= 0 {
+		cfg.NoShowGrace = time.Duration(minutes) * time.Minute
+	}
+	if bps, err := strconv.ParseInt(os.Getenv("NO_SHOW_PENALTY_BPS"), 10, 64); err == nil && bps >= 0 {
+		cfg.NoShowPenaltyBps = bps
+	}
+	return cfg
+}
+
+// BookingJobs returns the jobs moving bookings through their lifecycle.
+func BookingJobs(cfg BookingConfig) []scheduler.Job {
+	jobs := []scheduler.Job{{
+		Name:     "complete-bookings",
+		Interval: cfg.Interval,
+		Run: func(ctx context.Context, db *gorm.DB) error {
+			return completeBookings(db, cfg, time.Now())
+		},
+	}}
+	if cfg.NoShowGrace > 0 {
+		jobs = append(jobs, scheduler.Job{
+			Name:     "mark-no-shows",
+			Interval: cfg.Interval,
+			Run: func(ctx context.Context, db *gorm.DB) error {
+				return markNoShows(db, cfg, time.Now())
+			},
+		})
+	}
+	return jobs
+}
+
+// completeBookings marks bookings that have ended as completed. When no-shows are
+// tracked only checked-in bookings and tournament fixtures complete; the others become
+// no-shows instead.
+func completeBookings(db *gorm.DB, cfg BookingConfig, now time.Time) error {
+	query := db.Where("status = ?", models.BookingStatusCheckedIn)
+	if cfg.NoShowGrace == 0 {
+		query = query.Or("status = ?", models.BookingStatusConfirmed)
+	} else {
+		query = query.Or("status = ? AND tournament_id IS NOT NULL", models.BookingStatusConfirmed)
+	}
+
+	result := db.Model(&models.Booking{}).Where(query).
+		Where("DATE_ADD(booking_time, INTERVAL duration HOUR) < ?", now).
+		Limit(batchSize).
+		Update("status", models.BookingStatusCompleted)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Marked %d bookings as completed", result.RowsAffected)
+	}
+	return nil
+}
+
+// markNoShows marks confirmed bookings nobody checked in to after the grace period,
+// and charges the optional penalty to the customer. Tournament fixtures are held by the
+// arena owner, so they never count as no-shows.
+func markNoShows(db *gorm.DB, cfg BookingConfig, now time.Time) error {
+	var bookings []models.Booking
+	if err := db.Where("status = ? AND checked_in_at IS NULL AND booking_time < ?", models.BookingStatusConfirmed, now.Add(-cfg.NoShowGrace)).
+		Where("tournament_id IS NULL").
+		Limit(batchSize).
+		Find(&bookings).Error; err != nil {
+		return err
+	}
+
+	for _, booking := range bookings {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Guard on the status so a concurrent check-in wins
+			result := tx.Model(&models.Booking{}).
+				Where("id = ? AND status = ?", booking.ID, models.BookingStatusConfirmed).
+				Update("status", models.BookingStatusNoShow)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			if err := tx.Model(&models.User{}).Where("id = ?", booking.UserID).
+				UpdateColumn("no_show_count", gorm.Expr("no_show_count + 1")).Error; err != nil {
+				return err
+			}
+
+			if cfg.NoShowPenaltyBps == 0 {
+				return nil
+			}
+			penalty := models.AccountPenalty{
+				UserID:    booking.UserID,
+				BookingID: booking.ID,
+				Reason:    models.PenaltyReasonNoShow,
+				Amount:    booking.TotalAmount.Percent(cfg.NoShowPenaltyBps),
+			}
+			return tx.Create(&penalty).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(bookings) > 0 {
+		log.Printf("Checked %d bookings for no-shows", len(bookings))
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sparring-backend/internal/loyalty"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/notify"
+	"sparring-backend/internal/scheduler"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoyaltyJobs returns the jobs awarding loyalty points for completed bookings and
+// referrals, and expiring points past their date, at the configured intervals.
+func LoyaltyJobs(cfg loyalty.Config) []scheduler.Job {
+	var jobs []scheduler.Job
+	if cfg.AwardInterval > 0 {
+		jobs = append(jobs, scheduler.Job{
+			Name:     "award-loyalty-points",
+			Interval: cfg.AwardInterval,
+			Run: func(ctx context.Context, db *gorm.DB) error {
+				now := time.Now()
+				if err := awardBookingPoints(db, cfg, now); err != nil {
+					return err
+				}
+				return rewardReferrals(ctx, db, cfg, now)
+			},
+		})
+	}
+	if cfg.ExpireInterval > 0 {
+		jobs = append(jobs, scheduler.Job{
+			Name:     "expire-loyalty-points",
+			Interval: cfg.ExpireInterval,
+			Run: func(ctx context.Context, db *gorm.DB) error {
+				expired, err := loyalty.Expire(db, time.Now(), batchSize)
+				if expired > 0 {
+					log.Printf("Expired %d loyalty point lots", expired)
+				}
+				return err
+			},
+		})
+	}
+	return jobs
+}
+
+// awardBookingPoints credits the customers of completed paid bookings with points for every
+// hour they paid for: hours paid with points and bookings covered by a pass earn nothing.
+// The points expire cfg.Expiry after the booking ended, so bookings whose points would
+// already have expired are skipped.
+func awardBookingPoints(db *gorm.DB, cfg loyalty.Config, now time.Time) error {
+	if cfg.PointsPerHour == 0 {
+		return nil
+	}
+
+	var bookings []models.Booking
+	if err := db.Where("status = ? AND total_amount_minor > 0", models.BookingStatusCompleted).
+		Where("pass_id IS NULL AND duration > free_hours").
+		Where("DATE_ADD(booking_time, INTERVAL duration HOUR) > ?", now.Add(-cfg.Expiry)).
+		Where("NOT EXISTS (SELECT 1 FROM points_entries WHERE points_entries.reference = CONCAT('booking:', bookings.id))").
+		Order("id").Limit(batchSize).
+		Find(&bookings).Error; err != nil {
+		return err
+	}
+
+	for _, booking := range bookings {
+		bookingID := booking.ID
+		expiresAt := booking.BookingTime.Add(time.Duration(booking.Duration)*time.Hour + cfg.Expiry)
+		entry := models.PointsEntry{
+			UserID:      booking.UserID,
+			Kind:        models.PointsBooking,
+			Points:      cfg.PointsPerHour * (booking.Duration - booking.FreeHours),
+			ExpiresAt:   &expiresAt,
+			Reference:   "booking:" + strconv.FormatUint(uint64(booking.ID), 10),
+			BookingID:   &bookingID,
+			Description: fmt.Sprintf("Booking #%d", booking.ID),
+		}
+		if _, err := loyalty.Credit(db, &entry); err != nil {
+			return err
+		}
+	}
+	if len(bookings) > 0 {
+		log.Printf("Awarded loyalty points for %d bookings", len(bookings))
+	}
+	return nil
+}
+
+// rewardReferrals credits both users of every pending referral whose referred user has
+// completed a paid booking, and lets the referrer know.
+func rewardReferrals(ctx context.Context, db *gorm.DB, cfg loyalty.Config, now time.Time) error {
+	if cfg.ReferralPoints == 0 {
+		return nil
+	}
+
+	var referrals []models.Referral
+	if err := db.Preload("Referred").
+		Where("status = ?", models.ReferralPending).
+		Where("EXISTS (SELECT 1 FROM bookings WHERE bookings.user_id = referrals.referred_id AND bookings.status = ? AND bookings.total_amount_minor > 0 AND bookings.deleted_at IS NULL)", models.BookingStatusCompleted).
+		Order("id").Limit(batchSize).
+		Find(&referrals).Error; err != nil {
+		return err
+	}
+
+	for _, referral := range referrals {
+		rewarded := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Guard on the status so a referral is never rewarded twice
+			result := tx.Model(&models.Referral{}).
+				Where("id = ? AND status = ?", referral.ID, models.ReferralPending).
+				Updates(map[string]interface{}{"status": models.ReferralRewarded, "rewarded_at": now})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			referralID := referral.ID
+			expiresAt := now.Add(cfg.Expiry)
+			reference := "referral:" + strconv.FormatUint(uint64(referral.ID), 10)
+			for _, entry := range []models.PointsEntry{
+				{UserID: referral.ReferrerID, Reference: reference + ":referrer", Description: "Referral of " + referral.Referred.Name},
+				{UserID: referral.ReferredID, Reference: reference + ":referred", Description: "Welcome bonus for joining with a referral"},
+			} {
+				entry.Kind = models.PointsReferral
+				entry.Points = cfg.ReferralPoints
+				entry.ExpiresAt = &expiresAt
+				entry.ReferralID = &referralID
+				if _, err := loyalty.Credit(tx, &entry); err != nil {
+					return err
+				}
+			}
+			rewarded = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !rewarded {
+			continue
+		}
+
+		var referrer models.User
+		if err := db.First(&referrer, referral.ReferrerID).Error; err == nil {
+			notify.Send(ctx, notify.Message{
+				UserID:  referrer.ID,
+				Email:   referrer.Email,
+				Subject: "You earned referral points",
+				Body:    fmt.Sprintf("%s played their first booking. You both earned %d loyalty points.", referral.Referred.Name, cfg.ReferralPoints),
+			})
+		}
+	}
+	return nil
+}
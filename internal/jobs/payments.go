@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/notify"
+	"sparring-backend/internal/scheduler"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentReminderConfig configures the reminders sent to team members with unpaid shares
+type PaymentReminderConfig struct {
+	Interval    time.Duration // How often the job runs
+	RemindEvery time.Duration // Time between reminders for the same share, 0 disables reminders
+}
+
+// PaymentReminderConfigFromEnv reads the reminder configuration from environment variables,
+// falling back to a reminder every 24 hours.
+func PaymentReminderConfigFromEnv() PaymentReminderConfig {
+	cfg := PaymentReminderConfig{
+		Interval:    15 * time.Minute,
+		RemindEvery: 24 * time.Hour,
+	}
+	if hours, err := strconv.Atoi(os.Getenv("PAYMENT_REMINDER_HOURS")); err == nil && hours >= 0 {
+		cfg.RemindEvery = time.Duration(hours) * time.Hour
+	}
+	return cfg
+}
+
+// PaymentReminderJobs returns the job reminding members to pay their share of split bookings.
+func PaymentReminderJobs(cfg PaymentReminderConfig) []scheduler.Job {
+	if cfg.RemindEvery == 0 {
+		return nil
+	}
+	return []scheduler.Job{{
+		Name:     "remind-payment-shares",
+		Interval: cfg.Interval,
+		Run: func(ctx context.Context, db *gorm.DB) error {
+			return remindPaymentShares(ctx, db, cfg, time.Now())
+		},
+	}}
+}
+
+// remindPaymentShares notifies members whose share of an upcoming pending booking is still
+// unpaid, at most once per RemindEvery.
+func remindPaymentShares(ctx context.Context, db *gorm.DB, cfg PaymentReminderConfig, now time.Time) error {
+	var shares []models.PaymentShare
+	if err := db.Preload("User").Preload("Booking").
+		Joins("JOIN bookings ON bookings.id = payment_shares.booking_id").
+		Where("payment_shares.status = ? AND bookings.status = ? AND bookings.booking_time > ?",
+			models.PaymentSharePending, models.BookingStatusPending, now).
+		Where("COALESCE(payment_shares.last_reminded_at, payment_shares.created_at) < ?", now.Add(-cfg.RemindEvery)).
+		Limit(batchSize).
+		Find(&shares).Error; err != nil {
+		return err
+	}
+
+	for _, share := range shares {
+		notify.Send(ctx, notify.Message{
+			UserID:  share.UserID,
+			Email:   share.User.Email,
+			Subject: "Reminder: pay your share",
+			Body: fmt.Sprintf("Your share of %s for booking #%d on %s is still unpaid. The booking is confirmed once everyone has paid.",
+				share.Amount, share.BookingID, share.Booking.BookingTime.Format(time.RFC1123)),
+		})
+		if err := db.Model(&share).Update("last_reminded_at", now).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(shares) > 0 {
+		log.Printf("Sent %d payment share reminders", len(shares))
+	}
+	return nil
+}
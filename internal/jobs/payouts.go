@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sparring-backend/internal/ledger"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/notify"
+	"sparring-backend/internal/scheduler"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PayoutConfig configures the payout batch job
+type PayoutConfig struct {
+	Interval time.Duration // How often balances are paid out, 0 disables payouts
+	Hold     time.Duration // Revenue is held this long before it is paid out, leaving time for refunds
+}
+
+// PayoutConfigFromEnv reads the payout configuration from environment variables, falling
+// back to a daily batch holding revenue for 7 days.
+func PayoutConfigFromEnv() PayoutConfig {
+	cfg := PayoutConfig{
+		Interval: 24 * time.Hour,
+		Hold:     7 * 24 * time.Hour,
+	}
+	if hours, err := strconv.Atoi(os.Getenv("PAYOUT_INTERVAL_HOURS")); err == nil && hours >= 0 {
+		cfg.Interval = time.Duration(hours) * time.Hour
+	}
+	if days, err := strconv.Atoi(os.Getenv("PAYOUT_HOLD_DAYS")); err == nil && days >= 0 {
+		cfg.Hold = time.Duration(days) * 24 * time.Hour
+	}
+	return cfg
+}
+
+// PayoutJobs returns the job creating payouts of the owners' balances.
+func PayoutJobs(cfg PayoutConfig) []scheduler.Job {
+	if cfg.Interval == 0 {
+		return nil
+	}
+	return []scheduler.Job{{
+		Name:     "create-payouts",
+		Interval: cfg.Interval,
+		Run: func(ctx context.Context, db *gorm.DB) error {
+			return createPayouts(ctx, db, cfg, time.Now())
+		},
+	}}
+}
+
+// createPayouts creates a pending payout for every owner and currency with a positive balance
+// of revenue older than the hold period. The balance moves to payouts in transit until an
+// admin marks the payout as sent or failed.
+func createPayouts(ctx context.Context, db *gorm.DB, cfg PayoutConfig, now time.Time) error {
+	cutoff := now.Add(-cfg.Hold)
+
+	var ownerIDs []uint
+	if err := db.Model(&models.LedgerPosting{}).
+		Where("account = ? AND owner_id IS NOT NULL", ledger.AccountOwnerPayable).
+		Distinct().Pluck("owner_id", &ownerIDs).Error; err != nil {
+		return err
+	}
+
+	created := 0
+	for _, ownerID := range ownerIDs {
+		var payouts []models.Payout
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Lock the owner so a payout is never created twice from the same balance
+			var owner models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&owner, ownerID).Error; err != nil {
+				return err
+			}
+			balances, err := ledger.OwnerBalances(tx, ownerID, cutoff)
+			if err != nil {
+				return err
+			}
+
+			for currency, balance := range balances {
+				if balance <= 0 {
+					continue
+				}
+				payout := models.Payout{
+					OwnerID:  ownerID,
+					Owner:    owner,
+					Amount:   money.New(balance, currency),
+					Status:   models.PayoutPending,
+					CutoffAt: cutoff,
+				}
+				if err := tx.Omit("Owner").Create(&payout).Error; err != nil {
+					return err
+				}
+				txn := ledger.PayoutTransaction(payout, models.LedgerPayout, now)
+				if err := ledger.Post(tx, &txn); err != nil {
+					return err
+				}
+				payouts = append(payouts, payout)
+			}
+			return nil
+		})
+		if err != nil {
+			// A deleted owner, for instance, must not hold up the payouts of the others
+			log.Printf("Could not create payouts for owner %d: %v", ownerID, err)
+			continue
+		}
+
+		for _, payout := range payouts {
+			notify.Send(ctx, notify.Message{
+				UserID:  payout.OwnerID,
+				Email:   payout.Owner.Email,
+				Subject: "A payout is on its way",
+				Body:    fmt.Sprintf("We are paying out %s for your bookings up to %s (payout #%d).", payout.Amount, payout.CutoffAt.Format(time.RFC1123), payout.ID),
+			})
+		}
+		created += len(payouts)
+	}
+
+	if created > 0 {
+		log.Printf("Created %d payouts", created)
+	}
+	return nil
+}
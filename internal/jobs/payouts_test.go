@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"context"
+	"sparring-backend/internal/ledger"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/testdb"
+	"testing"
+	"time"
+)
+
+func TestCreatePayoutsSkipsDeletedOwners(t *testing.T) {
+	db := testdb.New(t, &models.User{}, &models.LedgerTransaction{}, &models.LedgerPosting{}, &models.Payout{})
+	now := time.Now()
+	cfg := PayoutConfig{Hold: 24 * time.Hour}
+
+	for i, email := range []string{"gone@example.com", "owner@example.com"} {
+		owner := models.User{Name: "Owner", Email: email}
+		if err := db.Create(&owner).Error; err != nil {
+			t.Fatal(err)
+		}
+		arena := models.Arena{OwnerID: owner.ID}
+		arena.ID = uint(i + 1)
+		payment := models.Payment{BookingID: uint(i + 1), Amount: money.New(1000, "EUR")}
+		payment.ID = uint(i + 1)
+		txn := ledger.PaymentTransaction(payment, arena, 1000)
+		txn.OccurredAt = now.Add(-48 * time.Hour)
+		if err := ledger.Post(db, &txn); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if err := db.Delete(&owner).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := createPayouts(context.Background(), db, cfg, now); err != nil {
+		t.Fatalf("createPayouts() = %v", err)
+	}
+	var payouts []models.Payout
+	db.Find(&payouts)
+	if len(payouts) != 1 || payouts[0].Amount != money.New(900, "EUR") {
+		t.Errorf("payouts = %+v, want one of 9.00 EUR for the remaining owner", payouts)
+	}
+}
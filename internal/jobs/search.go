@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"sparring-backend/internal/scheduler"
+	"sparring-backend/internal/search"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SearchConfig configures the refresh of the search index
+type SearchConfig struct {
+	Interval time.Duration // How often every replica rebuilds its index, 0 disables it
+}
+
+// SearchConfigFromEnv reads the search index configuration from environment variables,
+// falling back to a refresh every 5 minutes.
+func SearchConfigFromEnv() SearchConfig {
+	cfg := SearchConfig{Interval: 5 * time.Minute}
+	if minutes, err := strconv.Atoi(os.Getenv("SEARCH_REFRESH_MINUTES")); err == nil && minutes >= 0 {
+		cfg.Interval = time.Duration(minutes) * time.Minute
+	}
+	return cfg
+}
+
+// SearchJobs returns the job refreshing the search index from the database. Every replica
+// holds its own in-memory index, so the job runs on all of them.
+func SearchJobs(cfg SearchConfig) []scheduler.Job {
+	if cfg.Interval == 0 {
+		return nil
+	}
+	return []scheduler.Job{{
+		Name:     "refresh-search-index",
+		Interval: cfg.Interval,
+		Local:    true,
+		Run: func(ctx context.Context, db *gorm.DB) error {
+			return search.Refresh(ctx, search.Default, db)
+		},
+	}}
+}
@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/notify"
+	"sparring-backend/internal/scheduler"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FieldWatchConfig configures the field watch job
+type FieldWatchConfig struct {
+	Interval time.Duration // How often watches are checked, 0 disables the job
+}
+
+// FieldWatchConfigFromEnv reads the field watch configuration from environment variables,
+// falling back to a check every 5 minutes.
+func FieldWatchConfigFromEnv() FieldWatchConfig {
+	cfg := FieldWatchConfig{Interval: 5 * time.Minute}
+	if minutes, err := strconv.Atoi(os.Getenv("FIELD_WATCH_INTERVAL_MINUTES")); err == nil && minutes >= 0 {
+		cfg.Interval = time.Duration(minutes) * time.Minute
+	}
+	return cfg
+}
+
+// FieldWatchJobs returns the job notifying users when a field they watch frees up.
+func FieldWatchJobs(cfg FieldWatchConfig) []scheduler.Job {
+	if cfg.Interval == 0 {
+		return nil
+	}
+	return []scheduler.Job{{
+		Name:     "check-field-watches",
+		Interval: cfg.Interval,
+		Run: func(ctx context.Context, db *gorm.DB) error {
+			return checkFieldWatches(ctx, db, time.Now())
+		},
+	}}
+}
+
+// checkFieldWatches looks for a free slot in the window of every watch that hasn't passed and
+// notifies the user when one appears that wasn't there at the previous check.
+func checkFieldWatches(ctx context.Context, db *gorm.DB, now time.Time) error {
+	var watches []models.FieldWatch
+	notified := 0
+	err := db.Preload("User").Preload("Field").
+		Where("ends_at > ?", now).
+		FindInBatches(&watches, batchSize, func(tx *gorm.DB, batch int) error {
+			for _, watch := range watches {
+				start, available, err := FreeSlot(db, watch, now)
+				if err != nil {
+					return err
+				}
+				if available == watch.Available {
+					continue
+				}
+
+				updates := map[string]interface{}{"available": available}
+				if available {
+					notify.Send(ctx, notify.Message{
+						UserID:  watch.UserID,
+						Email:   watch.User.Email,
+						Subject: "A field you watch is free",
+						Body: fmt.Sprintf("%s is free for %d hours from %s. Book it before someone else does.",
+							watch.Field.FieldName, watch.Duration, start.Format(time.RFC1123)),
+					})
+					updates["notified_at"] = now
+					notified++
+				}
+				if err := db.Model(&watch).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+
+	if notified > 0 {
+		log.Printf("Notified %d field watches", notified)
+	}
+	return err
+}
+
+// FreeSlot returns the start of the first gap of the watch duration between the active
+// bookings of the field within the rest of the window, and whether there is one.
+func FreeSlot(db *gorm.DB, watch models.FieldWatch, now time.Time) (time.Time, bool, error) {
+	start, end := watch.StartsAt, watch.EndsAt
+	if start.Before(now) {
+		start = now
+	}
+	duration := time.Duration(watch.Duration) * time.Hour
+
+	var bookings []models.Booking
+	if err := db.Where("field_id = ?", watch.FieldID).
+		Scopes(models.ActiveBookingsBetween(start, end)).
+		Find(&bookings).Error; err != nil {
+		return time.Time{}, false, err
+	}
+	sort.Slice(bookings, func(i, j int) bool { return bookings[i].BookingTime.Before(bookings[j].BookingTime) })
+
+	cursor := start
+	for _, booking := range bookings {
+		if booking.BookingTime.Sub(cursor) >= duration {
+			return cursor, true, nil
+		}
+		if bookingEnd := booking.BookingTime.Add(time.Duration(booking.Duration) * time.Hour); bookingEnd.After(cursor) {
+			cursor = bookingEnd
+		}
+	}
+	return cursor, end.Sub(cursor) >= duration, nil
+}
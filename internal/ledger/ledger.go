@@ -0,0 +1,256 @@
+package ledger
+
+import (
+	"errors"
+	"os"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnbalanced is returned for transactions whose postings don't add up to zero
+var ErrUnbalanced = errors.New("ledger transaction is not balanced")
+
+// Accounts. Debits are positive amounts and credits negative ones, so the owner payable
+// account normally has a negative (credit) balance: what the platform owes the owner.
+const (
+	AccountCash             = "cash"               // Money held by the platform at the payment provider
+	AccountOwnerPayable     = "owner_payable"      // Owed to an owner, kept per owner
+	AccountCommission       = "commission_revenue" // Platform commission earned
+	AccountPayoutsInTransit = "payouts_in_transit" // Payouts created but not sent yet
+)
+
+// DefaultCommissionBps returns the platform commission, PLATFORM_COMMISSION_BPS basis points
+// (1000 = 10%, the default).
+func DefaultCommissionBps() int64 {
+	if bps, err := strconv.ParseInt(os.Getenv("PLATFORM_COMMISSION_BPS"), 10, 64); err == nil && bps >= 0 && bps <= 10000 {
+		return bps
+	}
+	return 1000
+}
+
+// CommissionBps returns the commission rate of the arena.
+func CommissionBps(arena models.Arena) int64 {
+	if arena.CommissionBps != nil {
+		return *arena.CommissionBps
+	}
+	return DefaultCommissionBps()
+}
+
+// Debit returns a posting adding the amount to the account.
+func Debit(account string, ownerID *uint, amount money.Money) models.LedgerPosting {
+	return models.LedgerPosting{Account: account, OwnerID: ownerID, Amount: amount}
+}
+
+// Credit returns a posting taking the amount from the account.
+func Credit(account string, ownerID *uint, amount money.Money) models.LedgerPosting {
+	return models.LedgerPosting{Account: account, OwnerID: ownerID, Amount: amount.Neg()}
+}
+
+// Validate checks the postings add up to zero in every currency.
+func Validate(postings []models.LedgerPosting) error {
+	if len(postings) < 2 {
+		return ErrUnbalanced
+	}
+	sums := map[string]int64{}
+	for _, posting := range postings {
+		sums[posting.Amount.Currency] += posting.Amount.Minor
+	}
+	for _, sum := range sums {
+		if sum != 0 {
+			return ErrUnbalanced
+		}
+	}
+	return nil
+}
+
+// Post records the transaction with its postings. Posting a reference a second time does
+// nothing, so callers can safely retry. Zero postings are left out.
+func Post(tx *gorm.DB, txn *models.LedgerTransaction) error {
+	postings := make([]models.LedgerPosting, 0, len(txn.Postings))
+	for _, posting := range txn.Postings {
+		if !posting.Amount.IsZero() {
+			postings = append(postings, posting)
+		}
+	}
+	if len(postings) == 0 {
+		return nil // Nothing moved
+	}
+	if err := Validate(postings); err != nil {
+		return err
+	}
+	if txn.OccurredAt.IsZero() {
+		txn.OccurredAt = time.Now()
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("Postings").Create(txn)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil // Already posted
+	}
+	for i := range postings {
+		postings[i].TransactionID = txn.ID
+	}
+	txn.Postings = postings
+	return tx.Create(&postings).Error
+}
+
+// PaymentTransaction splits a booking payment between the owner and the platform commission.
+func PaymentTransaction(payment models.Payment, arena models.Arena, bps int64) models.LedgerTransaction {
+	commission := payment.Amount.Percent(bps)
+	ownerShare, _ := payment.Amount.Sub(commission)
+	return models.LedgerTransaction{
+		Reference:     "payment:" + strconv.FormatUint(uint64(payment.ID), 10),
+		Kind:          models.LedgerBookingPayment,
+		OwnerID:       arena.OwnerID,
+		ArenaID:       &arena.ID,
+		BookingID:     &payment.BookingID,
+		PaymentID:     &payment.ID,
+		CommissionBps: bps,
+		Description:   "Payment " + payment.ProviderRef + " for booking #" + strconv.FormatUint(uint64(payment.BookingID), 10),
+		Postings: []models.LedgerPosting{
+			Debit(AccountCash, nil, payment.Amount),
+			Credit(AccountOwnerPayable, &arena.OwnerID, ownerShare),
+			Credit(AccountCommission, nil, commission),
+		},
+	}
+}
+
+// RefundTransaction reverses part of a payment: the owner and the platform give back their
+// share of the refund. commission is the part of the refund taken back from the commission.
+func RefundTransaction(reference string, payment models.Payment, arena models.Arena, refund, commission money.Money) models.LedgerTransaction {
+	ownerShare, _ := refund.Sub(commission)
+	return models.LedgerTransaction{
+		Reference:   reference,
+		Kind:        models.LedgerRefund,
+		OwnerID:     arena.OwnerID,
+		ArenaID:     &arena.ID,
+		BookingID:   &payment.BookingID,
+		PaymentID:   &payment.ID,
+		Description: "Refund of payment " + payment.ProviderRef + " for booking #" + strconv.FormatUint(uint64(payment.BookingID), 10),
+		Postings: []models.LedgerPosting{
+			Debit(AccountOwnerPayable, &arena.OwnerID, ownerShare),
+			Debit(AccountCommission, nil, commission),
+			Credit(AccountCash, nil, refund),
+		},
+	}
+}
+
+// RefundCommission returns the commission to give back for a refund of the payment. It is
+// proportional to the refund, and whatever commission is left when the payment is fully
+// refunded, so rounding never leaves commission on a refunded payment.
+func RefundCommission(tx *gorm.DB, payment models.Payment, refund money.Money, fullyRefunded bool) (money.Money, error) {
+	var original models.LedgerTransaction
+	err := tx.Where("reference = ?", "payment:"+strconv.FormatUint(uint64(payment.ID), 10)).First(&original).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return money.Zero(refund.Currency), nil // Paid before the ledger existed
+	}
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	// Commission still held for the payment: the negated balance of its commission postings
+	var held int64
+	if err := tx.Model(&models.LedgerPosting{}).
+		Joins("JOIN ledger_transactions ON ledger_transactions.id = ledger_postings.transaction_id").
+		Where("ledger_transactions.payment_id = ? AND ledger_postings.account = ?", payment.ID, AccountCommission).
+		Select("COALESCE(-SUM(ledger_postings.amount_minor), 0)").
+		Scan(&held).Error; err != nil {
+		return money.Money{}, err
+	}
+	remaining := money.New(held, refund.Currency)
+	if fullyRefunded {
+		return remaining, nil
+	}
+	return refund.Percent(original.CommissionBps).Min(remaining), nil
+}
+
+// PostLegacyPayment posts a payment received before the ledger existed, and what was
+// refunded of it. Payments refunded before refunds were tracked count as refunded in full.
+func PostLegacyPayment(tx *gorm.DB, payment models.Payment, arena models.Arena) error {
+	txn := PaymentTransaction(payment, arena, CommissionBps(arena))
+	txn.OccurredAt = payment.CreatedAt
+	if err := Post(tx, &txn); err != nil {
+		return err
+	}
+
+	refunded := payment.Refunded
+	fullyRefunded := payment.Status == models.PaymentStatusRefunded
+	if fullyRefunded {
+		refunded = payment.Amount
+	}
+	if refunded.IsZero() {
+		return nil
+	}
+	commission, err := RefundCommission(tx, payment, refunded, fullyRefunded)
+	if err != nil {
+		return err
+	}
+	refund := RefundTransaction("refund:legacy:"+strconv.FormatUint(uint64(payment.ID), 10), payment, arena, refunded, commission)
+	refund.OccurredAt = payment.UpdatedAt
+	return Post(tx, &refund)
+}
+
+// OwnerBalances returns what the platform owes the owner per currency, counting only
+// revenue earned up to cutoff; refunds, payouts and later corrections always count.
+// A negative balance means the owner owes the platform.
+func OwnerBalances(tx *gorm.DB, ownerID uint, cutoff time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Currency string
+		Balance  int64
+	}
+	err := tx.Model(&models.LedgerPosting{}).
+		Joins("JOIN ledger_transactions ON ledger_transactions.id = ledger_postings.transaction_id").
+		Where("ledger_postings.account = ? AND ledger_postings.owner_id = ?", AccountOwnerPayable, ownerID).
+		Where("(ledger_transactions.kind <> ? OR ledger_transactions.occurred_at <= ?)", models.LedgerBookingPayment, cutoff).
+		Select("ledger_postings.amount_currency AS currency, -SUM(ledger_postings.amount_minor) AS balance").
+		Group("ledger_postings.amount_currency").
+		Scan(&rows).Error
+
+	balances := map[string]int64{}
+	for _, row := range rows {
+		balances[row.Currency] = row.Balance
+	}
+	return balances, err
+}
+
+// PayoutTransaction records a step of a payout: kind LedgerPayout moves the owner's balance
+// to payouts in transit, LedgerPayoutSent pays it out of cash and LedgerPayoutFailed gives
+// it back to the owner.
+func PayoutTransaction(payout models.Payout, kind string, at time.Time) models.LedgerTransaction {
+	id := strconv.FormatUint(uint64(payout.ID), 10)
+	txn := models.LedgerTransaction{
+		Reference:  kind + ":" + id,
+		Kind:       kind,
+		OwnerID:    payout.OwnerID,
+		PayoutID:   &payout.ID,
+		OccurredAt: at,
+	}
+	switch kind {
+	case models.LedgerPayout:
+		txn.Description = "Payout #" + id
+		txn.Postings = []models.LedgerPosting{
+			Debit(AccountOwnerPayable, &payout.OwnerID, payout.Amount),
+			Credit(AccountPayoutsInTransit, nil, payout.Amount),
+		}
+	case models.LedgerPayoutSent:
+		txn.Description = "Payout #" + id + " sent"
+		txn.Postings = []models.LedgerPosting{
+			Debit(AccountPayoutsInTransit, nil, payout.Amount),
+			Credit(AccountCash, nil, payout.Amount),
+		}
+	case models.LedgerPayoutFailed:
+		txn.Description = "Payout #" + id + " failed"
+		txn.Postings = []models.LedgerPosting{
+			Debit(AccountPayoutsInTransit, nil, payout.Amount),
+			Credit(AccountOwnerPayable, &payout.OwnerID, payout.Amount),
+		}
+	}
+	return txn
+}
@@ -0,0 +1,190 @@
+package ledger
+
+import (
+	"errors"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"sparring-backend/internal/testdb"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	owner := uint(7)
+	tests := []struct {
+		name     string
+		postings []models.LedgerPosting
+		want     error
+	}{
+		{"balanced", []models.LedgerPosting{
+			Debit(AccountCash, nil, money.New(1000, "EUR")),
+			Credit(AccountOwnerPayable, &owner, money.New(900, "EUR")),
+			Credit(AccountCommission, nil, money.New(100, "EUR")),
+		}, nil},
+		{"unbalanced", []models.LedgerPosting{
+			Debit(AccountCash, nil, money.New(1000, "EUR")),
+			Credit(AccountOwnerPayable, &owner, money.New(999, "EUR")),
+		}, ErrUnbalanced},
+		{"balanced across currencies only", []models.LedgerPosting{
+			Debit(AccountCash, nil, money.New(1000, "EUR")),
+			Credit(AccountOwnerPayable, &owner, money.New(1000, "USD")),
+		}, ErrUnbalanced},
+		{"single posting", []models.LedgerPosting{
+			Debit(AccountCash, nil, money.Zero("EUR")),
+		}, ErrUnbalanced},
+		{"no postings", nil, ErrUnbalanced},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.postings); !errors.Is(err, tt.want) {
+				t.Errorf("Validate() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostRejectsUnbalanced(t *testing.T) {
+	db := testdb.New(t, &models.LedgerTransaction{}, &models.LedgerPosting{})
+	owner := uint(7)
+	txn := models.LedgerTransaction{Reference: "payment:1", Kind: models.LedgerBookingPayment, OwnerID: owner, Postings: []models.LedgerPosting{
+		Debit(AccountCash, nil, money.New(1000, "EUR")),
+		Credit(AccountOwnerPayable, &owner, money.New(900, "EUR")),
+	}}
+	if err := Post(db, &txn); !errors.Is(err, ErrUnbalanced) {
+		t.Fatalf("Post() = %v, want ErrUnbalanced", err)
+	}
+	var count int64
+	db.Model(&models.LedgerTransaction{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d transactions recorded, want 0", count)
+	}
+}
+
+func TestPostIsIdempotent(t *testing.T) {
+	db := testdb.New(t, &models.LedgerTransaction{}, &models.LedgerPosting{})
+	arena := models.Arena{OwnerID: 7}
+	arena.ID = 3
+	payment := models.Payment{BookingID: 5, Amount: money.New(1000, "EUR"), ProviderRef: "pi_1"}
+	payment.ID = 1
+
+	for i := 0; i < 2; i++ {
+		txn := PaymentTransaction(payment, arena, 1000)
+		if err := Post(db, &txn); err != nil {
+			t.Fatalf("Post() #%d = %v", i+1, err)
+		}
+	}
+
+	var transactions, postings int64
+	db.Model(&models.LedgerTransaction{}).Count(&transactions)
+	db.Model(&models.LedgerPosting{}).Count(&postings)
+	if transactions != 1 || postings != 3 {
+		t.Errorf("got %d transactions and %d postings, want 1 and 3", transactions, postings)
+	}
+	balances, err := OwnerBalances(db, arena.OwnerID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances["EUR"] != 900 {
+		t.Errorf("owner balance = %d, want 900", balances["EUR"])
+	}
+}
+
+func TestRefundCommission(t *testing.T) {
+	db := testdb.New(t, &models.LedgerTransaction{}, &models.LedgerPosting{})
+	arena := models.Arena{OwnerID: 7}
+	arena.ID = 3
+	payment := models.Payment{BookingID: 5, Amount: money.New(1000, "EUR"), ProviderRef: "pi_1"}
+	payment.ID = 1
+
+	// Before the payment is in the ledger there is no commission to give back
+	commission, err := RefundCommission(db, payment, money.New(300, "EUR"), false)
+	if err != nil || !commission.IsZero() {
+		t.Fatalf("RefundCommission() before posting = %v, %v, want 0", commission, err)
+	}
+
+	txn := PaymentTransaction(payment, arena, 1250) // 125.00 of commission
+	if err := Post(db, &txn); err != nil {
+		t.Fatal(err)
+	}
+
+	// A partial refund takes back its share, rounded: 12.5% of 300 is 37.5
+	commission, err = RefundCommission(db, payment, money.New(300, "EUR"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commission.Minor != 38 {
+		t.Errorf("partial refund commission = %d, want 38", commission.Minor)
+	}
+	refund := RefundTransaction("refund:1", payment, arena, money.New(300, "EUR"), commission)
+	if err := Post(db, &refund); err != nil {
+		t.Fatal(err)
+	}
+
+	// The full refund of the rest gives back what is left, not its rounded 87.5
+	commission, err = RefundCommission(db, payment, money.New(700, "EUR"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commission.Minor != 87 {
+		t.Errorf("full refund commission = %d, want 87", commission.Minor)
+	}
+	refund = RefundTransaction("refund:2", payment, arena, money.New(700, "EUR"), commission)
+	if err := Post(db, &refund); err != nil {
+		t.Fatal(err)
+	}
+
+	var held int64
+	db.Model(&models.LedgerPosting{}).Where("account = ?", AccountCommission).Select("COALESCE(SUM(amount_minor), 0)").Scan(&held)
+	if held != 0 {
+		t.Errorf("commission left on the refunded payment = %d, want 0", held)
+	}
+	balances, err := OwnerBalances(db, arena.OwnerID, txn.OccurredAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances["EUR"] != 0 {
+		t.Errorf("owner balance = %d, want 0", balances["EUR"])
+	}
+}
+
+func TestPostLegacyPayment(t *testing.T) {
+	arena := models.Arena{OwnerID: 7}
+	arena.ID = 3
+	tests := []struct {
+		name     string
+		status   string
+		refunded int64
+		want     int64 // Owner balance afterwards
+	}{
+		{"paid", models.PaymentStatusSucceeded, 0, 900},
+		{"partly refunded", models.PaymentStatusSucceeded, 400, 540},
+		{"refunded", models.PaymentStatusRefunded, 1000, 0},
+		{"refunded before refunds were tracked", models.PaymentStatusRefunded, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.New(t, &models.LedgerTransaction{}, &models.LedgerPosting{})
+			payment := models.Payment{BookingID: 5, Amount: money.New(1000, "EUR"), Refunded: money.New(tt.refunded, "EUR"), Status: tt.status, ProviderRef: "pi_1"}
+			payment.ID = 1
+			payment.CreatedAt = time.Now().Add(-time.Hour)
+
+			for i := 0; i < 2; i++ { // The migration may run again after a failure
+				if err := PostLegacyPayment(db, payment, arena); err != nil {
+					t.Fatalf("PostLegacyPayment() #%d = %v", i+1, err)
+				}
+			}
+			balances, err := OwnerBalances(db, arena.OwnerID, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if balances["EUR"] != tt.want {
+				t.Errorf("owner balance = %d, want %d", balances["EUR"], tt.want)
+			}
+			var commission int64
+			db.Model(&models.LedgerPosting{}).Where("account = ?", AccountCommission).Select("COALESCE(-SUM(amount_minor), 0)").Scan(&commission)
+			if tt.status == models.PaymentStatusRefunded && commission != 0 {
+				t.Errorf("commission left on a refunded payment = %d, want 0", commission)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package loyalty
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"sparring-backend/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInsufficientPoints = errors.New("not enough loyalty points")
+	ErrInvalidRedemption  = errors.New("points are redeemed in whole free hours of field time")
+)
+
+// Config sets how points are earned, redeemed and expire
+type Config struct {
+	PointsPerHour     int           // Earned per hour of a completed booking paid for
+	ReferralPoints    int           // Earned by the referrer and the referred user on a successful referral
+	PointsPerFreeHour int           // Redeemed for one hour of field time
+	Expiry            time.Duration // Points expire this long after they were earned
+	AwardInterval     time.Duration // How often points are awarded, 0 disables the job
+	ExpireInterval    time.Duration // How often lots past their date are expired, 0 disables the job
+}
+
+// Default is the loyalty configuration used by the application, set from ConfigFromEnv
+// when the server starts
+var Default = defaultConfig()
+
+// defaultConfig returns 10 points per hour, 200 points per referral, 100 points per free
+// hour and a year of validity, awarded every 5 minutes and expired hourly.
+func defaultConfig() Config {
+	return Config{
+		PointsPerHour:     10,
+		ReferralPoints:    200,
+		PointsPerFreeHour: 100,
+		Expiry:            365 * 24 * time.Hour,
+		AwardInterval:     5 * time.Minute,
+		ExpireInterval:    time.Hour,
+	}
+}
+
+// ConfigFromEnv reads the loyalty configuration from environment variables, falling back
+// to the defaults for those unset or invalid.
+func ConfigFromEnv() Config {
+	cfg := defaultConfig()
+	if points, err := strconv.Atoi(os.Getenv("LOYALTY_POINTS_PER_HOUR")); err == nil && points >= 0 {
+		cfg.PointsPerHour = points
+	}
+	if points, err := strconv.Atoi(os.Getenv("LOYALTY_REFERRAL_POINTS")); err == nil && points >= 0 {
+		cfg.ReferralPoints = points
+	}
+	if points, err := strconv.Atoi(os.Getenv("LOYALTY_POINTS_PER_FREE_HOUR")); err == nil && points > 0 {
+		cfg.PointsPerFreeHour = points
+	}
+	if days, err := strconv.Atoi(os.Getenv("LOYALTY_POINTS_EXPIRY_DAYS")); err == nil && days > 0 {
+		cfg.Expiry = time.Duration(days) * 24 * time.Hour
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("LOYALTY_AWARD_INTERVAL_MINUTES")); err == nil && minutes >= 0 {
+		cfg.AwardInterval = time.Duration(minutes) * time.Minute
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("LOYALTY_EXPIRE_INTERVAL_MINUTES")); err == nil && minutes >= 0 {
+		cfg.ExpireInterval = time.Duration(minutes) * time.Minute
+	}
+	return cfg
+}
+
+// FreeHours returns the hours of field time the points pay for, failing unless they are
+// a positive multiple of the points per free hour.
+func (cfg Config) FreeHours(points int) (int, error) {
+	if points <= 0 || points%cfg.PointsPerFreeHour != 0 {
+		return 0, ErrInvalidRedemption
+	}
+	return points / cfg.PointsPerFreeHour, nil
+}
+
+// referralAlphabet leaves out characters that are easily mistaken for one another
+const referralAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NewReferralCode returns a random 8 character referral code.
+func NewReferralCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = referralAlphabet[int(b[i])%len(referralAlphabet)]
+	}
+	return string(b), nil
+}
+
+// NormalizeCode makes referral codes case-insensitive.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Credit adds a lot of points to the user's balance. Crediting a reference a second time
+// does nothing, so callers can safely retry. It reports whether the points were added.
+func Credit(tx *gorm.DB, entry *models.PointsEntry) (bool, error) {
+	if entry.Points <= 0 {
+		return false, nil
+	}
+	entry.Remaining = entry.Points
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Debit takes points from the user's unexpired lots, those expiring first used first,
+// and records the movement with the points taken from every lot. The lots are locked so
+// concurrent debits can't spend the same points. Debiting a reference a second time
+// does nothing.
+func Debit(tx *gorm.DB, entry *models.PointsEntry, now time.Time) error {
+	if entry.Points >= 0 {
+		return ErrInvalidRedemption
+	}
+	var existing int64
+	if err := tx.Model(&models.PointsEntry{}).Where("reference = ?", entry.Reference).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return nil // Already debited
+	}
+
+	var lots []models.PointsEntry
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND remaining > 0 AND expires_at > ?", entry.UserID, now).
+		Order("expires_at, id").Find(&lots).Error; err != nil {
+		return err
+	}
+
+	needed := -entry.Points
+	var uses []models.PointsLotUse
+	for _, lot := range lots {
+		if needed == 0 {
+			break
+		}
+		used := min(lot.Remaining, needed)
+		if err := tx.Model(&lot).UpdateColumn("remaining", lot.Remaining-used).Error; err != nil {
+			return err
+		}
+		uses = append(uses, models.PointsLotUse{LotID: lot.ID, Points: used})
+		needed -= used
+	}
+	if needed > 0 {
+		return ErrInsufficientPoints
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+	for i := range uses {
+		uses[i].EntryID = entry.ID
+	}
+	return tx.Create(&uses).Error
+}
+
+// Restore gives the points of a debit back to the lots they were taken from, which keep
+// their expiry dates, and records the movement as entry. Points taken from lots that have
+// expired since are not given back. Restoring a debit a second time does nothing. It
+// returns the points given back.
+func Restore(tx *gorm.DB, debit models.PointsEntry, entry *models.PointsEntry, now time.Time) (int, error) {
+	// Lock the debit so concurrent restores of it run one after the other
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&debit, debit.ID).Error; err != nil {
+		return 0, err
+	}
+	var existing int64
+	if err := tx.Model(&models.PointsEntry{}).Where("reference = ?", entry.Reference).Count(&existing).Error; err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, nil // Already restored
+	}
+
+	var uses []models.PointsLotUse
+	if err := tx.Where("entry_id = ?", debit.ID).Order("id").Find(&uses).Error; err != nil {
+		return 0, err
+	}
+	restored := 0
+	for _, use := range uses {
+		var lot models.PointsEntry
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&lot, use.LotID).Error; err != nil {
+			return 0, err
+		}
+		if lot.ExpiresAt == nil || !lot.ExpiresAt.After(now) {
+			continue // Would have expired anyway
+		}
+		if err := tx.Model(&lot).UpdateColumn("remaining", lot.Remaining+use.Points).Error; err != nil {
+			return 0, err
+		}
+		restored += use.Points
+	}
+	if restored == 0 {
+		return 0, nil
+	}
+
+	// The entry only records the movement, the points are back in their lots
+	entry.Points = restored
+	entry.Remaining = 0
+	entry.ExpiresAt = nil
+	return restored, tx.Create(entry).Error
+}
+
+// Balance returns the user's points that haven't been redeemed or expired.
+func Balance(tx *gorm.DB, userID uint, now time.Time) (int, error) {
+	var balance int
+	err := tx.Model(&models.PointsEntry{}).
+		Where("user_id = ? AND remaining > 0 AND expires_at > ?", userID, now).
+		Select("COALESCE(SUM(remaining), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// Expire records the expiry of up to limit lots that are past their date with points left.
+// It returns how many lots expired.
+func Expire(db *gorm.DB, now time.Time, limit int) (int, error) {
+	var lots []models.PointsEntry
+	if err := db.Where("remaining > 0 AND expires_at <= ?", now).Order("expires_at, id").Limit(limit).Find(&lots).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, lot := range lots {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Lock the lot so a concurrent redemption either spends it first or not at all
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&lot, lot.ID).Error; err != nil {
+				return err
+			}
+			if lot.Remaining == 0 {
+				return nil
+			}
+			entry := models.PointsEntry{
+				UserID:      lot.UserID,
+				Kind:        models.PointsExpired,
+				Points:      -lot.Remaining,
+				Reference:   "expire:" + strconv.FormatUint(uint64(lot.ID), 10),
+				Description: "Points earned on " + lot.CreatedAt.Format("2 Jan 2006") + " expired",
+			}
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+			expired++
+			return tx.Model(&lot).UpdateColumn("remaining", 0).Error
+		})
+		if err != nil {
+			return expired, err
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,186 @@
+package loyalty
+
+import (
+	"errors"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/testdb"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var now = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// credit adds a lot of points for user 1 expiring after the given number of days.
+func credit(t *testing.T, db *gorm.DB, reference string, points, days int) models.PointsEntry {
+	t.Helper()
+	expiresAt := now.AddDate(0, 0, days)
+	lot := models.PointsEntry{UserID: 1, Kind: models.PointsAdjustment, Points: points, ExpiresAt: &expiresAt, Reference: reference}
+	if ok, err := Credit(db, &lot); err != nil || !ok {
+		t.Fatalf("Credit(%s) = %v, %v", reference, ok, err)
+	}
+	return lot
+}
+
+// remaining returns the points left in each lot, by reference.
+func remaining(t *testing.T, db *gorm.DB) map[string]int {
+	t.Helper()
+	var lots []models.PointsEntry
+	if err := db.Where("expires_at IS NOT NULL").Find(&lots).Error; err != nil {
+		t.Fatal(err)
+	}
+	result := map[string]int{}
+	for _, lot := range lots {
+		result[lot.Reference] = lot.Remaining
+	}
+	return result
+}
+
+func redemption(reference string, points int) *models.PointsEntry {
+	return &models.PointsEntry{UserID: 1, Kind: models.PointsRedeemed, Points: -points, Reference: reference}
+}
+
+func TestCreditIsIdempotent(t *testing.T) {
+	db := testdb.New(t, &models.PointsEntry{}, &models.PointsLotUse{})
+	credit(t, db, "booking:1", 30, 10)
+	again := models.PointsEntry{UserID: 1, Kind: models.PointsBooking, Points: 30, ExpiresAt: &now, Reference: "booking:1"}
+	if ok, err := Credit(db, &again); err != nil || ok {
+		t.Errorf("second Credit() = %v, %v, want false", ok, err)
+	}
+	if balance, _ := Balance(db, 1, now); balance != 30 {
+		t.Errorf("Balance() = %d, want 30", balance)
+	}
+}
+
+func TestDebitUsesLotsExpiringFirst(t *testing.T) {
+	db := testdb.New(t, &models.PointsEntry{}, &models.PointsLotUse{})
+	credit(t, db, "late", 100, 300)
+	credit(t, db, "soon", 50, 10)
+	credit(t, db, "expired", 500, -1)
+
+	if err := Debit(db, redemption("redeem:1", 80), now); err != nil {
+		t.Fatalf("Debit() = %v", err)
+	}
+	want := map[string]int{"soon": 0, "late": 70, "expired": 500}
+	for reference, points := range remaining(t, db) {
+		if points != want[reference] {
+			t.Errorf("lot %s has %d points left, want %d", reference, points, want[reference])
+		}
+	}
+
+	var uses []models.PointsLotUse
+	db.Order("id").Find(&uses)
+	if len(uses) != 2 || uses[0].Points != 50 || uses[1].Points != 30 {
+		t.Errorf("lot uses = %+v, want 50 then 30 points", uses)
+	}
+	if balance, _ := Balance(db, 1, now); balance != 70 {
+		t.Errorf("Balance() = %d, want 70", balance)
+	}
+}
+
+func TestDebitInsufficientPoints(t *testing.T) {
+	db := testdb.New(t, &models.PointsEntry{}, &models.PointsLotUse{})
+	credit(t, db, "soon", 50, 10)
+	credit(t, db, "expired", 500, -1)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return Debit(tx, redemption("redeem:1", 80), now)
+	})
+	if !errors.Is(err, ErrInsufficientPoints) {
+		t.Fatalf("Debit() = %v, want ErrInsufficientPoints", err)
+	}
+	if left := remaining(t, db)["soon"]; left != 50 {
+		t.Errorf("lot has %d points left after the failed debit, want 50", left)
+	}
+	if err := Debit(db, &models.PointsEntry{UserID: 1, Points: 10, Reference: "redeem:2"}, now); !errors.Is(err, ErrInvalidRedemption) {
+		t.Errorf("Debit() of positive points = %v, want ErrInvalidRedemption", err)
+	}
+}
+
+func TestDebitIsIdempotent(t *testing.T) {
+	db := testdb.New(t, &models.PointsEntry{}, &models.PointsLotUse{})
+	credit(t, db, "lot", 100, 10)
+
+	for i := 0; i < 2; i++ {
+		if err := Debit(db, redemption("redeem:1", 40), now); err != nil {
+			t.Fatalf("Debit() #%d = %v", i+1, err)
+		}
+	}
+	if balance, _ := Balance(db, 1, now); balance != 60 {
+		t.Errorf("Balance() = %d, want 60", balance)
+	}
+	var entries int64
+	db.Model(&models.PointsEntry{}).Where("reference = ?", "redeem:1").Count(&entries)
+	if entries != 1 {
+		t.Errorf("%d debit entries, want 1", entries)
+	}
+}
+
+func TestRestoreReturnsPointsToTheirLots(t *testing.T) {
+	db := testdb.New(t, &models.PointsEntry{}, &models.PointsLotUse{})
+	soon := credit(t, db, "soon", 50, 10)
+	credit(t, db, "late", 100, 300)
+
+	debit := redemption("redeem:1", 80)
+	if err := Debit(db, debit, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first lot expires before the booking is cancelled: its points stay expired
+	later := soon.ExpiresAt.Add(time.Hour)
+	if _, err := Expire(db, later, 10); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		entry := &models.PointsEntry{UserID: 1, Kind: models.PointsRestored, Reference: "restore:1"}
+		restored, err := Restore(db, *debit, entry, later)
+		if err != nil {
+			t.Fatalf("Restore() #%d = %v", i+1, err)
+		}
+		want := 30
+		if i > 0 {
+			want = 0
+		}
+		if restored != want {
+			t.Errorf("Restore() #%d = %d points, want %d", i+1, restored, want)
+		}
+	}
+
+	var late models.PointsEntry
+	db.Where("reference = ?", "late").First(&late)
+	if late.Remaining != 100 || !late.ExpiresAt.Equal(now.AddDate(0, 0, 300)) {
+		t.Errorf("late lot has %d points expiring %v, want 100 expiring %v", late.Remaining, late.ExpiresAt, now.AddDate(0, 0, 300))
+	}
+	if balance, _ := Balance(db, 1, later); balance != 100 {
+		t.Errorf("Balance() = %d, want 100", balance)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	db := testdb.New(t, &models.PointsEntry{}, &models.PointsLotUse{})
+	credit(t, db, "expired", 40, -2)
+	credit(t, db, "also expired", 60, -1)
+	credit(t, db, "valid", 100, 10)
+	if err := Debit(db, redemption("redeem:1", 10), now.AddDate(0, 0, -3)); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := Expire(db, now, 10)
+	if err != nil || expired != 2 {
+		t.Fatalf("Expire() = %d, %v, want 2 lots", expired, err)
+	}
+	left := remaining(t, db)
+	if left["expired"] != 0 || left["also expired"] != 0 || left["valid"] != 100 {
+		t.Errorf("points left = %v, want only the valid lot's 100", left)
+	}
+
+	var sum int
+	db.Model(&models.PointsEntry{}).Where("kind = ?", models.PointsExpired).Select("COALESCE(SUM(points), 0)").Scan(&sum)
+	if sum != -90 {
+		t.Errorf("expired entries add up to %d, want -90", sum)
+	}
+	if again, err := Expire(db, now, 10); err != nil || again != 0 {
+		t.Errorf("second Expire() = %d, %v, want 0", again, err)
+	}
+}
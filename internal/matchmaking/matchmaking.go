@@ -0,0 +1,212 @@
+package matchmaking
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"sort"
+	"sparring-backend/internal/geo"
+	"time"
+)
+
+// ErrInvalidWindow is returned for availability windows that can't be parsed
+var ErrInvalidWindow = errors.New("availability windows need a weekday from 0 to 6 and HH:MM times with end after start")
+
+// Window is a weekly availability window in minutes since Sunday 00:00
+type Window struct {
+	Start int
+	End   int
+}
+
+// ParseWindow converts a weekday (0 = Sunday) and "HH:MM" bounds to a weekly window.
+// The end may be "24:00" for windows running until midnight.
+func ParseWindow(weekday int, startTime, endTime string) (Window, error) {
+	if weekday < 0 || weekday > 6 {
+		return Window{}, ErrInvalidWindow
+	}
+	start, err := parseClock(startTime)
+	if err != nil {
+		return Window{}, err
+	}
+	end, err := parseClock(endTime)
+	if err != nil {
+		return Window{}, err
+	}
+	if end <= start {
+		return Window{}, ErrInvalidWindow
+	}
+	day := weekday * 24 * 60
+	return Window{Start: day + start, End: day + end}, nil
+}
+
+// parseClock converts "HH:MM" to minutes since midnight.
+func parseClock(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, ErrInvalidWindow
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Profile describes a team for matchmaking
+type Profile struct {
+	TeamID  uint
+	Rating  int
+	Arenas  []geo.Point // Preferred arenas with known coordinates
+	Windows []Window    // Weekly availability
+}
+
+// Suggestion is a scored opponent
+type Suggestion struct {
+	TeamID         uint     `json:"team_id"`
+	Score          float64  `json:"score"` // From 0 to 1, higher is a better match
+	RatingGap      int      `json:"rating_gap"`
+	DistanceKm     *float64 `json:"distance_km"`     // Between the closest preferred arenas, nil when unknown
+	OverlapMinutes int      `json:"overlap_minutes"` // Weekly minutes both teams are available
+}
+
+// Weights balance the score components. They should add up to 1.
+type Weights struct {
+	Rating       float64
+	Distance     float64
+	Availability float64
+}
+
+// DefaultWeights favour skill proximity, then location and availability equally
+var DefaultWeights = Weights{Rating: 0.5, Distance: 0.25, Availability: 0.25}
+
+// Scale parameters: a rating gap of ratingScale or a distance of distanceScaleKm halves the component
+const (
+	ratingScale     = 200.0
+	distanceScaleKm = 10.0
+	unknownScore    = 0.5 // Used when either team lacks the data for a component
+)
+
+// Score rates how well the candidate fits the team as an opponent.
+func Score(team, candidate Profile, weights Weights) Suggestion {
+	suggestion := Suggestion{TeamID: candidate.TeamID}
+
+	gap := team.Rating - candidate.Rating
+	if gap < 0 {
+		gap = -gap
+	}
+	suggestion.RatingGap = gap
+	ratingScore := 1 / (1 + math.Pow(float64(gap)/ratingScale, 2))
+
+	distanceScore := unknownScore
+	if distance, ok := closestDistance(team.Arenas, candidate.Arenas); ok {
+		suggestion.DistanceKm = &distance
+		distanceScore = 1 / (1 + distance/distanceScaleKm)
+	}
+
+	availabilityScore := unknownScore
+	if len(team.Windows) > 0 && len(candidate.Windows) > 0 {
+		own, theirs := merge(team.Windows), merge(candidate.Windows)
+		suggestion.OverlapMinutes = overlap(own, theirs)
+		availabilityScore = float64(suggestion.OverlapMinutes) / float64(min(total(own), total(theirs)))
+	}
+
+	suggestion.Score = weights.Rating*ratingScore + weights.Distance*distanceScore + weights.Availability*availabilityScore
+	return suggestion
+}
+
+// Suggest returns the best limit candidates for the team, best first.
+// It keeps a bounded heap, so it runs in O(n log limit) over the candidates.
+func Suggest(team Profile, candidates []Profile, limit int, weights Weights) []Suggestion {
+	if limit <= 0 {
+		return nil
+	}
+
+	best := &suggestionHeap{}
+	for _, candidate := range candidates {
+		if candidate.TeamID == team.TeamID {
+			continue
+		}
+		suggestion := Score(team, candidate, weights)
+		if best.Len() < limit {
+			heap.Push(best, suggestion)
+		} else if suggestion.Score > (*best)[0].Score {
+			(*best)[0] = suggestion
+			heap.Fix(best, 0)
+		}
+	}
+
+	result := make([]Suggestion, best.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(best).(Suggestion)
+	}
+	return result
+}
+
+// closestDistance returns the distance between the closest pair of arenas.
+func closestDistance(a, b []geo.Point) (float64, bool) {
+	closest, found := math.MaxFloat64, false
+	for _, p := range a {
+		for _, q := range b {
+			if d := geo.DistanceKm(p, q); d < closest {
+				closest, found = d, true
+			}
+		}
+	}
+	return closest, found
+}
+
+// merge sorts windows and joins the overlapping ones.
+func merge(windows []Window) []Window {
+	sorted := append([]Window(nil), windows...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	merged := []Window{}
+	for _, w := range sorted {
+		if n := len(merged); n > 0 && w.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, w.End)
+			continue
+		}
+		merged = append(merged, w)
+	}
+	return merged
+}
+
+// overlap returns the minutes shared by two merged, sorted window lists.
+func overlap(a, b []Window) int {
+	minutes, i, j := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		start, end := max(a[i].Start, b[j].Start), min(a[i].End, b[j].End)
+		if end > start {
+			minutes += end - start
+		}
+		if a[i].End < b[j].End {
+			i++
+		} else {
+			j++
+		}
+	}
+	return minutes
+}
+
+// total returns the minutes covered by merged windows.
+func total(windows []Window) int {
+	minutes := 0
+	for _, w := range windows {
+		minutes += w.End - w.Start
+	}
+	return minutes
+}
+
+// suggestionHeap is a min-heap on score, holding the best suggestions seen so far
+type suggestionHeap []Suggestion
+
+func (h suggestionHeap) Len() int            { return len(h) }
+func (h suggestionHeap) Less(i, j int) bool  { return h[i].Score < h[j].Score }
+func (h suggestionHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *suggestionHeap) Push(x interface{}) { *h = append(*h, x.(Suggestion)) }
+func (h *suggestionHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,114 @@
+package matchmaking
+
+import (
+	"errors"
+	"math"
+	"sparring-backend/internal/geo"
+	"testing"
+)
+
+// tenKmNorth is 10 km north of the origin along the meridian
+var tenKmNorth = geo.Point{Lat: 10 / (6371.0 * math.Pi / 180)}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		weekday    int
+		start, end string
+		want       Window
+		err        error
+	}{
+		{0, "00:00", "24:00", Window{0, 1440}, nil},
+		{1, "18:30", "20:00", Window{1440 + 1110, 1440 + 1200}, nil},
+		{6, "23:00", "24:00", Window{6*1440 + 1380, 7 * 1440}, nil},
+		{7, "10:00", "12:00", Window{}, ErrInvalidWindow},
+		{-1, "10:00", "12:00", Window{}, ErrInvalidWindow},
+		{1, "12:00", "12:00", Window{}, ErrInvalidWindow},
+		{1, "14:00", "12:00", Window{}, ErrInvalidWindow},
+		{1, "9am", "12:00", Window{}, ErrInvalidWindow},
+		{1, "24:00", "24:00", Window{}, ErrInvalidWindow},
+	}
+	for _, tt := range tests {
+		got, err := ParseWindow(tt.weekday, tt.start, tt.end)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ParseWindow(%d, %q, %q) = %v, %v, want %v, %v", tt.weekday, tt.start, tt.end, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	monday := func(start, end int) Window { return Window{1440 + start*60, 1440 + end*60} }
+	origin := geo.Point{}
+
+	tests := []struct {
+		name          string
+		team, other   Profile
+		wantScore     float64
+		wantDistance  float64 // Negative when unknown
+		wantOverlap   int
+		wantRatingGap int
+	}{
+		{"perfect match", Profile{Rating: 1500, Arenas: []geo.Point{origin}, Windows: []Window{monday(18, 20)}},
+			Profile{Rating: 1500, Arenas: []geo.Point{origin}, Windows: []Window{monday(18, 20)}}, 1, 0, 120, 0},
+		{"unknown distance and availability count as half", Profile{Rating: 1500}, Profile{Rating: 1500}, 0.75, -1, 0, 0},
+		{"rating gap of the scale halves the rating score", Profile{Rating: 1600}, Profile{Rating: 1400}, 0.5, -1, 0, 200},
+		{"distance of the scale halves the distance score", Profile{Rating: 1500, Arenas: []geo.Point{origin}},
+			Profile{Rating: 1500, Arenas: []geo.Point{tenKmNorth}}, 0.5 + 0.125 + 0.125, 10, 0, 0},
+		{"closest pair of arenas counts", Profile{Rating: 1500, Arenas: []geo.Point{{Lat: 40, Lng: 40}, origin}},
+			Profile{Rating: 1500, Arenas: []geo.Point{{Lat: -40, Lng: -40}, tenKmNorth}}, 0.5 + 0.125 + 0.125, 10, 0, 0},
+		{"overlap relative to the shorter availability", Profile{Rating: 1500, Windows: []Window{monday(18, 20)}},
+			Profile{Rating: 1500, Windows: []Window{monday(19, 22)}}, 0.5 + 0.125 + 0.125, -1, 60, 0},
+		{"overlapping windows are merged", Profile{Rating: 1500, Windows: []Window{monday(18, 20), monday(19, 21)}},
+			Profile{Rating: 1500, Windows: []Window{monday(18, 21)}}, 0.5 + 0.125 + 0.25, -1, 180, 0},
+		{"no shared time", Profile{Rating: 1500, Windows: []Window{monday(8, 10)}},
+			Profile{Rating: 1500, Windows: []Window{monday(10, 12), {0, 60}}}, 0.5 + 0.125, -1, 0, 0},
+	}
+	for _, tt := range tests {
+		got := Score(tt.team, tt.other, DefaultWeights)
+		if math.Abs(got.Score-tt.wantScore) > 1e-6 {
+			t.Errorf("%s: score %v, want %v", tt.name, got.Score, tt.wantScore)
+		}
+		switch {
+		case tt.wantDistance < 0 && got.DistanceKm != nil:
+			t.Errorf("%s: distance %v, want unknown", tt.name, *got.DistanceKm)
+		case tt.wantDistance >= 0 && (got.DistanceKm == nil || math.Abs(*got.DistanceKm-tt.wantDistance) > 1e-6):
+			t.Errorf("%s: distance %v, want %v", tt.name, got.DistanceKm, tt.wantDistance)
+		}
+		if got.OverlapMinutes != tt.wantOverlap || got.RatingGap != tt.wantRatingGap {
+			t.Errorf("%s: overlap %d and rating gap %d, want %d and %d", tt.name, got.OverlapMinutes, got.RatingGap, tt.wantOverlap, tt.wantRatingGap)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	team := Profile{TeamID: 1, Rating: 1500}
+	candidates := []Profile{
+		{TeamID: 1, Rating: 1500}, // The team itself
+		{TeamID: 2, Rating: 1900},
+		{TeamID: 3, Rating: 1510},
+		{TeamID: 4, Rating: 1300},
+		{TeamID: 5, Rating: 1450},
+		{TeamID: 6, Rating: 2500},
+	}
+
+	tests := []struct {
+		limit int
+		want  []uint
+	}{
+		{0, nil},
+		{1, []uint{3}},
+		{3, []uint{3, 5, 4}},
+		{10, []uint{3, 5, 4, 2, 6}},
+	}
+	for _, tt := range tests {
+		got := Suggest(team, candidates, tt.limit, DefaultWeights)
+		if len(got) != len(tt.want) {
+			t.Errorf("limit %d: %d suggestions, want %d", tt.limit, len(got), len(tt.want))
+			continue
+		}
+		for i, suggestion := range got {
+			if suggestion.TeamID != tt.want[i] {
+				t.Errorf("limit %d: suggestion %d is team %d, want %d", tt.limit, i, suggestion.TeamID, tt.want[i])
+			}
+		}
+	}
+}
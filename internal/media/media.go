@@ -0,0 +1,144 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	// Register the decoders of the accepted formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+var (
+	ErrUnsupportedType = errors.New("only JPEG, PNG and GIF images are accepted")
+	ErrTooLarge        = errors.New("the image dimensions are too large")
+)
+
+// MaxPixels bounds the decoded size of an image, so a small but highly compressed
+// upload can't exhaust memory: 16 megapixels take 64MB once decoded as 8-bit RGBA,
+// and less for JPEG. Thumbnails are scaled from the decoded image without a copy.
+const MaxPixels = 16_000_000
+
+// ThumbnailSize is the longest side of generated thumbnails, in pixels
+const ThumbnailSize = 320
+
+// extensions maps the accepted content types to file extensions
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Image is a decoded upload
+type Image struct {
+	ContentType string // Sniffed from the data, never taken from the client
+	Extension   string
+	Width       int
+	Height      int
+	img         image.Image
+}
+
+// Decode sniffs the content type of the data and decodes the image, checking its
+// dimensions before allocating the pixels.
+func Decode(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	extension, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	return &Image{ContentType: contentType, Extension: extension, Width: config.Width, Height: config.Height, img: img}, nil
+}
+
+// Thumbnail returns a JPEG of the image scaled down to fit in a size by size square.
+// Smaller images keep their dimensions.
+func (i *Image) Thumbnail(size int) ([]byte, error) {
+	width, height := i.Width, i.Height
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/width)
+		} else {
+			width, height = max(1, width*size/height), size
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scale(i.img, width, height), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scale resizes with a box filter: each destination pixel averages the source pixels it covers.
+// Pixels are read straight from the decoded image, so only the thumbnail is allocated.
+func scale(src image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	pixel := pixelReader(src)
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, max((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, max((x+1)*srcWidth/width, x*srcWidth/width+1)
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb := pixel(bounds.Min.X+sx, bounds.Min.Y+sy)
+					r, g, b = r+uint64(pr), g+uint64(pg), b+uint64(pb)
+					n++
+				}
+			}
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8(r / n >> 8)
+			dst.Pix[offset+1] = uint8(g / n >> 8)
+			dst.Pix[offset+2] = uint8(b / n >> 8)
+			dst.Pix[offset+3] = 0xff
+		}
+	}
+	return dst
+}
+
+// pixelReader returns a function reading the 16-bit color of a pixel flattened onto white,
+// since JPEG has no alpha channel. JPEG and alpha PNG images get a fast path that doesn't
+// go through the color.Color interface.
+func pixelReader(img image.Image) func(x, y int) (r, g, b uint32) {
+	switch img := img.(type) {
+	case *image.YCbCr:
+		return func(x, y int) (uint32, uint32, uint32) {
+			c := img.YCbCrAt(x, y)
+			r, g, b := color.YCbCrToRGB(c.Y, c.Cb, c.Cr)
+			return uint32(r) * 0x101, uint32(g) * 0x101, uint32(b) * 0x101
+		}
+	case *image.NRGBA:
+		return func(x, y int) (uint32, uint32, uint32) {
+			return onWhite(img.NRGBAAt(x, y).RGBA())
+		}
+	default:
+		return func(x, y int) (uint32, uint32, uint32) {
+			return onWhite(img.At(x, y).RGBA())
+		}
+	}
+}
+
+// onWhite composites an alpha-premultiplied color over white.
+func onWhite(r, g, b, a uint32) (uint32, uint32, uint32) {
+	white := 0xffff - a
+	return r + white, g + white, b + white
+}
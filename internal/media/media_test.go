@@ -0,0 +1,95 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestThumbnail(t *testing.T) {
+	// Left half opaque red, right half fully transparent
+	src := image.NewNRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 400; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 0xff, A: 0xff})
+		}
+	}
+
+	img, err := Decode(encodePNG(t, src))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if img.ContentType != "image/png" || img.Width != 800 || img.Height != 400 {
+		t.Fatalf("Decode = %s %dx%d", img.ContentType, img.Width, img.Height)
+	}
+
+	data, err := img.Thumbnail(ThumbnailSize)
+	if err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if bounds := thumb.Bounds(); bounds.Dx() != 320 || bounds.Dy() != 160 {
+		t.Fatalf("thumbnail is %dx%d, want 320x160", bounds.Dx(), bounds.Dy())
+	}
+
+	near := func(got uint32, want uint8) bool {
+		diff := int(got>>8) - int(want)
+		return diff > -16 && diff < 16 // JPEG is lossy
+	}
+	if r, g, b, _ := thumb.At(40, 80).RGBA(); !near(r, 0xff) || !near(g, 0) || !near(b, 0) {
+		t.Errorf("opaque half is %d,%d,%d, want red", r>>8, g>>8, b>>8)
+	}
+	if r, g, b, _ := thumb.At(280, 80).RGBA(); !near(r, 0xff) || !near(g, 0xff) || !near(b, 0xff) {
+		t.Errorf("transparent half is %d,%d,%d, want white", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestThumbnailKeepsSmallImages(t *testing.T) {
+	img, err := Decode(encodePNG(t, image.NewGray(image.Rect(0, 0, 100, 50))))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	data, err := img.Thumbnail(ThumbnailSize)
+	if err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width != 100 || config.Height != 50 {
+		t.Errorf("thumbnail is %dx%d (%v), want 100x50", config.Width, config.Height, err)
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	if _, err := Decode([]byte("GIF89a")); err != ErrUnsupportedType {
+		t.Errorf("Decode of a broken GIF = %v, want ErrUnsupportedType", err)
+	}
+	if _, err := Decode([]byte("%PDF-1.4")); err != ErrUnsupportedType {
+		t.Errorf("Decode of a PDF = %v, want ErrUnsupportedType", err)
+	}
+
+	// Only the header is read to reject an image too large to decode
+	huge := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	huge[16], huge[17], huge[18], huge[19] = 0, 0, 0x13, 0x88 // Width 5000
+	huge[20], huge[21], huge[22], huge[23] = 0, 0, 0x13, 0x88 // Height 5000
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	if _, err := Decode(huge); err != ErrTooLarge {
+		t.Errorf("Decode of a 5000x5000 image = %v, want ErrTooLarge", err)
+	}
+}
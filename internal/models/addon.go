@@ -0,0 +1,31 @@
+package models
+
+import (
+	"sparring-backend/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// AddOn is equipment or an amenity an arena rents out with its bookings, e.g. balls, bibs or lockers
+type AddOn struct {
+	gorm.Model
+	ArenaID     uint        `gorm:"not null;index" json:"arena_id"` // Foreign key for Arena
+	Name        string      `gorm:"not null" json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `gorm:"embedded;embeddedPrefix:price_" json:"price"` // Per unit for the whole booking, or per hour with PerHour
+	PerHour     bool        `gorm:"not null;default:false" json:"per_hour"`      // Charge the price for every hour of the booking
+	Stock       int         `gorm:"not null;default:0" json:"stock"`             // Units that can be rented out at the same time
+	Active      bool        `gorm:"not null" json:"active"`                      // Inactive add-ons can't be attached to new bookings
+}
+
+// BookingAddOn is a quantity of an add-on reserved for a booking. The units are held
+// for the booking's slot while the booking is active.
+type BookingAddOn struct {
+	ID        uint        `gorm:"primarykey" json:"id"`
+	BookingID uint        `gorm:"not null;index" json:"booking_id"` // Foreign key for Booking
+	AddOnID   uint        `gorm:"not null;index" json:"add_on_id"`  // Foreign key for AddOn
+	AddOn     *AddOn      `gorm:"foreignKey:AddOnID" json:"add_on,omitempty"`
+	Quantity  int         `gorm:"not null" json:"quantity"`
+	UnitPrice money.Money `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"` // Price of one unit for the whole booking
+	Amount    money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+}
@@ -1,25 +1,48 @@
 package models
 
 import (
+	"sparring-backend/internal/money"
+	"time"
+
 	"gorm.io/gorm"
 )
 
 // Arena represents a sport arena where users can book fields
 type Arena struct {
 	gorm.Model
-	Name        string    `gorm:"not null" json:"name"`
-	Location    string    `gorm:"not null" json:"location"`
-	OwnerID     uint      `gorm:"not null" json:"owner_id"` // Foreign key for User (Owner)
-	Owner       User      `gorm:"foreignKey:OwnerID" json:"owner"`
-	Fields      []Field   `gorm:"foreignKey:ArenaID" json:"fields"`
-	Bookings    []Booking `gorm:"foreignKey:ArenaID" json:"bookings"`
+	Name          string       `gorm:"not null" json:"name"`
+	Location      string       `gorm:"not null" json:"location"`
+	Description   string       `gorm:"type:text" json:"description"`
+	Latitude      *float64     `json:"latitude"` // Nil when the arena hasn't been placed on the map
+	Longitude     *float64     `json:"longitude"`
+	Geohash       string       `gorm:"size:12;index" json:"-"`                       // Derived from the coordinates, indexed for proximity searches
+	Currency      string       `gorm:"size:3;not null;default:USD" json:"currency"`  // ISO 4217 code used for all prices in this arena
+	Timezone      string       `gorm:"size:64;not null;default:UTC" json:"timezone"` // IANA time zone, e.g. "Asia/Jakarta"
+	TaxName       string       `gorm:"size:32;not null;default:VAT" json:"tax_name"` // Label of the tax printed on invoices
+	TaxRateBps    int64        `gorm:"not null;default:0" json:"tax_rate_bps"`       // Tax included in prices, in basis points (1100 = 11%)
+	RatingAverage float64      `gorm:"not null;default:0" json:"rating_average"`     // Average of the visible reviews, kept up to date on every change
+	RatingCount   int          `gorm:"not null;default:0" json:"rating_count"`
+	CommissionBps *int64       `json:"commission_bps"`           // Platform commission on payments, nil for the platform default
+	OwnerID       uint         `gorm:"not null" json:"owner_id"` // Foreign key for User (Owner)
+	Owner         User         `gorm:"foreignKey:OwnerID" json:"owner"`
+	Fields        []Field      `gorm:"foreignKey:ArenaID" json:"fields"`
+	Bookings      []Booking    `gorm:"foreignKey:ArenaID" json:"bookings"`
+	Media         []ArenaMedia `gorm:"foreignKey:ArenaID" json:"media,omitempty"`
+}
+
+// Zone returns the arena time zone, falling back to UTC when unset or invalid.
+func (a *Arena) Zone() *time.Location {
+	if loc, err := time.LoadLocation(a.Timezone); err == nil && a.Timezone != "" {
+		return loc
+	}
+	return time.UTC
 }
 
 // Field represents a sport field inside an arena
 type Field struct {
 	gorm.Model
-	ArenaID    uint   `gorm:"not null" json:"arena_id"` // Foreign key for Arena
-	FieldName  string `gorm:"not null" json:"field_name"`
-	SportType  string `gorm:"not null" json:"sport_type"` // e.g., Soccer, Basketball, etc.
-	PricePerHr float64 `json:"price_per_hr"` // Price per hour for the field
-}
\ No newline at end of file
+	ArenaID    uint        `gorm:"not null" json:"arena_id"` // Foreign key for Arena
+	FieldName  string      `gorm:"not null" json:"field_name"`
+	SportType  string      `gorm:"not null" json:"sport_type"`                                // e.g., Soccer, Basketball, etc.
+	PricePerHr money.Money `gorm:"embedded;embeddedPrefix:price_per_hr_" json:"price_per_hr"` // Price per hour for the field
+}
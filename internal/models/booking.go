@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sparring-backend/internal/money"
 	"time"
 
 	"gorm.io/gorm"
@@ -9,14 +10,60 @@ import (
 // Booking represents a user's booking for a field in an arena
 type Booking struct {
 	gorm.Model
-	UserID      uint      `gorm:"not null" json:"user_id"` // Foreign key for User
-	User        User      `gorm:"foreignKey:UserID" json:"user"`
-	ArenaID     uint      `gorm:"not null" json:"arena_id"` // Foreign key for Arena
-	Arena       Arena     `gorm:"foreignKey:ArenaID" json:"arena"`
-	FieldID     uint      `gorm:"not null" json:"field_id"` // Foreign key for Field
-	Field       Field     `gorm:"foreignKey:FieldID" json:"field"`
-	BookingTime time.Time `gorm:"not null" json:"booking_time"` // Booking time for the field
-	Duration    int       `gorm:"not null" json:"duration"`     // Duration in hours
-	TotalAmount float64   `json:"total_amount"`                 // Total amount to be paid
-	Status      string    `gorm:"not null" json:"status"`       // "pending", "confirmed", "cancelled", etc.
+	UserID         uint           `gorm:"not null" json:"user_id"` // Foreign key for User
+	User           User           `gorm:"foreignKey:UserID" json:"user"`
+	TeamID         *uint          `gorm:"index" json:"team_id"`          // Team the booking was made for, visible to all its members
+	OpponentTeamID *uint          `gorm:"index" json:"opponent_team_id"` // Opposing team of a sparring match, also sees the booking
+	TournamentID   *uint          `gorm:"index" json:"tournament_id"`    // Tournament of a fixture booking, held by the arena owner
+	ArenaID        uint           `gorm:"not null" json:"arena_id"`      // Foreign key for Arena
+	Arena          Arena          `gorm:"foreignKey:ArenaID" json:"arena"`
+	FieldID        uint           `gorm:"not null" json:"field_id"` // Foreign key for Field
+	Field          Field          `gorm:"foreignKey:FieldID" json:"field"`
+	BookingTime    time.Time      `gorm:"not null" json:"booking_time"`                              // Booking time for the field
+	Duration       int            `gorm:"not null" json:"duration"`                                  // Duration in hours
+	TotalAmount    money.Money    `gorm:"embedded;embeddedPrefix:total_amount_" json:"total_amount"` // Total amount to be paid
+	PromoCodeID    *uint          `json:"promo_code_id"`                                             // Promo code applied, if any
+	Discount       money.Money    `gorm:"embedded;embeddedPrefix:discount_" json:"discount"`         // Discount granted by the promo code
+	PassID         *uint          `gorm:"index" json:"pass_id"`                                      // Pass or membership covering the field, if any
+	FreeHours      int            `gorm:"not null;default:0" json:"free_hours"`                      // Hours paid with loyalty points
+	Status         string         `gorm:"not null" json:"status"`                                    // "pending", "confirmed", "cancelled", etc.
+	CheckedInAt    *time.Time     `json:"checked_in_at"`                                             // When staff checked the customer in
+	CheckedInBy    *uint          `json:"checked_in_by"`                                             // Staff user who checked the customer in
+	Lines          []BookingLine  `gorm:"foreignKey:BookingID" json:"lines,omitempty"`               // Price breakdown
+	AddOns         []BookingAddOn `gorm:"foreignKey:BookingID" json:"add_ons,omitempty"`             // Equipment and amenities rented with the booking
+}
+
+// Booking statuses
+const (
+	BookingStatusPending   = "pending"
+	BookingStatusConfirmed = "confirmed"
+	BookingStatusCancelled = "cancelled"
+	BookingStatusRefunded  = "refunded"
+	BookingStatusCheckedIn = "checked_in"
+	BookingStatusCompleted = "completed"
+	BookingStatusNoShow    = "no_show"
+)
+
+// ActiveBookingStatuses are the statuses of bookings that hold their field
+var ActiveBookingStatuses = []string{
+	BookingStatusPending,
+	BookingStatusConfirmed,
+	BookingStatusCheckedIn,
+}
+
+// ActiveBookingsBetween scopes a query on bookings to the active ones overlapping the time
+// between start and end.
+func ActiveBookingsBetween(start, end time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("bookings.status IN ?", ActiveBookingStatuses).
+			Where("bookings.booking_time < ? AND DATE_ADD(bookings.booking_time, INTERVAL bookings.duration HOUR) > ?", end, start)
+	}
+}
+
+// CheckInNonce records a check-in code that has been used, so it can't be replayed
+type CheckInNonce struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Nonce     string    `gorm:"size:64;not null;uniqueIndex" json:"nonce"`
+	BookingID uint      `gorm:"not null;index" json:"booking_id"`
+	UsedAt    time.Time `json:"used_at"`
 }
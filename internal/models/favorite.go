@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Favorite is an arena, or a single field of it, a user keeps coming back to
+type Favorite struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"` // Foreign key for User
+	ArenaID   uint      `gorm:"not null" json:"arena_id"`      // Foreign key for Arena, also set for field favorites
+	Arena     *Arena    `gorm:"foreignKey:ArenaID" json:"arena,omitempty"`
+	FieldID   *uint     `json:"field_id"` // Set when a single field is favorited
+	Field     *Field    `gorm:"foreignKey:FieldID" json:"field,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SavedSearch stores search filters a user runs again later
+type SavedSearch struct {
+	gorm.Model
+	UserID    uint     `gorm:"not null;index" json:"user_id"` // Foreign key for User
+	Name      string   `gorm:"not null" json:"name"`
+	Query     string   `json:"query"` // Full-text query
+	SportType string   `json:"sport_type"`
+	Latitude  *float64 `json:"latitude"` // Center of a proximity search
+	Longitude *float64 `json:"longitude"`
+	RadiusKm  *float64 `json:"radius_km"`
+	MinRating *float64 `json:"min_rating"`
+}
+
+// FieldWatch asks for a notification when a favorited field frees up for a slot of
+// the given duration within a time window
+type FieldWatch struct {
+	gorm.Model
+	UserID     uint       `gorm:"not null;index" json:"user_id"` // Foreign key for User
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	FieldID    uint       `gorm:"not null;index" json:"field_id"` // Foreign key for Field
+	Field      Field      `gorm:"foreignKey:FieldID" json:"-"`
+	StartsAt   time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt     time.Time  `gorm:"not null;index" json:"ends_at"`
+	Duration   int        `gorm:"not null" json:"duration"`                // Hours
+	Available  bool       `gorm:"not null;default:false" json:"available"` // Whether a slot was free at the last check
+	NotifiedAt *time.Time `json:"notified_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"sparring-backend/internal/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invoice is the receipt issued when a booking is paid.
+// Numbers are sequential per arena, e.g. "INV-3-000042".
+type Invoice struct {
+	gorm.Model
+	ArenaID    uint          `gorm:"not null;uniqueIndex:idx_invoice_arena_sequence" json:"arena_id"` // Foreign key for Arena
+	Sequence   int           `gorm:"not null;uniqueIndex:idx_invoice_arena_sequence" json:"sequence"` // Per arena counter
+	Number     string        `gorm:"size:64;not null;uniqueIndex" json:"number"`
+	BookingID  uint          `gorm:"not null;uniqueIndex" json:"booking_id"` // Foreign key for Booking, one invoice per booking
+	Booking    Booking       `gorm:"foreignKey:BookingID" json:"-"`
+	UserID     uint          `gorm:"not null;index" json:"user_id"` // Customer billed
+	IssuedAt   time.Time     `gorm:"not null" json:"issued_at"`
+	Subtotal   money.Money   `gorm:"embedded;embeddedPrefix:subtotal_" json:"subtotal"` // Total excluding tax
+	TaxName    string        `json:"tax_name"`
+	TaxRateBps int64         `gorm:"not null;default:0" json:"tax_rate_bps"`
+	Tax        money.Money   `gorm:"embedded;embeddedPrefix:tax_" json:"tax"`
+	Total      money.Money   `gorm:"embedded;embeddedPrefix:total_" json:"total"` // Total including tax, as paid
+	Lines      []InvoiceLine `gorm:"foreignKey:InvoiceID" json:"lines"`
+}
+
+// InvoiceLine is a line item of an invoice, copied from the booking price breakdown
+type InvoiceLine struct {
+	gorm.Model
+	InvoiceID   uint        `gorm:"not null;index" json:"invoice_id"` // Foreign key for Invoice
+	Description string      `gorm:"not null" json:"description"`
+	Quantity    int         `gorm:"not null;default:1" json:"quantity"`
+	UnitPrice   money.Money `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+	Amount      money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// JobLease gives one replica the exclusive right to run a scheduled job until LockedUntil
+type JobLease struct {
+	Name        string     `gorm:"primarykey;size:100" json:"name"`
+	Owner       string     `gorm:"size:191;not null" json:"owner"` // Replica holding the lease
+	LockedUntil time.Time  `gorm:"not null" json:"locked_until"`
+	LastRunAt   *time.Time `json:"last_run_at"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"sparring-backend/internal/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LedgerTransaction is a balanced set of ledger postings recording one money movement,
+// such as a booking payment, a refund or a payout. Its Reference makes posting idempotent.
+type LedgerTransaction struct {
+	ID            uint            `gorm:"primarykey" json:"id"`
+	Reference     string          `gorm:"size:191;not null;uniqueIndex" json:"reference"` // e.g. "payment:12", "refund:evt_123", "payout:4"
+	Kind          string          `gorm:"size:32;not null;index" json:"kind"`
+	OwnerID       uint            `gorm:"not null;index" json:"owner_id"` // Arena owner the movement concerns
+	ArenaID       *uint           `gorm:"index" json:"arena_id"`
+	BookingID     *uint           `gorm:"index" json:"booking_id"`
+	PaymentID     *uint           `gorm:"index" json:"payment_id"`
+	PayoutID      *uint           `gorm:"index" json:"payout_id"`
+	CommissionBps int64           `gorm:"not null;default:0" json:"commission_bps"` // Commission rate applied to a booking payment
+	Description   string          `gorm:"not null" json:"description"`
+	OccurredAt    time.Time       `gorm:"not null;index" json:"occurred_at"`
+	Postings      []LedgerPosting `gorm:"foreignKey:TransactionID" json:"postings,omitempty"`
+}
+
+// LedgerPosting debits (positive amount) or credits (negative amount) an account.
+// The postings of a transaction add up to zero in every currency.
+type LedgerPosting struct {
+	ID            uint        `gorm:"primarykey" json:"id"`
+	TransactionID uint        `gorm:"not null;index" json:"transaction_id"` // Foreign key for LedgerTransaction
+	Account       string      `gorm:"size:32;not null;index:idx_ledger_account" json:"account"`
+	OwnerID       *uint       `gorm:"index:idx_ledger_account" json:"owner_id"` // Set on the accounts kept per owner
+	Amount        money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+}
+
+// Ledger transaction kinds
+const (
+	LedgerBookingPayment = "booking_payment"
+	LedgerRefund         = "refund"
+	LedgerPayout         = "payout"        // Balance moved out to a pending payout
+	LedgerPayoutSent     = "payout_sent"   // The transfer to the owner went through
+	LedgerPayoutFailed   = "payout_failed" // The transfer failed, the balance is owed again
+)
+
+// Payout is a transfer of an owner's balance in one currency, created by the payout batch job
+type Payout struct {
+	gorm.Model
+	OwnerID   uint        `gorm:"not null;index" json:"owner_id"` // Foreign key for User
+	Owner     User        `gorm:"foreignKey:OwnerID" json:"-"`
+	Amount    money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Status    string      `gorm:"not null;index" json:"status"` // "pending", "sent", "failed"
+	CutoffAt  time.Time   `gorm:"not null" json:"cutoff_at"`    // Revenue earned up to this time is included
+	Reference string      `json:"reference"`                    // Bank or provider transfer reference
+	SentAt    *time.Time  `json:"sent_at"`
+}
+
+// Payout statuses
+const (
+	PayoutPending = "pending"
+	PayoutSent    = "sent"
+	PayoutFailed  = "failed"
+)
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// PointsEntry is a movement of a user's loyalty points, kept as an audit of every change
+// to their balance. Points are positive when earned or given back and negative when
+// redeemed or expired. Positive entries are lots that expire on their own: Remaining
+// holds the points of the lot not redeemed or expired yet, oldest to expire used first.
+type PointsEntry struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"` // Foreign key for User
+	Kind        string     `gorm:"not null" json:"kind"`
+	Points      int        `gorm:"not null" json:"points"`
+	Remaining   int        `gorm:"not null;default:0" json:"remaining"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at"`                // Lots only
+	Reference   string     `gorm:"size:191;not null;uniqueIndex" json:"-"` // Makes awarding and redeeming idempotent, e.g. "booking:42"
+	BookingID   *uint      `gorm:"index" json:"booking_id"`
+	ReferralID  *uint      `json:"referral_id"`
+	CreatedByID *uint      `json:"created_by_id"` // Admin who made a manual adjustment
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// PointsLotUse records the points a debit took from a lot, so they can go back to the
+// same lot, with its expiry date, when the redemption is undone
+type PointsLotUse struct {
+	ID      uint `gorm:"primarykey" json:"id"`
+	EntryID uint `gorm:"not null;index" json:"entry_id"` // Debit entry, foreign key for PointsEntry
+	LotID   uint `gorm:"not null;index" json:"lot_id"`   // Lot the points came from, foreign key for PointsEntry
+	Points  int  `gorm:"not null" json:"points"`
+}
+
+// Points entry kinds
+const (
+	PointsBooking    = "booking"    // Earned on a completed booking
+	PointsReferral   = "referral"   // Earned when a referral books for the first time
+	PointsRedeemed   = "redeemed"   // Spent on a booking
+	PointsRestored   = "restored"   // Given back to their lots when a booking paid with points is cancelled
+	PointsExpired    = "expired"    // Lot left unused until it expired
+	PointsAdjustment = "adjustment" // Manual change by an admin
+)
+
+// Referral records a user who registered with someone else's referral code. Both earn
+// points once the referred user completes their first paid booking.
+type Referral struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	ReferrerID uint       `gorm:"not null;index" json:"referrer_id"`       // Foreign key for User
+	ReferredID uint       `gorm:"not null;uniqueIndex" json:"referred_id"` // Foreign key for User
+	Referred   User       `gorm:"foreignKey:ReferredID" json:"-"`
+	Status     string     `gorm:"not null" json:"status"` // "pending" or "rewarded"
+	RewardedAt *time.Time `json:"rewarded_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Referral statuses
+const (
+	ReferralPending  = "pending"
+	ReferralRewarded = "rewarded"
+)
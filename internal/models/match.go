@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MatchRequest is an open challenge posted by a team looking for a sparring opponent
+type MatchRequest struct {
+	gorm.Model
+	TeamID         uint                 `gorm:"not null;index" json:"team_id"` // Foreign key for Team (challenger)
+	Team           Team                 `gorm:"foreignKey:TeamID" json:"team"`
+	CreatedByID    uint                 `gorm:"not null" json:"created_by_id"` // Captain who posted the challenge, billed for the booking
+	SportType      string               `gorm:"not null;index" json:"sport_type"`
+	MinRating      int                  `gorm:"not null;default:0" json:"min_rating"` // Opponent skill range, 0 for no bound
+	MaxRating      int                  `gorm:"not null;default:0" json:"max_rating"`
+	Duration       int                  `gorm:"not null" json:"duration"` // Hours
+	Message        string               `json:"message"`
+	Status         string               `gorm:"not null;index" json:"status"`                 // "open", "matched", "cancelled"
+	Arenas         []Arena              `gorm:"many2many:match_request_arenas" json:"arenas"` // Preferred arenas, empty for any
+	Windows        []MatchRequestWindow `gorm:"foreignKey:MatchRequestID" json:"windows"`
+	OpponentTeamID *uint                `json:"opponent_team_id"` // Team that accepted the challenge
+	BookingID      *uint                `json:"booking_id"`       // Booking created on acceptance
+	MatchedAt      *time.Time           `json:"matched_at"`
+}
+
+// MatchRequestWindow is a time window in which the challenging team can play
+type MatchRequestWindow struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	MatchRequestID uint      `gorm:"not null;index" json:"match_request_id"` // Foreign key for MatchRequest
+	StartsAt       time.Time `gorm:"not null" json:"starts_at"`
+	EndsAt         time.Time `gorm:"not null" json:"ends_at"`
+}
+
+// Match request statuses
+const (
+	MatchRequestOpen      = "open"
+	MatchRequestMatched   = "matched"
+	MatchRequestCancelled = "cancelled"
+)
@@ -0,0 +1,19 @@
+package models
+
+import "gorm.io/gorm"
+
+// ArenaMedia is a photo of an arena, or of one of its fields when FieldID is set.
+// The files live in object storage; URLs are signed when the media is served.
+type ArenaMedia struct {
+	gorm.Model
+	ArenaID      uint   `gorm:"not null;index" json:"arena_id"`
+	FieldID      *uint  `gorm:"index" json:"field_id"`
+	Key          string `gorm:"size:255;not null" json:"-"` // Object storage key of the original
+	ThumbnailKey string `gorm:"size:255;not null" json:"-"`
+	ContentType  string `gorm:"size:64;not null" json:"content_type"`
+	Size         int64  `gorm:"not null" json:"size"` // In bytes
+	Width        int    `gorm:"not null" json:"width"`
+	Height       int    `gorm:"not null" json:"height"`
+	URL          string `gorm:"-" json:"url"`
+	ThumbnailURL string `gorm:"-" json:"thumbnail_url"`
+}
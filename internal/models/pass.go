@@ -0,0 +1,76 @@
+package models
+
+import (
+	"sparring-backend/internal/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PassPlan is a membership or a session pass an arena sells. Plans can't be edited once
+// created, only retired, so the passes already sold keep the terms they were sold with.
+type PassPlan struct {
+	gorm.Model
+	ArenaID           uint        `gorm:"not null;index" json:"arena_id"` // Foreign key for Arena
+	Name              string      `gorm:"not null" json:"name"`           // e.g., "Monthly membership", "10 sessions"
+	Kind              string      `gorm:"not null" json:"kind"`           // "membership" or "pass"
+	Price             money.Money `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+	ValidityDays      int         `gorm:"not null" json:"validity_days"`                // Days a pass stays valid from the day it starts
+	Credits           int         `gorm:"not null;default:0" json:"credits"`            // Bookings covered, 0 for unlimited (memberships only)
+	MaxDuration       int         `gorm:"not null;default:0" json:"max_duration"`       // Longest booking covered in hours, 0 for any
+	CancellationHours int         `gorm:"not null;default:0" json:"cancellation_hours"` // Credits come back for cancellations at least this long before the booking
+	DaysOfWeek        string      `json:"days_of_week"`                                 // Comma separated, 0 = Sunday; empty for every day
+	StartTime         string      `gorm:"size:5" json:"start_time"`                     // "HH:MM" in the arena time zone; empty for midnight
+	EndTime           string      `gorm:"size:5" json:"end_time"`                       // "HH:MM", may wrap past midnight; empty for midnight
+	Fields            []Field     `gorm:"many2many:pass_plan_fields" json:"fields"`     // Empty for every field of the arena
+	Active            bool        `gorm:"not null" json:"active"`                       // Retired plans can't be sold anymore
+}
+
+// Pass plan kinds. Active memberships also give member pricing at the arena.
+const (
+	PassKindMembership = "membership"
+	PassKindPass       = "pass"
+)
+
+// Pass is a membership or session pass held by a customer
+type Pass struct {
+	gorm.Model
+	PlanID           uint        `gorm:"not null;index" json:"plan_id"` // Foreign key for PassPlan
+	Plan             *PassPlan   `gorm:"foreignKey:PlanID" json:"plan,omitempty"`
+	ArenaID          uint        `gorm:"not null;index" json:"arena_id"` // Foreign key for Arena
+	UserID           uint        `gorm:"not null;index" json:"user_id"`  // Foreign key for User
+	User             User        `gorm:"foreignKey:UserID" json:"-"`
+	Kind             string      `gorm:"not null" json:"kind"`
+	Price            money.Money `gorm:"embedded;embeddedPrefix:price_" json:"price"` // Price paid at the arena
+	ValidFrom        time.Time   `gorm:"not null" json:"valid_from"`
+	ValidUntil       time.Time   `gorm:"not null;index" json:"valid_until"`      // Exclusive
+	Credits          int         `gorm:"not null;default:0" json:"credits"`      // Bookings covered, 0 for unlimited
+	CreditsUsed      int         `gorm:"not null;default:0" json:"credits_used"` // Net of restored credits
+	Status           string      `gorm:"not null" json:"status"`                 // "active" or "cancelled"
+	IssuedByID       uint        `gorm:"not null" json:"issued_by_id"`           // Owner or staff member who sold it
+	RemainingCredits int         `gorm:"-" json:"remaining_credits"`             // Set when the pass is returned, -1 for unlimited
+}
+
+// Pass statuses
+const (
+	PassStatusActive    = "active"
+	PassStatusCancelled = "cancelled"
+)
+
+// Remaining returns the credits left on the pass, or -1 when it is unlimited.
+func (p Pass) Remaining() int {
+	if p.Credits == 0 {
+		return -1
+	}
+	return max(p.Credits-p.CreditsUsed, 0)
+}
+
+// PassRedemption records a booking covered by a pass
+type PassRedemption struct {
+	ID         uint        `gorm:"primarykey" json:"id"`
+	PassID     uint        `gorm:"not null;index" json:"pass_id"`                 // Foreign key for Pass
+	BookingID  uint        `gorm:"not null;uniqueIndex" json:"booking_id"`        // Foreign key for Booking
+	Amount     money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"` // Field price the pass covered
+	RestoredAt *time.Time  `json:"restored_at"`                                   // When the credit came back after a cancellation
+	CreatedAt  time.Time   `json:"created_at"`
+}
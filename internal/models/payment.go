@@ -0,0 +1,39 @@
+package models
+
+import (
+	"sparring-backend/internal/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Payment represents a payment collected by a provider for a booking
+type Payment struct {
+	gorm.Model
+	BookingID   uint        `gorm:"not null;index" json:"booking_id"` // Foreign key for Booking
+	Booking     Booking     `gorm:"foreignKey:BookingID" json:"-"`
+	Provider    string      `gorm:"not null" json:"provider"`                          // e.g., "stripe", "midtrans"
+	ProviderRef string      `gorm:"size:191;not null;uniqueIndex" json:"provider_ref"` // Payment ID on the provider side
+	Amount      money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`     // Amount collected
+	Refunded    money.Money `gorm:"embedded;embeddedPrefix:refunded_" json:"refunded"` // Amount refunded so far
+	Status      string      `gorm:"not null" json:"status"`                            // "succeeded", "refunded", etc.
+	FlagReason  string      `json:"flag_reason,omitempty"`                             // Why a flagged payment didn't settle the booking
+}
+
+// PaymentEvent records a webhook event received from a payment provider.
+// The unique EventID is what makes webhook processing idempotent.
+type PaymentEvent struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	EventID     string    `gorm:"size:191;not null;uniqueIndex" json:"event_id"` // Event ID on the provider side
+	Type        string    `gorm:"not null" json:"type"`
+	BookingID   uint      `gorm:"index" json:"booking_id"`
+	Payload     string    `gorm:"type:text" json:"-"` // Raw body as received, for auditing
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// Payment statuses
+const (
+	PaymentStatusSucceeded = "succeeded"
+	PaymentStatusRefunded  = "refunded"
+	PaymentStatusFlagged   = "flagged" // Collected but not matching what was owed, to be refunded
+)
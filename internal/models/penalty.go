@@ -0,0 +1,24 @@
+package models
+
+import (
+	"sparring-backend/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// AccountPenalty is a fee charged to a user's account, e.g. for not showing up to a booking
+type AccountPenalty struct {
+	gorm.Model
+	UserID    uint        `gorm:"not null;index" json:"user_id"`          // Foreign key for User
+	BookingID uint        `gorm:"not null;uniqueIndex" json:"booking_id"` // Foreign key for Booking, at most one penalty per booking
+	Reason    string      `gorm:"not null" json:"reason"`
+	Amount    money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Settled   bool        `gorm:"not null;default:false" json:"settled"` // Charged to a later booking
+
+	ChargedBookingID *uint `gorm:"index" json:"charged_booking_id"` // Booking the penalty was added to, nil while outstanding
+}
+
+// Penalty reasons
+const (
+	PenaltyReasonNoShow = "no_show"
+)
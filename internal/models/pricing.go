@@ -0,0 +1,55 @@
+package models
+
+import (
+	"sparring-backend/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// PricingRule adjusts the hourly price of an arena's fields for matching hours.
+// A "rate" rule replaces the hourly price (the highest priority match wins), while
+// "adjustment" rules add a percentage on top of it (negative for discounts).
+type PricingRule struct {
+	gorm.Model
+	ArenaID       uint        `gorm:"not null;index" json:"arena_id"`         // Foreign key for Arena
+	FieldID       *uint       `gorm:"index" json:"field_id"`                  // Restrict to one field, nil for every field of the arena
+	Name          string      `gorm:"not null" json:"name"`                   // e.g., "Weekend evenings"
+	Kind          string      `gorm:"not null" json:"kind"`                   // "rate" or "adjustment"
+	Priority      int         `gorm:"not null;default:0" json:"priority"`     // Higher wins between rate rules
+	DaysOfWeek    string      `json:"days_of_week"`                           // Comma separated, 0 = Sunday; empty for every day
+	StartTime     string      `gorm:"size:5" json:"start_time"`               // "HH:MM" in the arena time zone; empty for midnight
+	EndTime       string      `gorm:"size:5" json:"end_time"`                 // "HH:MM", may wrap past midnight; empty for midnight
+	StartDate     string      `gorm:"size:10" json:"start_date"`              // "YYYY-MM-DD", inclusive; empty for no lower bound
+	EndDate       string      `gorm:"size:10" json:"end_date"`                // "YYYY-MM-DD", inclusive; empty for no upper bound
+	MinDuration   int         `gorm:"not null;default:0" json:"min_duration"` // Only applies to bookings of at least this many hours
+	MembersOnly   bool        `gorm:"not null;default:false" json:"members_only"`
+	Rate          money.Money `gorm:"embedded;embeddedPrefix:rate_" json:"rate"` // Hourly price for "rate" rules
+	AdjustmentBps int64       `gorm:"not null;default:0" json:"adjustment_bps"`  // Basis points for "adjustment" rules, -1000 = 10% off
+}
+
+// Pricing rule kinds
+const (
+	PricingRuleRate       = "rate"
+	PricingRuleAdjustment = "adjustment"
+)
+
+// BookingLine is one priced line of a booking, e.g. a single hour of field time
+type BookingLine struct {
+	gorm.Model
+	BookingID   uint        `gorm:"not null;index" json:"booking_id"` // Foreign key for Booking
+	Kind        string      `gorm:"not null" json:"kind"`             // "hour", etc.
+	Description string      `gorm:"not null" json:"description"`
+	Quantity    int         `gorm:"not null;default:1" json:"quantity"`
+	UnitPrice   money.Money `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+	Amount      money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+}
+
+// Booking line kinds
+const (
+	BookingLineHour     = "hour"
+	BookingLineAddOn    = "add_on"
+	BookingLineDiscount = "discount"
+	BookingLinePass     = "pass"
+	BookingLinePoints   = "points"
+	BookingLinePenalty  = "penalty"
+)
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"sparring-backend/internal/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PromoCode is a discount voucher customers can apply to a booking
+type PromoCode struct {
+	gorm.Model
+	Code           string      `gorm:"size:64;not null;uniqueIndex" json:"code"`      // Case-insensitive, stored upper-case
+	Kind           string      `gorm:"not null" json:"kind"`                          // "percentage" or "fixed"
+	PercentBps     int64       `gorm:"not null;default:0" json:"percent_bps"`         // Basis points for "percentage" codes, 1000 = 10% off
+	Amount         money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"` // Amount off for "fixed" codes
+	ValidFrom      *time.Time  `json:"valid_from"`                                    // Nil for no lower bound
+	ValidUntil     *time.Time  `json:"valid_until"`                                   // Nil for no upper bound
+	MaxUses        int         `gorm:"not null;default:0" json:"max_uses"`            // Total redemptions allowed, 0 for unlimited
+	MaxUsesPerUser int         `gorm:"not null;default:0" json:"max_uses_per_user"`   // Redemptions allowed per user, 0 for unlimited
+	UsedCount      int         `gorm:"not null;default:0" json:"used_count"`
+	SportTypes     string      `json:"sport_types"`                               // Comma separated, empty for every sport
+	Arenas         []Arena     `gorm:"many2many:promo_code_arenas" json:"arenas"` // Empty for every arena
+}
+
+// Promo code kinds
+const (
+	PromoKindPercentage = "percentage"
+	PromoKindFixed      = "fixed"
+)
+
+// PromoRedemption records a promo code applied to a booking
+type PromoRedemption struct {
+	gorm.Model
+	PromoCodeID uint        `gorm:"not null;index" json:"promo_code_id"` // Foreign key for PromoCode
+	UserID      uint        `gorm:"not null;index" json:"user_id"`       // Foreign key for User
+	BookingID   uint        `gorm:"not null;index" json:"booking_id"`    // Foreign key for Booking
+	Discount    money.Money `gorm:"embedded;embeddedPrefix:discount_" json:"discount"`
+}
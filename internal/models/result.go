@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MatchResult is the score of a sparring match between two teams. One captain reports it
+// and the captain of the other team confirms or disputes it; ratings only change once confirmed.
+type MatchResult struct {
+	gorm.Model
+	BookingID        uint       `gorm:"not null;uniqueIndex" json:"booking_id"` // Foreign key for Booking
+	HomeTeamID       uint       `gorm:"not null;index" json:"home_team_id"`     // Team the booking was made for
+	AwayTeamID       uint       `gorm:"not null;index" json:"away_team_id"`     // Opponent team of the booking
+	SportType        string     `gorm:"not null" json:"sport_type"`
+	HomeScore        int        `gorm:"not null" json:"home_score"`
+	AwayScore        int        `gorm:"not null" json:"away_score"`
+	ReportedByTeamID uint       `gorm:"not null" json:"reported_by_team_id"`
+	ReportedByID     uint       `gorm:"not null" json:"reported_by_id"` // Foreign key for User
+	Status           string     `gorm:"not null" json:"status"`         // "pending", "confirmed", "disputed"
+	DisputeReason    string     `json:"dispute_reason,omitempty"`
+	ConfirmedAt      *time.Time `json:"confirmed_at"`
+}
+
+// Match result statuses
+const (
+	MatchResultPending   = "pending"
+	MatchResultConfirmed = "confirmed"
+	MatchResultDisputed  = "disputed"
+)
+
+// RatingChange records how a confirmed result moved a team's rating
+type RatingChange struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	TeamID         uint      `gorm:"not null;index:idx_rating_change_team" json:"team_id"` // Foreign key for Team
+	MatchResultID  uint      `gorm:"not null;index" json:"match_result_id"`                // Foreign key for MatchResult
+	OpponentTeamID uint      `gorm:"not null" json:"opponent_team_id"`
+	SportType      string    `gorm:"not null" json:"sport_type"`
+	RatingBefore   int       `gorm:"not null" json:"rating_before"`
+	RatingAfter    int       `gorm:"not null" json:"rating_after"`
+	Delta          int       `gorm:"not null" json:"delta"`
+	CreatedAt      time.Time `gorm:"index:idx_rating_change_team" json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Review is a user's rating of an arena, or of one of its fields, after playing there
+type Review struct {
+	gorm.Model
+	ArenaID      uint       `gorm:"not null;uniqueIndex:idx_review_author" json:"arena_id"`    // Foreign key for Arena
+	FieldID      *uint      `gorm:"index" json:"field_id"`                                     // Set when the review is about a single field
+	FieldKey     uint       `gorm:"not null;default:0;uniqueIndex:idx_review_author" json:"-"` // FieldID, or 0 for the arena, as NULLs never collide in a unique index
+	UserID       uint       `gorm:"not null;uniqueIndex:idx_review_author" json:"user_id"`     // Foreign key for User
+	User         User       `gorm:"foreignKey:UserID" json:"-"`
+	Rating       int        `gorm:"not null" json:"rating"` // From 1 to 5
+	Text         string     `gorm:"type:text" json:"text"`
+	OwnerReply   string     `gorm:"type:text" json:"owner_reply,omitempty"`
+	RepliedAt    *time.Time `json:"replied_at,omitempty"`
+	Hidden       bool       `gorm:"not null;default:false" json:"hidden"` // Hidden by a moderator, excluded from listings and averages
+	HiddenReason string     `json:"hidden_reason,omitempty"`
+}
+
+// ReviewReport flags a review as abusive for the moderation queue
+type ReviewReport struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	ReviewID   uint       `gorm:"not null;uniqueIndex:idx_review_reporter" json:"review_id"` // Foreign key for Review
+	Review     Review     `gorm:"foreignKey:ReviewID" json:"review"`
+	ReporterID uint       `gorm:"not null;uniqueIndex:idx_review_reporter" json:"reporter_id"` // Foreign key for User
+	Reason     string     `gorm:"not null" json:"reason"`
+	Status     string     `gorm:"size:16;not null;index" json:"status"` // "open", "upheld", "dismissed"
+	ResolvedBy *uint      `json:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Review report statuses
+const (
+	ReportOpen      = "open"
+	ReportUpheld    = "upheld"    // The review was hidden
+	ReportDismissed = "dismissed" // The review stays visible
+)
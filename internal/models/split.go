@@ -0,0 +1,31 @@
+package models
+
+import (
+	"sparring-backend/internal/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentShare is the part of a split booking one team member has to pay.
+// The booking is confirmed once every share is paid or covered by the captain.
+type PaymentShare struct {
+	gorm.Model
+	BookingID      uint        `gorm:"not null;index" json:"booking_id"` // Foreign key for Booking
+	Booking        Booking     `gorm:"foreignKey:BookingID" json:"-"`
+	UserID         uint        `gorm:"not null;index" json:"user_id"` // Foreign key for User
+	User           User        `gorm:"foreignKey:UserID" json:"-"`
+	Amount         money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Status         string      `gorm:"not null" json:"status"` // "pending", "paid", "covered", "refunded"
+	PaymentID      *uint       `json:"payment_id"`             // Payment that settled or covered the share
+	PaidAt         *time.Time  `json:"paid_at"`
+	LastRemindedAt *time.Time  `json:"last_reminded_at"`
+}
+
+// Payment share statuses
+const (
+	PaymentSharePending  = "pending"
+	PaymentSharePaid     = "paid"
+	PaymentShareCovered  = "covered" // Taken over by the captain
+	PaymentShareRefunded = "refunded"
+)
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ArenaStaff gives a user access to a single arena with the listed permissions.
+// Removing someone from the staff deletes the row, so they can be invited again.
+type ArenaStaff struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	ArenaID     uint      `gorm:"not null;uniqueIndex:idx_arena_staff" json:"arena_id"` // Foreign key for Arena
+	Arena       *Arena    `gorm:"foreignKey:ArenaID" json:"arena,omitempty"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_arena_staff;index" json:"user_id"` // Foreign key for User
+	User        User      `gorm:"foreignKey:UserID" json:"-"`
+	Permissions string    `gorm:"not null" json:"permissions"` // Comma separated, e.g. "view_bookings,check_in"
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Staff permissions. Pricing, arena settings and the staff itself stay with the owner.
+const (
+	StaffViewBookings = "view_bookings" // See the arena's bookings and their invoices
+	StaffCheckIn      = "check_in"      // Check customers in at the desk
+	StaffManageAddOns = "manage_add_ons"
+	StaffSellPasses   = "sell_passes" // Issue and cancel memberships and passes
+)
+
+// StaffPermissions lists every staff permission
+var StaffPermissions = []string{StaffViewBookings, StaffCheckIn, StaffManageAddOns, StaffSellPasses}
+
+// Has reports whether the staff member was granted the permission.
+func (s ArenaStaff) Has(permission string) bool {
+	for _, granted := range strings.Split(s.Permissions, ",") {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// StaffInvitation invites someone, by email, to join the staff of an arena
+type StaffInvitation struct {
+	gorm.Model
+	ArenaID     uint      `gorm:"not null;index" json:"arena_id"` // Foreign key for Arena
+	Arena       Arena     `gorm:"foreignKey:ArenaID" json:"arena"`
+	Email       string    `gorm:"not null;index" json:"email"`
+	Permissions string    `gorm:"not null" json:"permissions"` // Granted on acceptance
+	Token       string    `gorm:"size:64;not null;uniqueIndex" json:"token"`
+	InvitedByID uint      `gorm:"not null" json:"invited_by_id"` // Foreign key for User
+	Status      string    `gorm:"not null" json:"status"`        // Same statuses as team invitations
+	ExpiresAt   time.Time `gorm:"not null" json:"expires_at"`
+}
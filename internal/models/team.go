@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Team is a group of players who book and play sparring matches together
+type Team struct {
+	gorm.Model
+	Name            string             `gorm:"not null" json:"name"`
+	SportType       string             `gorm:"not null;index:idx_team_sport_rating" json:"sport_type"`          // e.g., Soccer, Basketball, etc.
+	Rating          int                `gorm:"not null;default:1500;index:idx_team_sport_rating" json:"rating"` // Skill rating in the team's sport
+	Members         []TeamMember       `gorm:"foreignKey:TeamID" json:"members,omitempty"`
+	PreferredArenas []Arena            `gorm:"many2many:team_preferred_arenas" json:"preferred_arenas,omitempty"`
+	Availability    []TeamAvailability `gorm:"foreignKey:TeamID" json:"availability,omitempty"`
+}
+
+// TeamAvailability is a weekly window in which a team usually plays, used for matchmaking
+type TeamAvailability struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	TeamID    uint   `gorm:"not null;index" json:"team_id"`     // Foreign key for Team
+	Weekday   int    `gorm:"not null" json:"weekday"`           // 0 = Sunday
+	StartTime string `gorm:"size:5;not null" json:"start_time"` // "HH:MM" in the arenas' local time
+	EndTime   string `gorm:"size:5;not null" json:"end_time"`   // "HH:MM", "24:00" for midnight
+}
+
+// TeamMember links a user to a team with a role
+type TeamMember struct {
+	gorm.Model
+	TeamID uint   `gorm:"not null;uniqueIndex:idx_team_member" json:"team_id"` // Foreign key for Team
+	UserID uint   `gorm:"not null;uniqueIndex:idx_team_member" json:"user_id"` // Foreign key for User
+	User   User   `gorm:"foreignKey:UserID" json:"-"`
+	Role   string `gorm:"not null" json:"role"` // "captain" or "member"
+}
+
+// Team roles
+const (
+	TeamRoleCaptain = "captain"
+	TeamRoleMember  = "member"
+)
+
+// TeamInvitation invites a user to join a team. Invitations addressed to an email
+// can only be accepted by that user; invite links without an email can be used by anyone
+// until they expire or are revoked.
+type TeamInvitation struct {
+	gorm.Model
+	TeamID      uint      `gorm:"not null;index" json:"team_id"` // Foreign key for Team
+	Team        Team      `gorm:"foreignKey:TeamID" json:"team"`
+	Email       string    `gorm:"index" json:"email"` // Empty for invite links
+	Token       string    `gorm:"size:64;not null;uniqueIndex" json:"token"`
+	InvitedByID uint      `gorm:"not null" json:"invited_by_id"` // Foreign key for User
+	Status      string    `gorm:"not null" json:"status"`        // "pending", "accepted", "declined", "revoked"
+	ExpiresAt   time.Time `gorm:"not null" json:"expires_at"`
+}
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+)
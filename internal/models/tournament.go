@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tournament is an event hosted by an arena: a league (round robin) or a knockout bracket
+type Tournament struct {
+	gorm.Model
+	ArenaID            uint             `gorm:"not null;index" json:"arena_id"` // Foreign key for Arena
+	Arena              Arena            `gorm:"foreignKey:ArenaID" json:"-"`
+	Name               string           `gorm:"not null" json:"name"`
+	SportType          string           `gorm:"not null" json:"sport_type"`
+	Format             string           `gorm:"size:32;not null" json:"format"` // "round_robin", "single_elimination", "double_elimination"
+	StartsAt           time.Time        `gorm:"not null" json:"starts_at"`
+	MatchDuration      int              `gorm:"not null" json:"match_duration"`       // Hours
+	RoundIntervalHours int              `gorm:"not null" json:"round_interval_hours"` // Time between the starts of consecutive rounds
+	MaxTeams           int              `gorm:"not null" json:"max_teams"`
+	Status             string           `gorm:"not null;index" json:"status"` // "registration", "in_progress", "completed"
+	ChampionTeamID     *uint            `json:"champion_team_id"`
+	Teams              []TournamentTeam `gorm:"foreignKey:TournamentID" json:"teams,omitempty"`
+}
+
+// Tournament statuses
+const (
+	TournamentRegistration = "registration"
+	TournamentInProgress   = "in_progress"
+	TournamentCompleted    = "completed"
+)
+
+// TournamentTeam registers a team in a tournament
+type TournamentTeam struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	TournamentID uint      `gorm:"not null;uniqueIndex:idx_tournament_team" json:"tournament_id"` // Foreign key for Tournament
+	TeamID       uint      `gorm:"not null;uniqueIndex:idx_tournament_team" json:"team_id"`       // Foreign key for Team
+	Team         Team      `gorm:"foreignKey:TeamID" json:"team"`
+	Seed         int       `json:"seed"` // Assigned by rating when the tournament starts
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Fixture is a match of a tournament. Knockout fixtures link to the fixtures their
+// winner and loser move on to; teams are filled in as results are entered.
+type Fixture struct {
+	gorm.Model
+	TournamentID       uint     `gorm:"not null;index" json:"tournament_id"` // Foreign key for Tournament
+	Bracket            string   `gorm:"size:16;not null" json:"bracket"`     // "league", "winners", "losers", "final"
+	Round              int      `gorm:"not null" json:"round"`
+	Position           int      `gorm:"not null" json:"position"`
+	HomeTeamID         *uint    `json:"home_team_id"`
+	AwayTeamID         *uint    `json:"away_team_id"`
+	HomeScore          *int     `json:"home_score"`
+	AwayScore          *int     `json:"away_score"`
+	WinnerTeamID       *uint    `json:"winner_team_id"`
+	LoserTeamID        *uint    `json:"loser_team_id"`
+	BookingID          *uint    `json:"booking_id"` // Field booking once both teams are known
+	Booking            *Booking `gorm:"foreignKey:BookingID" json:"booking,omitempty"`
+	Status             string   `gorm:"size:16;not null" json:"status"` // "pending", "scheduled", "completed", "bye"
+	NextFixtureID      *uint    `json:"next_fixture_id"`
+	NextSlot           string   `gorm:"size:4" json:"next_slot,omitempty"` // "home" or "away"
+	LoserNextFixtureID *uint    `json:"loser_next_fixture_id"`
+	LoserNextSlot      string   `gorm:"size:4" json:"loser_next_slot,omitempty"`
+}
+
+// Fixture statuses
+const (
+	FixturePending   = "pending"
+	FixtureScheduled = "scheduled"
+	FixtureCompleted = "completed"
+	FixtureBye       = "bye"
+)
@@ -4,9 +4,12 @@ import "gorm.io/gorm"
 
 type User struct {
 	gorm.Model
-	Name         string `json:"name"`
-	Email        string `gorm:"unique" json:"email"`
-	Password     string `json:"password"`
-	Role         string `json:"role"` // Add role for access control
-	RefreshToken string `json:"-"`
+	Name          string  `json:"name"`
+	Email         string  `gorm:"unique" json:"email"`
+	Password      string  `json:"password"`
+	Role          string  `json:"role"` // Add role for access control
+	RefreshToken  string  `json:"-"`
+	NoShowCount   int     `gorm:"not null;default:0" json:"no_show_count"` // Confirmed bookings the user never showed up to
+	CalendarToken *string `gorm:"size:64;uniqueIndex" json:"-"`            // Secret of the calendar subscription feeds
+	ReferralCode  *string `gorm:"size:16;uniqueIndex" json:"-"`            // Shared with friends, who enter it when they register
 }
@@ -0,0 +1,50 @@
+package money
+
+import "strings"
+
+// DefaultCurrency is used for arenas that don't specify a currency
+const DefaultCurrency = "USD"
+
+// exponents maps ISO 4217 currency codes to their number of minor unit digits
+var exponents = map[string]int{
+	"AUD": 2,
+	"BHD": 3,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"IDR": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"MYR": 2,
+	"NZD": 2,
+	"OMR": 3,
+	"PHP": 2,
+	"SGD": 2,
+	"THB": 2,
+	"USD": 2,
+	"VND": 0,
+}
+
+// NormalizeCurrency upper-cases and trims a currency code.
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ValidCurrency reports whether the code is a supported ISO 4217 currency.
+func ValidCurrency(code string) bool {
+	_, ok := exponents[code]
+	return ok
+}
+
+// Exponent returns the number of minor unit digits for the currency (2 if unknown).
+func Exponent(code string) int {
+	if exp, ok := exponents[code]; ok {
+		return exp
+	}
+	return 2
+}
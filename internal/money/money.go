@@ -0,0 +1,238 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+// Money is an amount stored as integer minor units (e.g., cents) of an ISO 4217 currency.
+// Embed it in models with `gorm:"embedded;embeddedPrefix:<name>_"`.
+type Money struct {
+	Minor    int64  `gorm:"not null;default:0"` // Amount in minor units
+	Currency string `gorm:"size:3"`             // ISO 4217 code
+}
+
+// New returns an amount of minor units in the given currency.
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// Zero returns a zero amount in the given currency.
+func Zero(currency string) Money {
+	return Money{Currency: currency}
+}
+
+// Parse parses a decimal string such as "12.50" into the given currency.
+// Amounts with more decimal places than the currency allows are rejected rather than rounded.
+func Parse(s, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	exp := Exponent(currency)
+	if whole == "" || len(frac) > exp || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	// Right pad the fraction so "12.5" becomes 1250 cents
+	digits := whole + frac + strings.Repeat("0", exp-len(frac))
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// isDigits reports whether s only contains ASCII digits.
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithCurrency assigns a currency to an amount that was decoded without one,
+// rescaling it from the default two decimal places to the currency's exponent.
+func (m Money) WithCurrency(currency string) (Money, error) {
+	if m.Currency == currency {
+		return m, nil
+	}
+	if m.Currency != "" {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	minor := m.Minor
+	for exp := Exponent(""); exp < Exponent(currency); exp++ {
+		minor *= 10
+	}
+	for exp := Exponent(""); exp > Exponent(currency); exp-- {
+		if minor%10 != 0 {
+			return Money{}, ErrInvalidAmount // More decimal places than the currency allows
+		}
+		minor /= 10
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// Add returns m + o. Both amounts must share a currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Minor: m.Minor + o.Minor, Currency: m.Currency}, nil
+}
+
+// Sub returns m - o. Both amounts must share a currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Minor: m.Minor - o.Minor, Currency: m.Currency}, nil
+}
+
+// Mul multiplies the amount by an integer quantity (e.g., hours).
+func (m Money) Mul(n int64) Money {
+	return Money{Minor: m.Minor * n, Currency: m.Currency}
+}
+
+// Percent returns the given rate of the amount, expressed in basis points
+// (1250 = 12.5%), rounded half away from zero to the nearest minor unit.
+func (m Money) Percent(basisPoints int64) Money {
+	product := m.Minor * basisPoints
+	result := product / 10000
+	if remainder := product % 10000; remainder >= 5000 {
+		result++
+	} else if remainder <= -5000 {
+		result--
+	}
+	return Money{Minor: result, Currency: m.Currency}
+}
+
+// Allocate splits the amount into n parts that add up exactly to the original,
+// handing leftover minor units to the first parts.
+func (m Money) Allocate(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	parts := make([]Money, n)
+	share, remainder := m.Minor/int64(n), m.Minor%int64(n)
+	for i := range parts {
+		parts[i] = Money{Minor: share, Currency: m.Currency}
+		if int64(i) < remainder {
+			parts[i].Minor++
+		} else if int64(i) < -remainder {
+			parts[i].Minor--
+		}
+	}
+	return parts
+}
+
+// Neg returns the negated amount.
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+// IsNegative reports whether the amount is below zero.
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// Min returns the smaller of two amounts in the same currency.
+func (m Money) Min(o Money) Money {
+	if o.Minor < m.Minor {
+		return o
+	}
+	return m
+}
+
+// Decimal formats the amount as a decimal string, e.g. "12.50".
+func (m Money) Decimal() string {
+	exp := Exponent(m.Currency)
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	digits := strconv.FormatInt(minor, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// String formats the amount with its currency, e.g. "12.50 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// moneyJSON is the wire format of Money. The amount is a decimal string so
+// clients never round it through a float.
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as {"amount": "12.50", "currency": "USD"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the object form as well as a bare decimal string or number,
+// in which case the currency is left empty for the caller to fill in.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var wire moneyJSON
+	switch {
+	case len(data) > 0 && data[0] == '{':
+		var raw struct {
+			Amount   json.RawMessage `json:"amount"`
+			Currency string          `json:"currency"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		wire.Amount = strings.Trim(string(raw.Amount), `"`)
+		wire.Currency = NormalizeCurrency(raw.Currency)
+	default:
+		// Read numbers from their literal text, never through float64
+		wire.Amount = strings.Trim(string(data), `"`)
+	}
+
+	if wire.Currency != "" && !ValidCurrency(wire.Currency) {
+		return fmt.Errorf("unsupported currency %q", wire.Currency)
+	}
+	parsed, err := Parse(wire.Amount, wire.Currency)
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, wire.Amount)
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,155 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     int64
+		err      error
+	}{
+		{"12.50", "USD", 1250, nil},
+		{"12.5", "USD", 1250, nil},
+		{"12", "USD", 1200, nil},
+		{"12.", "USD", 1200, nil},
+		{" 0.01 ", "USD", 1, nil},
+		{"-3.25", "USD", -325, nil},
+		{"1500", "JPY", 1500, nil},
+		{"1.234", "KWD", 1234, nil},
+		{"12.345", "USD", 0, ErrInvalidAmount}, // Never rounded
+		{"1.5", "JPY", 0, ErrInvalidAmount},
+		{".5", "USD", 0, ErrInvalidAmount},
+		{"", "USD", 0, ErrInvalidAmount},
+		{"-", "USD", 0, ErrInvalidAmount},
+		{"1e3", "USD", 0, ErrInvalidAmount},
+		{"1,50", "USD", 0, ErrInvalidAmount},
+		{"99999999999999999999", "USD", 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, tt.currency)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q, %s) error = %v, want %v", tt.in, tt.currency, err, tt.err)
+			continue
+		}
+		if err == nil && (got.Minor != tt.want || got.Currency != tt.currency) {
+			t.Errorf("Parse(%q, %s) = %v, want %d %s", tt.in, tt.currency, got, tt.want, tt.currency)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		minor int64
+		bps   int64
+		want  int64
+	}{
+		{10000, 1000, 1000},
+		{1000, 1250, 125},
+		{5, 1000, 1},   // 0.5 rounds away from zero
+		{4, 1000, 0},   // 0.4 rounds down
+		{15, 3333, 5},  // 4.9995 rounds up
+		{-5, 1000, -1}, // -0.5 rounds away from zero
+		{-4, 1000, 0},  // -0.4 rounds towards zero
+		{1999, 5000, 1000},
+		{1000, -1000, -100},
+		{1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := New(tt.minor, "USD").Percent(tt.bps); got.Minor != tt.want || got.Currency != "USD" {
+			t.Errorf("New(%d).Percent(%d) = %d, want %d", tt.minor, tt.bps, got.Minor, tt.want)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		minor int64
+		n     int
+		want  []int64
+	}{
+		{1000, 4, []int64{250, 250, 250, 250}},
+		{1000, 3, []int64{334, 333, 333}},
+		{1001, 3, []int64{334, 334, 333}},
+		{2, 3, []int64{1, 1, 0}},
+		{-1000, 3, []int64{-334, -333, -333}},
+		{0, 2, []int64{0, 0}},
+		{1000, 1, []int64{1000}},
+		{1000, 0, nil},
+	}
+	for _, tt := range tests {
+		parts := New(tt.minor, "USD").Allocate(tt.n)
+		if len(parts) != len(tt.want) {
+			t.Errorf("New(%d).Allocate(%d) returned %d parts, want %d", tt.minor, tt.n, len(parts), len(tt.want))
+			continue
+		}
+		var sum int64
+		for i, part := range parts {
+			if part.Minor != tt.want[i] || part.Currency != "USD" {
+				t.Errorf("New(%d).Allocate(%d)[%d] = %v, want %d", tt.minor, tt.n, i, part, tt.want[i])
+			}
+			sum += part.Minor
+		}
+		if len(parts) > 0 && sum != tt.minor {
+			t.Errorf("New(%d).Allocate(%d) adds up to %d", tt.minor, tt.n, sum)
+		}
+	}
+}
+
+func TestWithCurrency(t *testing.T) {
+	tests := []struct {
+		in       Money
+		currency string
+		want     int64
+		err      error
+	}{
+		{New(1250, ""), "USD", 1250, nil},
+		{New(1250, "USD"), "USD", 1250, nil},
+		{New(1250, ""), "KWD", 12500, nil}, // Rescaled from 2 to 3 decimal places
+		{New(1500, ""), "JPY", 15, nil},    // Rescaled from 2 to 0 decimal places
+		{New(1550, ""), "JPY", 0, ErrInvalidAmount},
+		{New(1250, "EUR"), "USD", 0, ErrCurrencyMismatch},
+		{New(1250, "USD"), "", 0, ErrCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		got, err := tt.in.WithCurrency(tt.currency)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%v.WithCurrency(%s) error = %v, want %v", tt.in, tt.currency, err, tt.err)
+			continue
+		}
+		if err == nil && (got.Minor != tt.want || got.Currency != tt.currency) {
+			t.Errorf("%v.WithCurrency(%s) = %v, want %d %s", tt.in, tt.currency, got, tt.want, tt.currency)
+		}
+	}
+}
+
+func TestAddSubCurrencyMismatch(t *testing.T) {
+	usd, eur := New(100, "USD"), New(100, "EUR")
+	if _, err := usd.Add(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add across currencies error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+	if _, err := usd.Sub(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Sub across currencies error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{New(1250, "USD"), "12.50"},
+		{New(5, "USD"), "0.05"},
+		{New(-5, "USD"), "-0.05"},
+		{New(1500, "JPY"), "1500"},
+		{New(1, "KWD"), "0.001"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.Decimal(); got != tt.want {
+			t.Errorf("%d %s Decimal() = %q, want %q", tt.in.Minor, tt.in.Currency, got, tt.want)
+		}
+	}
+}
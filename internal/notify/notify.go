@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+// Message is a notification sent to a user
+type Message struct {
+	UserID  uint   // Recipient, 0 when only the email is known
+	Email   string // Recipient email
+	Subject string
+	Body    string
+}
+
+// Notifier delivers notifications to users (email, push, etc.)
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// LogNotifier writes notifications to the application log. It is the default
+// until a delivery channel is configured.
+type LogNotifier struct{}
+
+// Notify logs the message.
+func (LogNotifier) Notify(ctx context.Context, msg Message) error {
+	log.Printf("Notification to user %d <%s>: %s: %s", msg.UserID, msg.Email, msg.Subject, msg.Body)
+	return nil
+}
+
+// Default is the notifier used by the application
+var Default Notifier = LogNotifier{}
+
+// Send delivers a message with the default notifier, logging failures since
+// notifications must never fail the request that triggered them.
+func Send(ctx context.Context, msg Message) {
+	if err := Default.Notify(ctx, msg); err != nil {
+		log.Printf("Failed to send notification %q to user %d: %v", msg.Subject, msg.UserID, err)
+	}
+}
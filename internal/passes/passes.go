@@ -0,0 +1,124 @@
+package passes
+
+import (
+	"errors"
+	"fmt"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/pricing"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidPlan   = errors.New("invalid pass plan")
+	ErrNotActive     = errors.New("pass is not valid at the time of the booking")
+	ErrExhausted     = errors.New("pass has no credits left")
+	ErrNotApplicable = errors.New("pass does not cover this booking")
+)
+
+// Validate normalizes and checks a pass plan before it is saved.
+func Validate(plan *models.PassPlan, currency string) error {
+	plan.Name = strings.TrimSpace(plan.Name)
+	if plan.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPlan)
+	}
+
+	switch plan.Kind {
+	case models.PassKindMembership:
+		if plan.Credits < 0 {
+			return fmt.Errorf("%w: credits cannot be negative", ErrInvalidPlan)
+		}
+	case models.PassKindPass:
+		if plan.Credits <= 0 {
+			return fmt.Errorf("%w: passes need at least one credit", ErrInvalidPlan)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be %q or %q", ErrInvalidPlan, models.PassKindMembership, models.PassKindPass)
+	}
+
+	price, err := plan.Price.WithCurrency(currency)
+	if err != nil || price.IsNegative() {
+		return fmt.Errorf("%w: price must be a positive amount in %s", ErrInvalidPlan, currency)
+	}
+	plan.Price = price
+
+	if plan.ValidityDays <= 0 {
+		return fmt.Errorf("%w: validity_days must be at least one", ErrInvalidPlan)
+	}
+	if plan.MaxDuration < 0 || plan.CancellationHours < 0 {
+		return fmt.Errorf("%w: max_duration and cancellation_hours cannot be negative", ErrInvalidPlan)
+	}
+	if err := pricing.ValidateWindow(plan.DaysOfWeek, plan.StartTime, plan.EndTime); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+	}
+	return nil
+}
+
+// Issue creates an active pass of the plan for the user, valid for the plan's number
+// of days from the start of the day it starts in the arena time zone.
+func Issue(plan models.PassPlan, arena models.Arena, userID, issuedByID uint, from time.Time) models.Pass {
+	local := from.In(arena.Zone())
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	return models.Pass{
+		PlanID:     plan.ID,
+		ArenaID:    plan.ArenaID,
+		UserID:     userID,
+		Kind:       plan.Kind,
+		Price:      plan.Price,
+		ValidFrom:  start,
+		ValidUntil: start.AddDate(0, 0, plan.ValidityDays),
+		Credits:    plan.Credits,
+		Status:     models.PassStatusActive,
+		IssuedByID: issuedByID,
+	}
+}
+
+// Check reports whether the pass covers a booking of the field starting at start for
+// duration hours. The plan and its fields must be preloaded; every hour of the booking
+// must fall in the plan's days and times in the arena time zone.
+func Check(pass models.Pass, arena models.Arena, field models.Field, start time.Time, duration int) error {
+	if pass.Status != models.PassStatusActive || pass.ArenaID != field.ArenaID {
+		return ErrNotApplicable
+	}
+	end := start.Add(time.Duration(duration) * time.Hour)
+	if start.Before(pass.ValidFrom) || end.After(pass.ValidUntil) {
+		return ErrNotActive
+	}
+	if pass.Remaining() == 0 {
+		return ErrExhausted
+	}
+
+	plan := pass.Plan
+	if plan == nil {
+		return ErrNotApplicable
+	}
+	if plan.MaxDuration > 0 && duration > plan.MaxDuration {
+		return ErrNotApplicable
+	}
+	if len(plan.Fields) > 0 {
+		allowed := false
+		for _, f := range plan.Fields {
+			if f.ID == field.ID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return ErrNotApplicable
+		}
+	}
+
+	loc := arena.Zone()
+	for hour := 0; hour < duration; hour++ {
+		if !pricing.InWindow(plan.DaysOfWeek, plan.StartTime, plan.EndTime, start.Add(time.Duration(hour)*time.Hour).In(loc)) {
+			return ErrNotApplicable
+		}
+	}
+	return nil
+}
+
+// Restorable reports whether a cancellation at now gives the credit used for a booking
+// starting at start back to the pass.
+func Restorable(plan models.PassPlan, start, now time.Time) bool {
+	return !now.Add(time.Duration(plan.CancellationHours) * time.Hour).After(start)
+}
@@ -0,0 +1,103 @@
+package passes
+
+import (
+	"errors"
+	"sparring-backend/internal/models"
+	"testing"
+	"time"
+)
+
+// at returns the given hour on a Monday, 8 January 2024, or days later.
+func at(days, hour int) time.Time {
+	return time.Date(2024, time.January, 8+days, hour, 0, 0, 0, time.UTC)
+}
+
+func TestCheck(t *testing.T) {
+	arena := models.Arena{}
+	arena.ID = 1
+	field := models.Field{ArenaID: 1}
+	field.ID = 10
+	other := models.Field{ArenaID: 1}
+	other.ID = 11
+
+	plan := func(change func(*models.PassPlan)) *models.PassPlan {
+		p := &models.PassPlan{ArenaID: 1, Kind: models.PassKindPass, Credits: 5}
+		if change != nil {
+			change(p)
+		}
+		return p
+	}
+	pass := func(change func(*models.Pass)) models.Pass {
+		p := models.Pass{ArenaID: 1, Kind: models.PassKindPass, Credits: 5, Status: models.PassStatusActive,
+			ValidFrom: at(0, 0), ValidUntil: at(7, 0), Plan: plan(nil)}
+		if change != nil {
+			change(&p)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		pass     models.Pass
+		field    models.Field
+		start    time.Time
+		duration int
+		want     error
+	}{
+		{"covered", pass(nil), field, at(1, 18), 2, nil},
+		{"starts at valid_from", pass(nil), field, at(0, 0), 1, nil},
+		{"ends at valid_until", pass(nil), field, at(6, 23), 1, nil},
+		{"before valid_from", pass(nil), field, at(-1, 23), 1, ErrNotActive},
+		{"runs past valid_until", pass(nil), field, at(6, 23), 2, ErrNotActive},
+		{"exhausted", pass(func(p *models.Pass) { p.CreditsUsed = 5 }), field, at(1, 18), 1, ErrExhausted},
+		{"last credit", pass(func(p *models.Pass) { p.CreditsUsed = 4 }), field, at(1, 18), 1, nil},
+		{"unlimited", pass(func(p *models.Pass) { p.Credits, p.CreditsUsed = 0, 40 }), field, at(1, 18), 1, nil},
+		{"cancelled", pass(func(p *models.Pass) { p.Status = models.PassStatusCancelled }), field, at(1, 18), 1, ErrNotApplicable},
+		{"other arena", pass(func(p *models.Pass) { p.ArenaID = 2 }), field, at(1, 18), 1, ErrNotApplicable},
+		{"too long", pass(func(p *models.Pass) { p.Plan = plan(func(pl *models.PassPlan) { pl.MaxDuration = 1 }) }), field, at(1, 18), 2, ErrNotApplicable},
+		{"listed field", pass(func(p *models.Pass) { p.Plan = plan(func(pl *models.PassPlan) { pl.Fields = []models.Field{field} }) }), field, at(1, 18), 1, nil},
+		{"unlisted field", pass(func(p *models.Pass) { p.Plan = plan(func(pl *models.PassPlan) { pl.Fields = []models.Field{field} }) }), other, at(1, 18), 1, ErrNotApplicable},
+		{"off-peak hours", pass(func(p *models.Pass) {
+			p.Plan = plan(func(pl *models.PassPlan) { pl.StartTime, pl.EndTime = "09:00", "17:00" })
+		}), field, at(1, 15), 2, nil},
+		{"runs out of the window", pass(func(p *models.Pass) {
+			p.Plan = plan(func(pl *models.PassPlan) { pl.StartTime, pl.EndTime = "09:00", "17:00" })
+		}), field, at(1, 16), 2, ErrNotApplicable},
+		{"weekdays only on Saturday", pass(func(p *models.Pass) {
+			p.Plan = plan(func(pl *models.PassPlan) { pl.DaysOfWeek = "1,2,3,4,5" })
+		}), field, at(5, 10), 1, ErrNotApplicable},
+		{"plan not loaded", pass(func(p *models.Pass) { p.Plan = nil }), field, at(1, 18), 1, ErrNotApplicable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Check(tt.pass, arena, tt.field, tt.start, tt.duration); !errors.Is(err, tt.want) {
+				t.Errorf("Check() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestorable(t *testing.T) {
+	plan := models.PassPlan{CancellationHours: 24}
+	start := at(2, 18)
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"well before", at(0, 18), true},
+		{"exactly at the deadline", at(1, 18), true},
+		{"just after the deadline", at(1, 18).Add(time.Minute), false},
+		{"after the start", at(2, 19), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Restorable(plan, start, tt.now); got != tt.want {
+				t.Errorf("Restorable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if !Restorable(models.PassPlan{}, start, start) {
+		t.Error("Restorable() without cancellation hours = false at the start, want true")
+	}
+}
@@ -0,0 +1,85 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sparring-backend/internal/money"
+	"strings"
+)
+
+// Webhook event types sent by the payment provider
+const (
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentRefunded  = "payment.refunded"
+)
+
+// SignatureHeader is the request header carrying the webhook signature
+const SignatureHeader = "X-Payment-Signature"
+
+// WebhookEvent is the envelope of a webhook sent by the payment provider
+type WebhookEvent struct {
+	ID   string `json:"id"`   // Provider event ID, unique per event (retries reuse it)
+	Type string `json:"type"` // e.g., "payment.succeeded"
+	Data struct {
+		BookingID uint   `json:"booking_id"`
+		PaymentID string `json:"payment_id"`
+		Provider  string `json:"provider"`
+		Amount    int64  `json:"amount"`   // Minor units, as sent by the provider
+		Currency  string `json:"currency"` // ISO 4217 code
+		ShareID   uint   `json:"share_id"` // Payment share settled by the payment, for split bookings
+	} `json:"data"`
+}
+
+// Amount returns the event amount as Money.
+func (e *WebhookEvent) Amount() money.Money {
+	return money.New(e.Data.Amount, money.NormalizeCurrency(e.Data.Currency))
+}
+
+// Sign computes the hex encoded HMAC-SHA256 of the body using the given secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the signature header against the raw request body.
+// The header may be sent either as the bare hex digest or prefixed with "sha256=".
+func VerifySignature(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	signature = strings.TrimPrefix(signature, "sha256=")
+
+	expected, err := hex.DecodeString(Sign(secret, body))
+	if err != nil {
+		return false
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	// Constant time comparison to avoid leaking the signature
+	return hmac.Equal(expected, given)
+}
+
+// ParseWebhookEvent decodes and validates a webhook body.
+func ParseWebhookEvent(body []byte) (*WebhookEvent, error) {
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	if event.ID == "" {
+		return nil, errors.New("event id is missing")
+	}
+	if event.Data.BookingID == 0 {
+		return nil, errors.New("booking id is missing")
+	}
+	if event.Data.PaymentID == "" {
+		return nil, errors.New("payment id is missing")
+	}
+	return &event, nil
+}
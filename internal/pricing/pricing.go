@@ -0,0 +1,344 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxDuration is the longest booking in hours, a day
+const MaxDuration = 24
+
+var (
+	ErrInvalidDuration = errors.New("duration must be between 1 and 24 hours")
+	ErrInvalidRule     = errors.New("invalid pricing rule")
+	ErrInvalidAddOn    = errors.New("invalid add-on")
+	ErrInvalidQuantity = errors.New("add-on quantities must be at least one")
+)
+
+// HourQuote is the price of a single hour of field time
+type HourQuote struct {
+	StartsAt time.Time   `json:"starts_at"`
+	Rate     money.Money `json:"rate"`   // Hourly rate after the winning rate rule
+	Amount   money.Money `json:"amount"` // Rate after adjustments
+	Rules    []string    `json:"rules"`  // Names of the rules that applied
+}
+
+// Quote is the price of booking a field, broken down per hour
+type Quote struct {
+	FieldID  uint         `json:"field_id"`
+	StartsAt time.Time    `json:"starts_at"`
+	Duration int          `json:"duration"` // Hours
+	Hours    []HourQuote  `json:"hours"`
+	AddOns   []AddOnQuote `json:"add_ons,omitempty"`
+	Total    money.Money  `json:"total"`
+}
+
+// AddOnQuote is the price of a quantity of an add-on rented with a booking
+type AddOnQuote struct {
+	AddOnID   uint        `json:"add_on_id"`
+	Name      string      `json:"name"`
+	Quantity  int         `json:"quantity"`
+	UnitPrice money.Money `json:"unit_price"` // One unit for the whole booking
+	Amount    money.Money `json:"amount"`
+}
+
+// Options describe who is booking, for rules restricted to some customers
+type Options struct {
+	Member bool
+}
+
+// QuoteField prices a booking of the field starting at start for duration hours.
+// Rules are matched against each hour in the arena's time zone.
+func QuoteField(arena models.Arena, field models.Field, rules []models.PricingRule, start time.Time, duration int, opts Options) (*Quote, error) {
+	if duration <= 0 || duration > MaxDuration {
+		return nil, ErrInvalidDuration
+	}
+
+	loc := arena.Zone()
+	quote := &Quote{
+		FieldID:  field.ID,
+		StartsAt: start.In(loc),
+		Duration: duration,
+		Total:    money.Zero(arena.Currency),
+	}
+
+	for h := 0; h < duration; h++ {
+		slot := quote.StartsAt.Add(time.Duration(h) * time.Hour)
+		hour, err := quoteHour(arena, field, rules, slot, duration, opts)
+		if err != nil {
+			return nil, err
+		}
+		if quote.Total, err = quote.Total.Add(hour.Amount); err != nil {
+			return nil, err
+		}
+		quote.Hours = append(quote.Hours, hour)
+	}
+	return quote, nil
+}
+
+// quoteHour prices one hour: the highest priority rate rule sets the rate, then
+// every matching adjustment rule is applied on top of it.
+func quoteHour(arena models.Arena, field models.Field, rules []models.PricingRule, slot time.Time, duration int, opts Options) (HourQuote, error) {
+	hour := HourQuote{StartsAt: slot, Rate: field.PricePerHr, Rules: []string{}}
+
+	var rateRule *models.PricingRule
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Kind != models.PricingRuleRate || !Matches(rule, field.ID, slot, duration, opts) {
+			continue
+		}
+		if rateRule == nil || rule.Priority > rateRule.Priority {
+			rateRule = rule
+		}
+	}
+	if rateRule != nil {
+		hour.Rate = rateRule.Rate
+		hour.Rules = append(hour.Rules, rateRule.Name)
+	}
+	if hour.Rate.Currency != arena.Currency {
+		return HourQuote{}, money.ErrCurrencyMismatch
+	}
+
+	hour.Amount = hour.Rate
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Kind != models.PricingRuleAdjustment || !Matches(rule, field.ID, slot, duration, opts) {
+			continue
+		}
+		adjusted, err := hour.Amount.Add(hour.Rate.Percent(rule.AdjustmentBps))
+		if err != nil {
+			return HourQuote{}, err
+		}
+		hour.Amount = adjusted
+		hour.Rules = append(hour.Rules, rule.Name)
+	}
+
+	// Stacked discounts never make an hour free of charge below zero
+	if hour.Amount.IsNegative() {
+		hour.Amount = money.Zero(hour.Amount.Currency)
+	}
+	return hour, nil
+}
+
+// Matches reports whether the rule applies to the hour of field time starting at slot.
+// The slot must already be in the arena's time zone.
+func Matches(rule *models.PricingRule, fieldID uint, slot time.Time, duration int, opts Options) bool {
+	if rule.FieldID != nil && *rule.FieldID != fieldID {
+		return false
+	}
+	if rule.MembersOnly && !opts.Member {
+		return false
+	}
+	if duration < rule.MinDuration {
+		return false
+	}
+
+	date := slot.Format("2006-01-02")
+	if rule.StartDate != "" && date < rule.StartDate {
+		return false
+	}
+	if rule.EndDate != "" && date > rule.EndDate {
+		return false
+	}
+
+	return InWindow(rule.DaysOfWeek, rule.StartTime, rule.EndTime, slot)
+}
+
+// InWindow reports whether the slot falls on one of the days of week (comma separated,
+// 0 = Sunday, empty for every day) between the "HH:MM" bounds, which may wrap past midnight.
+// The slot must already be in the arena's time zone.
+func InWindow(daysOfWeek, startTime, endTime string, slot time.Time) bool {
+	if daysOfWeek != "" {
+		days, err := parseDays(daysOfWeek)
+		if err != nil || !days[slot.Weekday()] {
+			return false
+		}
+	}
+
+	start, end, err := parseBand(startTime, endTime)
+	if err != nil {
+		return false
+	}
+	minute := slot.Hour()*60 + slot.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	if start > end {
+		return minute >= start || minute < end // Band wraps past midnight
+	}
+	return true // Equal bounds cover the whole day
+}
+
+// ValidateWindow checks the days of week and time bounds accepted by InWindow.
+func ValidateWindow(daysOfWeek, startTime, endTime string) error {
+	if daysOfWeek != "" {
+		if _, err := parseDays(daysOfWeek); err != nil {
+			return err
+		}
+	}
+	_, _, err := parseBand(startTime, endTime)
+	return err
+}
+
+// ValidateRule normalizes and checks a pricing rule before it is saved.
+func ValidateRule(rule *models.PricingRule, currency string) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+
+	switch rule.Kind {
+	case models.PricingRuleRate:
+		rate, err := rule.Rate.WithCurrency(currency)
+		if err != nil || rate.IsNegative() {
+			return fmt.Errorf("%w: rate must be zero or a positive amount in %s", ErrInvalidRule, currency)
+		}
+		rule.Rate = rate
+	case models.PricingRuleAdjustment:
+		if rule.AdjustmentBps < -10000 {
+			return fmt.Errorf("%w: adjustment cannot exceed a 100%% discount", ErrInvalidRule)
+		}
+		rule.Rate = money.Zero(currency)
+	default:
+		return fmt.Errorf("%w: kind must be %q or %q", ErrInvalidRule, models.PricingRuleRate, models.PricingRuleAdjustment)
+	}
+
+	if err := ValidateWindow(rule.DaysOfWeek, rule.StartTime, rule.EndTime); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	for _, date := range []string{rule.StartDate, rule.EndDate} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			return fmt.Errorf("%w: dates must be formatted as YYYY-MM-DD", ErrInvalidRule)
+		}
+	}
+	if rule.StartDate != "" && rule.EndDate != "" && rule.EndDate < rule.StartDate {
+		return fmt.Errorf("%w: end date is before start date", ErrInvalidRule)
+	}
+	if rule.MinDuration < 0 {
+		return fmt.Errorf("%w: minimum duration cannot be negative", ErrInvalidRule)
+	}
+	return nil
+}
+
+// parseDays parses a comma separated list of weekdays (0 = Sunday).
+func parseDays(value string) (map[time.Weekday]bool, error) {
+	days := map[time.Weekday]bool{}
+	for _, part := range strings.Split(value, ",") {
+		day, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || day < 0 || day > 6 {
+			return nil, fmt.Errorf("invalid day of week %q", part)
+		}
+		days[time.Weekday(day)] = true
+	}
+	return days, nil
+}
+
+// parseBand converts "HH:MM" bounds to minutes since midnight. Empty bounds mean midnight.
+func parseBand(startTime, endTime string) (int, int, error) {
+	start, err := parseClock(startTime, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(endTime, 24*60)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+// parseClock converts "HH:MM" to minutes since midnight.
+func parseClock(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// QuoteAddOn prices quantity units of the add-on for a booking of duration hours.
+func QuoteAddOn(addOn models.AddOn, quantity, duration int) (AddOnQuote, error) {
+	if quantity <= 0 {
+		return AddOnQuote{}, ErrInvalidQuantity
+	}
+	unitPrice := addOn.Price
+	if addOn.PerHour {
+		unitPrice = unitPrice.Mul(int64(duration))
+	}
+	return AddOnQuote{
+		AddOnID:   addOn.ID,
+		Name:      addOn.Name,
+		Quantity:  quantity,
+		UnitPrice: unitPrice,
+		Amount:    unitPrice.Mul(int64(quantity)),
+	}, nil
+}
+
+// AddAddOn adds a priced add-on to the quote total.
+func (q *Quote) AddAddOn(addOn AddOnQuote) error {
+	total, err := q.Total.Add(addOn.Amount)
+	if err != nil {
+		return err
+	}
+	q.Total = total
+	q.AddOns = append(q.AddOns, addOn)
+	return nil
+}
+
+// BookingLine converts the add-on quote into a booking line.
+func (a AddOnQuote) BookingLine() models.BookingLine {
+	return models.BookingLine{
+		Kind:        models.BookingLineAddOn,
+		Description: a.Name,
+		Quantity:    a.Quantity,
+		UnitPrice:   a.UnitPrice,
+		Amount:      a.Amount,
+	}
+}
+
+// ValidateAddOn normalizes and checks an add-on before it is saved.
+func ValidateAddOn(addOn *models.AddOn, currency string) error {
+	addOn.Name = strings.TrimSpace(addOn.Name)
+	if addOn.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAddOn)
+	}
+	price, err := addOn.Price.WithCurrency(currency)
+	if err != nil || price.IsNegative() {
+		return fmt.Errorf("%w: price must be a positive amount in %s", ErrInvalidAddOn, currency)
+	}
+	addOn.Price = price
+	if addOn.Stock < 0 {
+		return fmt.Errorf("%w: stock cannot be negative", ErrInvalidAddOn)
+	}
+	return nil
+}
+
+// BookingLines converts the quote into booking lines, one per hour of field time
+// followed by one per add-on.
+func (q *Quote) BookingLines(fieldName string) []models.BookingLine {
+	lines := make([]models.BookingLine, 0, len(q.Hours)+len(q.AddOns))
+	for _, hour := range q.Hours {
+		description := fmt.Sprintf("%s, %s-%s", fieldName, hour.StartsAt.Format("Mon 2 Jan 2006 15:04"), hour.StartsAt.Add(time.Hour).Format("15:04"))
+		if len(hour.Rules) > 0 {
+			description += " (" + strings.Join(hour.Rules, ", ") + ")"
+		}
+		lines = append(lines, models.BookingLine{
+			Kind:        models.BookingLineHour,
+			Description: description,
+			Quantity:    1,
+			UnitPrice:   hour.Amount,
+			Amount:      hour.Amount,
+		})
+	}
+	for _, addOn := range q.AddOns {
+		lines = append(lines, addOn.BookingLine())
+	}
+	return lines
+}
@@ -0,0 +1,180 @@
+package pricing
+
+import (
+	"errors"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"testing"
+	"time"
+)
+
+// at returns the given hour and minute on a Monday, 8 January 2024, or days later.
+func at(days, hour, minute int) time.Time {
+	return time.Date(2024, time.January, 8+days, hour, minute, 0, 0, time.UTC)
+}
+
+func TestInWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		days       string
+		start, end string
+		slot       time.Time
+		want       bool
+	}{
+		{"inside band", "", "18:00", "22:00", at(0, 19, 0), true},
+		{"start is inclusive", "", "18:00", "22:00", at(0, 18, 0), true},
+		{"end is exclusive", "", "18:00", "22:00", at(0, 22, 0), false},
+		{"before band", "", "18:00", "22:00", at(0, 17, 59), false},
+		{"empty end means midnight", "", "18:00", "", at(0, 23, 30), true},
+		{"empty start means midnight", "", "", "08:00", at(0, 0, 0), true},
+		{"wraps before midnight", "", "22:00", "02:00", at(0, 23, 0), true},
+		{"wraps after midnight", "", "22:00", "02:00", at(0, 1, 0), true},
+		{"wrapped end is exclusive", "", "22:00", "02:00", at(0, 2, 0), false},
+		{"outside wrapped band", "", "22:00", "02:00", at(0, 12, 0), false},
+		{"equal bounds cover the day", "", "06:00", "06:00", at(0, 3, 0), true},
+		{"empty bounds cover the day", "", "", "", at(0, 15, 0), true},
+		{"matching day", "1", "", "", at(0, 12, 0), true},
+		{"sunday is zero", "0", "", "", at(6, 12, 0), true},
+		{"other day", "0,6", "", "", at(0, 12, 0), false},
+		{"day list with spaces", "5, 1", "", "", at(0, 12, 0), true},
+		{"day and band", "6", "08:00", "12:00", at(5, 9, 0), true},
+		{"day out of range", "7", "", "", at(0, 12, 0), false},
+		{"day not a number", "mon", "", "", at(0, 12, 0), false},
+		{"invalid time", "", "25:00", "", at(0, 12, 0), false},
+	}
+	for _, tt := range tests {
+		if got := InWindow(tt.days, tt.start, tt.end, tt.slot); got != tt.want {
+			t.Errorf("%s: InWindow(%q, %q, %q, %s) = %v, want %v", tt.name, tt.days, tt.start, tt.end, tt.slot.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	tests := []struct {
+		days, start, end string
+		valid            bool
+	}{
+		{"", "", "", true},
+		{"0,1,2,3,4,5,6", "22:00", "02:00", true},
+		{"", "06:00", "06:00", true},
+		{"7", "", "", false},
+		{"-1", "", "", false},
+		{"1,", "", "", false},
+		{"", "6pm", "", false},
+		{"", "", "24:00", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateWindow(tt.days, tt.start, tt.end); (err == nil) != tt.valid {
+			t.Errorf("ValidateWindow(%q, %q, %q) = %v, want valid %v", tt.days, tt.start, tt.end, err, tt.valid)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	fieldID, otherField := uint(1), uint(2)
+	tests := []struct {
+		name     string
+		rule     models.PricingRule
+		duration int
+		opts     Options
+		want     bool
+	}{
+		{"no restrictions", models.PricingRule{}, 1, Options{}, true},
+		{"same field", models.PricingRule{FieldID: &fieldID}, 1, Options{}, true},
+		{"other field", models.PricingRule{FieldID: &otherField}, 1, Options{}, false},
+		{"members only for a member", models.PricingRule{MembersOnly: true}, 1, Options{Member: true}, true},
+		{"members only for a guest", models.PricingRule{MembersOnly: true}, 1, Options{}, false},
+		{"long enough", models.PricingRule{MinDuration: 2}, 2, Options{}, true},
+		{"too short", models.PricingRule{MinDuration: 2}, 1, Options{}, false},
+		{"within dates", models.PricingRule{StartDate: "2024-01-08", EndDate: "2024-01-08"}, 1, Options{}, true},
+		{"before start date", models.PricingRule{StartDate: "2024-01-09"}, 1, Options{}, false},
+		{"after end date", models.PricingRule{EndDate: "2024-01-07"}, 1, Options{}, false},
+		{"wrapped band", models.PricingRule{StartTime: "20:00", EndTime: "01:00"}, 1, Options{}, true},
+		{"other day", models.PricingRule{DaysOfWeek: "0,6"}, 1, Options{}, false},
+	}
+	for _, tt := range tests {
+		if got := Matches(&tt.rule, fieldID, at(0, 21, 0), tt.duration, tt.opts); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  models.PricingRule
+		valid bool
+	}{
+		{"rate", models.PricingRule{Name: "Evenings", Kind: models.PricingRuleRate, Rate: money.New(5000, "USD")}, true},
+		{"free rate", models.PricingRule{Name: "Free", Kind: models.PricingRuleRate, Rate: money.Zero("USD")}, true},
+		{"negative rate", models.PricingRule{Name: "Evenings", Kind: models.PricingRuleRate, Rate: money.New(-1, "USD")}, false},
+		{"rate in another currency", models.PricingRule{Name: "Evenings", Kind: models.PricingRuleRate, Rate: money.New(5000, "EUR")}, false},
+		{"full discount", models.PricingRule{Name: "Free", Kind: models.PricingRuleAdjustment, AdjustmentBps: -10000}, true},
+		{"over full discount", models.PricingRule{Name: "Free", Kind: models.PricingRuleAdjustment, AdjustmentBps: -10001}, false},
+		{"missing name", models.PricingRule{Name: " ", Kind: models.PricingRuleAdjustment}, false},
+		{"unknown kind", models.PricingRule{Name: "Evenings", Kind: "fixed"}, false},
+		{"invalid day", models.PricingRule{Name: "Evenings", Kind: models.PricingRuleAdjustment, DaysOfWeek: "7"}, false},
+		{"invalid date", models.PricingRule{Name: "Evenings", Kind: models.PricingRuleAdjustment, StartDate: "08/01/2024"}, false},
+		{"dates reversed", models.PricingRule{Name: "Evenings", Kind: models.PricingRuleAdjustment, StartDate: "2024-02-01", EndDate: "2024-01-01"}, false},
+		{"negative duration", models.PricingRule{Name: "Evenings", Kind: models.PricingRuleAdjustment, MinDuration: -1}, false},
+	}
+	for _, tt := range tests {
+		err := ValidateRule(&tt.rule, "USD")
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: ValidateRule = %v, want valid %v", tt.name, err, tt.valid)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("%s: ValidateRule error %v is not ErrInvalidRule", tt.name, err)
+		}
+	}
+}
+
+func TestQuoteFieldWrappedBand(t *testing.T) {
+	arena := models.Arena{Currency: "USD"}
+	field := models.Field{PricePerHr: money.New(4000, "USD")}
+	field.ID = 1
+	rules := []models.PricingRule{
+		{Name: "Late night", Kind: models.PricingRuleRate, StartTime: "23:00", EndTime: "01:00", Rate: money.New(2000, "USD")},
+		{Name: "Weekend", Kind: models.PricingRuleAdjustment, DaysOfWeek: "0,6", AdjustmentBps: 1000},
+	}
+
+	// Saturday 22:00 to Sunday 01:00 crosses both midnight and the end of the band
+	quote, err := QuoteField(arena, field, rules, at(5, 22, 0), 3, Options{})
+	if err != nil {
+		t.Fatalf("QuoteField: %v", err)
+	}
+	want := []int64{4400, 2200, 2200}
+	for i, hour := range quote.Hours {
+		if hour.Amount.Minor != want[i] {
+			t.Errorf("hour %d costs %d, want %d", i, hour.Amount.Minor, want[i])
+		}
+	}
+	if quote.Total.Minor != 8800 {
+		t.Errorf("total is %d, want 8800", quote.Total.Minor)
+	}
+}
+
+func TestQuoteFieldDuration(t *testing.T) {
+	arena := models.Arena{Currency: "USD"}
+	field := models.Field{PricePerHr: money.New(4000, "USD")}
+	tests := []struct {
+		duration int
+		want     error
+	}{
+		{-1, ErrInvalidDuration},
+		{0, ErrInvalidDuration},
+		{1, nil},
+		{MaxDuration, nil},
+		{MaxDuration + 1, ErrInvalidDuration},
+		{2000000000, ErrInvalidDuration},
+	}
+	for _, tt := range tests {
+		quote, err := QuoteField(arena, field, nil, at(0, 10, 0), tt.duration, Options{})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("QuoteField(%d hours) = %v, want %v", tt.duration, err, tt.want)
+		}
+		if err == nil && len(quote.Hours) != tt.duration {
+			t.Errorf("QuoteField(%d hours) priced %d hours", tt.duration, len(quote.Hours))
+		}
+	}
+}
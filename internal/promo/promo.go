@@ -0,0 +1,113 @@
+package promo
+
+import (
+	"errors"
+	"fmt"
+	"sparring-backend/internal/models"
+	"sparring-backend/internal/money"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCode   = errors.New("invalid promo code")
+	ErrNotActive     = errors.New("promo code is not active")
+	ErrExhausted     = errors.New("promo code has been fully redeemed")
+	ErrUserLimit     = errors.New("you have already used this promo code the maximum number of times")
+	ErrNotApplicable = errors.New("promo code does not apply to this booking")
+)
+
+// NormalizeCode makes codes case-insensitive.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate normalizes and checks a promo code before it is saved.
+func Validate(code *models.PromoCode) error {
+	code.Code = NormalizeCode(code.Code)
+	if code.Code == "" {
+		return fmt.Errorf("%w: code is required", ErrInvalidCode)
+	}
+
+	switch code.Kind {
+	case models.PromoKindPercentage:
+		if code.PercentBps <= 0 || code.PercentBps > 10000 {
+			return fmt.Errorf("%w: percent_bps must be between 1 and 10000", ErrInvalidCode)
+		}
+	case models.PromoKindFixed:
+		code.Amount.Currency = money.NormalizeCurrency(code.Amount.Currency)
+		if !money.ValidCurrency(code.Amount.Currency) || code.Amount.Minor <= 0 {
+			return fmt.Errorf("%w: amount must be a positive amount with a currency", ErrInvalidCode)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be %q or %q", ErrInvalidCode, models.PromoKindPercentage, models.PromoKindFixed)
+	}
+
+	if code.ValidFrom != nil && code.ValidUntil != nil && code.ValidUntil.Before(*code.ValidFrom) {
+		return fmt.Errorf("%w: valid_until is before valid_from", ErrInvalidCode)
+	}
+	if code.MaxUses < 0 || code.MaxUsesPerUser < 0 {
+		return fmt.Errorf("%w: usage limits cannot be negative", ErrInvalidCode)
+	}
+	return nil
+}
+
+// Check reports whether the code can be redeemed now for a booking of the field,
+// given how many times the user already redeemed it.
+func Check(code models.PromoCode, field models.Field, now time.Time, userUses int) error {
+	if code.ValidFrom != nil && now.Before(*code.ValidFrom) {
+		return ErrNotActive
+	}
+	if code.ValidUntil != nil && now.After(*code.ValidUntil) {
+		return ErrNotActive
+	}
+	if code.MaxUses > 0 && code.UsedCount >= code.MaxUses {
+		return ErrExhausted
+	}
+	if code.MaxUsesPerUser > 0 && userUses >= code.MaxUsesPerUser {
+		return ErrUserLimit
+	}
+
+	// Arena restriction (the arenas association must be preloaded)
+	if len(code.Arenas) > 0 {
+		allowed := false
+		for _, arena := range code.Arenas {
+			if arena.ID == field.ArenaID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return ErrNotApplicable
+		}
+	}
+
+	// Sport restriction
+	if code.SportTypes != "" {
+		allowed := false
+		for _, sport := range strings.Split(code.SportTypes, ",") {
+			if strings.EqualFold(strings.TrimSpace(sport), field.SportType) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return ErrNotApplicable
+		}
+	}
+	return nil
+}
+
+// Discount computes the amount taken off the subtotal. It never exceeds the subtotal.
+func Discount(code models.PromoCode, subtotal money.Money) (money.Money, error) {
+	switch code.Kind {
+	case models.PromoKindPercentage:
+		return subtotal.Percent(code.PercentBps).Min(subtotal), nil
+	case models.PromoKindFixed:
+		if code.Amount.Currency != subtotal.Currency {
+			return money.Money{}, ErrNotApplicable
+		}
+		return code.Amount.Min(subtotal), nil
+	}
+	return money.Money{}, ErrInvalidCode
+}
@@ -0,0 +1,37 @@
+package rating
+
+import "math"
+
+// KFactor is the largest rating change a single result can cause
+const KFactor = 32
+
+// Match outcomes from the home team's point of view
+const (
+	Loss = 0.0
+	Draw = 0.5
+	Win  = 1.0
+)
+
+// Expected returns the expected score, from 0 to 1, of a team rated a against a team rated b.
+func Expected(a, b int) float64 {
+	return 1 / (1 + math.Pow(10, float64(b-a)/400))
+}
+
+// Outcome converts a final score to the home team's outcome.
+func Outcome(homeScore, awayScore int) float64 {
+	switch {
+	case homeScore > awayScore:
+		return Win
+	case homeScore < awayScore:
+		return Loss
+	default:
+		return Draw
+	}
+}
+
+// Update returns the new Elo ratings after a match with the given home outcome. The change is
+// rounded once and applied to both sides, so the sum of the ratings is preserved.
+func Update(home, away int, outcome float64) (int, int) {
+	delta := int(math.Round(KFactor * (outcome - Expected(home, away))))
+	return home + delta, away - delta
+}
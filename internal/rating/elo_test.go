@@ -0,0 +1,74 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExpected(t *testing.T) {
+	tests := []struct {
+		a, b int
+		want float64
+	}{
+		{1500, 1500, 0.5},
+		{1900, 1500, 10.0 / 11},
+		{1500, 1900, 1.0 / 11},
+		{2300, 1500, 100.0 / 101},
+		{1200, 1400, 1 / (1 + math.Sqrt(10))},
+	}
+	for _, tt := range tests {
+		if got := Expected(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Expected(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := Expected(tt.a, tt.b) + Expected(tt.b, tt.a); math.Abs(got-1) > 1e-9 {
+			t.Errorf("Expected(%d, %d) and Expected(%d, %d) sum to %v, want 1", tt.a, tt.b, tt.b, tt.a, got)
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		home, away int
+		want       float64
+	}{
+		{3, 1, Win},
+		{0, 2, Loss},
+		{2, 2, Draw},
+		{0, 0, Draw},
+	}
+	for _, tt := range tests {
+		if got := Outcome(tt.home, tt.away); got != tt.want {
+			t.Errorf("Outcome(%d, %d) = %v, want %v", tt.home, tt.away, got, tt.want)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name               string
+		home, away         int
+		outcome            float64
+		wantHome, wantAway int
+	}{
+		{"even win takes half the K-factor", 1500, 1500, Win, 1516, 1484},
+		{"even loss", 1500, 1500, Loss, 1484, 1516},
+		{"even draw changes nothing", 1500, 1500, Draw, 1500, 1500},
+		{"favourite win gains little", 1900, 1500, Win, 1903, 1497},
+		{"upset gains almost the K-factor", 1500, 1900, Win, 1529, 1871},
+		{"draw moves the underdog up", 1500, 1900, Draw, 1513, 1887},
+		{"huge gap win is rounded to nothing", 2800, 1000, Win, 2800, 1000},
+		{"huge gap upset is capped by the K-factor", 1000, 2800, Win, 1032, 2768},
+	}
+	for _, tt := range tests {
+		home, away := Update(tt.home, tt.away, tt.outcome)
+		if home != tt.wantHome || away != tt.wantAway {
+			t.Errorf("%s: Update(%d, %d, %v) = %d, %d, want %d, %d", tt.name, tt.home, tt.away, tt.outcome, home, away, tt.wantHome, tt.wantAway)
+		}
+		if home+away != tt.home+tt.away {
+			t.Errorf("%s: ratings sum %d, want %d", tt.name, home+away, tt.home+tt.away)
+		}
+		if delta := home - tt.home; delta > KFactor || delta < -KFactor {
+			t.Errorf("%s: rating moved by %d, more than the K-factor", tt.name, delta)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package reporting
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidRange  = errors.New("from and to must be YYYY-MM-DD dates, from not after to, at most 366 days apart")
+	ErrInvalidPeriod = errors.New("period must be day, week or month")
+)
+
+// DefaultDays is the length of the range reported when no dates are given
+const DefaultDays = 30
+
+// maxDays bounds a range, so a report never scans more than a year of bookings
+const maxDays = 366
+
+// Range is a half-open time range covering whole days in the arena time zone
+type Range struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ParseRange parses inclusive "YYYY-MM-DD" from and to dates in loc. Missing dates default
+// to the DefaultDays ending today.
+func ParseRange(from, to string, loc *time.Location, now time.Time) (Range, error) {
+	today := now.In(loc)
+	end := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	if to != "" {
+		day, err := time.ParseInLocation("2006-01-02", to, loc)
+		if err != nil {
+			return Range{}, ErrInvalidRange
+		}
+		end = day.AddDate(0, 0, 1)
+	}
+	start := end.AddDate(0, 0, -DefaultDays)
+	if from != "" {
+		day, err := time.ParseInLocation("2006-01-02", from, loc)
+		if err != nil {
+			return Range{}, ErrInvalidRange
+		}
+		start = day
+	}
+	if !start.Before(end) || start.AddDate(0, 0, maxDays).Before(end) {
+		return Range{}, ErrInvalidRange
+	}
+	return Range{Start: start, End: end}, nil
+}
+
+// Cell is one hour of the week in an occupancy heatmap
+type Cell struct {
+	Weekday       int     `json:"weekday"` // 0 = Sunday
+	Hour          int     `json:"hour"`    // Local hour of the day
+	BookedHours   int     `json:"booked_hours"`
+	CapacityHours int     `json:"capacity_hours"` // Field hours there were in the range
+	Rate          float64 `json:"rate"`
+}
+
+// Heatmap counts booked and available field hours per local weekday and hour
+type Heatmap struct {
+	loc   *time.Location
+	rng   Range
+	cells [7][24]Cell
+}
+
+// NewHeatmap returns an empty heatmap of the range for the given number of fields.
+// Every local hour of the range counts as available on every field.
+func NewHeatmap(rng Range, fields int, loc *time.Location) *Heatmap {
+	h := &Heatmap{loc: loc, rng: rng}
+	for weekday := range h.cells {
+		for hour := range h.cells[weekday] {
+			h.cells[weekday][hour] = Cell{Weekday: weekday, Hour: hour}
+		}
+	}
+	for t := rng.Start; t.Before(rng.End); t = t.Add(time.Hour) {
+		local := t.In(loc)
+		h.cells[local.Weekday()][local.Hour()].CapacityHours += fields
+	}
+	return h
+}
+
+// Add counts the hours of a booking that fall inside the range.
+func (h *Heatmap) Add(start time.Time, duration int) {
+	for i := 0; i < duration; i++ {
+		t := start.Add(time.Duration(i) * time.Hour)
+		if t.Before(h.rng.Start) || !t.Before(h.rng.End) {
+			continue
+		}
+		local := t.In(h.loc)
+		h.cells[local.Weekday()][local.Hour()].BookedHours++
+	}
+}
+
+// AddHours counts count bookings of duration hours starting at the local weekday and hour,
+// all of them inside the range.
+func (h *Heatmap) AddHours(weekday, hour, duration, count int) {
+	for i := 0; i < duration; i++ {
+		slot := (weekday*24 + hour + i) % (7 * 24)
+		h.cells[slot/24][slot%24].BookedHours += count
+	}
+}
+
+// Cells returns the 168 cells, Sunday midnight first, with their rates filled in.
+func (h *Heatmap) Cells() []Cell {
+	cells := make([]Cell, 0, 7*24)
+	for weekday := range h.cells {
+		for _, cell := range h.cells[weekday] {
+			cell.Rate = Rate(int64(cell.BookedHours), int64(cell.CapacityHours))
+			cells = append(cells, cell)
+		}
+	}
+	return cells
+}
+
+// Totals returns the booked and available field hours of the whole range.
+func (h *Heatmap) Totals() (booked, capacity int) {
+	for weekday := range h.cells {
+		for _, cell := range h.cells[weekday] {
+			booked += cell.BookedHours
+			capacity += cell.CapacityHours
+		}
+	}
+	return booked, capacity
+}
+
+// Rate returns part/total rounded to 4 decimals, 0 when total is 0.
+func Rate(part, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*10000) / 10000
+}
+
+// PeriodFormat returns the MySQL DATE_FORMAT pattern labelling a revenue period:
+// "2026-03-14" for days, "2026-W11" (ISO weeks) for weeks and "2026-03" for months.
+func PeriodFormat(period string) (string, error) {
+	switch period {
+	case "day":
+		return "%Y-%m-%d", nil
+	case "week":
+		return "%x-W%v", nil
+	case "month":
+		return "%Y-%m", nil
+	}
+	return "", ErrInvalidPeriod
+}
+
+// CSVCell prefixes a cell starting like a spreadsheet formula (=, +, -, @, tab or carriage
+// return) with a quote, so it's shown as text. Negative numbers are plain values and kept.
+func CSVCell(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		if value[0] == '-' && isNumber(value[1:]) {
+			return value
+		}
+		return "'" + value
+	}
+	return value
+}
+
+// isNumber reports whether value is an unsigned decimal number like "12" or "12.50".
+func isNumber(value string) bool {
+	if strings.Trim(value, "0123456789.") != "" {
+		return false
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
@@ -0,0 +1,73 @@
+package reporting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeatmapAddHoursMatchesAdd(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*3600)
+	rng, err := ParseRange("2026-03-01", "2026-03-14", loc, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saturday 22:00 local, running over midnight into Sunday
+	start := time.Date(2026, 3, 7, 22, 0, 0, 0, loc)
+
+	byBooking := NewHeatmap(rng, 2, loc)
+	byBooking.Add(start, 3)
+	byBooking.Add(start, 3)
+	bySlot := NewHeatmap(rng, 2, loc)
+	bySlot.AddHours(int(time.Saturday), 22, 3, 2)
+
+	want, got := byBooking.Cells(), bySlot.Cells()
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("cell %d/%d: got %+v, want %+v", want[i].Weekday, want[i].Hour, got[i], want[i])
+		}
+	}
+	if booked, _ := bySlot.Totals(); booked != 6 {
+		t.Errorf("booked = %d, want 6", booked)
+	}
+	if cell := got[0]; cell.BookedHours != 2 {
+		t.Errorf("Sunday 00:00 booked = %d, want 2", cell.BookedHours)
+	}
+}
+
+func TestHeatmapAddClipsToRange(t *testing.T) {
+	rng, err := ParseRange("2026-03-02", "2026-03-02", time.UTC, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHeatmap(rng, 1, time.UTC)
+	h.Add(time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC), 2) // One hour before the range
+	h.Add(time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC), 2) // One hour after it
+	booked, capacity := h.Totals()
+	if booked != 2 || capacity != 24 {
+		t.Errorf("Totals() = %d, %d, want 2, 24", booked, capacity)
+	}
+}
+
+func TestCSVCell(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"Alice", "Alice"},
+		{`=HYPERLINK("http://evil")`, `'=HYPERLINK("http://evil")`},
+		{"+1+1", "'+1+1"},
+		{"-1+cmd|' /C calc'!A0", "'-1+cmd|' /C calc'!A0"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"-12.50", "-12.50"},
+		{"-", "'-"},
+		{"-1e5", "'-1e5"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := CSVCell(tt.in); got != tt.want {
+			t.Errorf("CSVCell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
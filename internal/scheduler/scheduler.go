@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sparring-backend/internal/models"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Job is a task run periodically by the scheduler
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Local    bool // Runs on every replica without a lease, e.g. to refresh in-process state
+	Run      func(ctx context.Context, db *gorm.DB) error
+}
+
+// Scheduler runs jobs in-process. Before each run a replica must acquire the
+// job's lease in the database, so only one replica runs a job per interval.
+type Scheduler struct {
+	db    *gorm.DB
+	owner string
+	jobs  []Job
+	wg    sync.WaitGroup
+}
+
+// New creates a scheduler identified by the host name and process ID.
+func New(db *gorm.DB) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		db:    db,
+		owner: fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Register adds a job. Jobs must be registered before Start.
+func (s *Scheduler) Register(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start runs every registered job on its interval until the context is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+			for {
+				s.runOnce(ctx, job)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(job)
+	}
+}
+
+// Wait blocks until every job loop has stopped after the context was cancelled.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// runOnce runs the job if this replica can acquire its lease.
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	if job.Local {
+		if err := job.Run(ctx, s.db.WithContext(ctx)); err != nil {
+			log.Printf("Scheduler: job %s failed: %v", job.Name, err)
+		}
+		return
+	}
+
+	acquired, err := s.acquire(job)
+	if err != nil {
+		log.Printf("Scheduler: could not acquire lease for %s: %v", job.Name, err)
+		return
+	}
+	if !acquired {
+		return // Another replica runs this job
+	}
+
+	start := time.Now()
+	if err := job.Run(ctx, s.db.WithContext(ctx)); err != nil {
+		log.Printf("Scheduler: job %s failed: %v", job.Name, err)
+		return
+	}
+	s.db.Model(&models.JobLease{}).Where("name = ?", job.Name).Update("last_run_at", start)
+}
+
+// leaseIntervals is how many intervals a lease lasts. The owner renews it on every run, so it
+// must outlast an interval: a run that starts a little late or takes a while must not let
+// another replica take the job over. Other replicas only take over once a run was missed.
+const leaseIntervals = 2
+
+// acquire takes the job lease if it is free, expired or already ours.
+func (s *Scheduler) acquire(job Job) (bool, error) {
+	now := time.Now()
+
+	// Make sure the lease row exists, expired so the first replica can take it
+	lease := models.JobLease{Name: job.Name, Owner: s.owner, LockedUntil: now.Add(-time.Second)}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&lease).Error; err != nil {
+		return false, err
+	}
+
+	// The conditional update is atomic, so only one replica can win an expired lease
+	result := s.db.Model(&models.JobLease{}).
+		Where("name = ? AND (locked_until < ? OR owner = ?)", job.Name, now, s.owner).
+		Updates(map[string]interface{}{"owner": s.owner, "locked_until": now.Add(leaseIntervals * job.Interval)})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
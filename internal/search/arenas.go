@@ -0,0 +1,52 @@
+package search
+
+import (
+	"context"
+	"sparring-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ArenaDocument builds the search document of an arena with its fields loaded.
+func ArenaDocument(arena models.Arena) Document {
+	doc := Document{
+		ArenaID:     arena.ID,
+		Name:        arena.Name,
+		Location:    arena.Location,
+		Description: arena.Description,
+	}
+	for _, field := range arena.Fields {
+		doc.SportTypes = append(doc.SportTypes, field.SportType)
+		doc.FieldNames = append(doc.FieldNames, field.FieldName)
+	}
+	return doc
+}
+
+// Refresh brings the index up to date with the database. An in-memory index is rebuilt off
+// to the side and swapped in, which also drops arenas deleted through other replicas.
+// Changes indexed while the new copy is being built are picked up by the next refresh.
+func Refresh(ctx context.Context, index Index, db *gorm.DB) error {
+	memory, ok := index.(*MemoryIndex)
+	if !ok {
+		return Rebuild(ctx, index, db)
+	}
+	fresh := NewMemoryIndex()
+	if err := Rebuild(ctx, fresh, db); err != nil {
+		return err
+	}
+	memory.replace(fresh)
+	return nil
+}
+
+// Rebuild indexes every arena of the database, in batches.
+func Rebuild(ctx context.Context, index Index, db *gorm.DB) error {
+	var arenas []models.Arena
+	return db.Preload("Fields").FindInBatches(&arenas, 500, func(tx *gorm.DB, batch int) error {
+		for _, arena := range arenas {
+			if err := index.Upsert(ctx, ArenaDocument(arena)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
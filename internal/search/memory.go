@@ -0,0 +1,256 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Weights of the document parts, a match in the name counts the most
+const (
+	weightName        = 3.0
+	weightSport       = 2.0
+	weightFieldName   = 1.5
+	weightLocation    = 1.5
+	weightDescription = 1.0
+)
+
+// Match qualities relative to an exact term match
+const (
+	qualityExact  = 1.0
+	qualityPrefix = 0.8
+	qualityFuzzy  = 0.6
+)
+
+// MemoryIndex is an embedded inverted index with prefix matching and typo tolerance.
+// Candidate terms for a query token are found through the trigrams they share with it.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[uint]float64 // Term to the best weight of the term in each arena
+	trigrams map[string]map[string]bool  // Trigram to the terms containing it
+	docTerms map[uint][]string           // Terms of each arena, to remove them on update or delete
+}
+
+// NewMemoryIndex returns an empty in-memory index.
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		postings: map[string]map[uint]float64{},
+		trigrams: map[string]map[string]bool{},
+		docTerms: map[uint][]string{},
+	}
+}
+
+// Upsert indexes the document, replacing any earlier version of the arena.
+func (m *MemoryIndex) Upsert(ctx context.Context, doc Document) error {
+	weights := map[string]float64{}
+	add := func(text string, weight float64) {
+		for _, term := range tokenize(text) {
+			if weight > weights[term] {
+				weights[term] = weight
+			}
+		}
+	}
+	add(doc.Name, weightName)
+	add(doc.Location, weightLocation)
+	add(doc.Description, weightDescription)
+	for _, sport := range doc.SportTypes {
+		add(sport, weightSport)
+	}
+	for _, name := range doc.FieldNames {
+		add(name, weightFieldName)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(doc.ArenaID)
+	terms := make([]string, 0, len(weights))
+	for term, weight := range weights {
+		if m.postings[term] == nil {
+			m.postings[term] = map[uint]float64{}
+			for _, trigram := range trigramsOf(term) {
+				if m.trigrams[trigram] == nil {
+					m.trigrams[trigram] = map[string]bool{}
+				}
+				m.trigrams[trigram][term] = true
+			}
+		}
+		m.postings[term][doc.ArenaID] = weight
+		terms = append(terms, term)
+	}
+	m.docTerms[doc.ArenaID] = terms
+	return nil
+}
+
+// Delete removes the arena from the index.
+func (m *MemoryIndex) Delete(ctx context.Context, arenaID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(arenaID)
+	return nil
+}
+
+// replace swaps in the contents of another index, which must not be used afterwards.
+func (m *MemoryIndex) replace(other *MemoryIndex) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.postings, m.trigrams, m.docTerms = other.postings, other.trigrams, other.docTerms
+}
+
+// remove drops the arena's postings and the terms no other arena uses. The lock must be held.
+func (m *MemoryIndex) remove(arenaID uint) {
+	for _, term := range m.docTerms[arenaID] {
+		delete(m.postings[term], arenaID)
+		if len(m.postings[term]) > 0 {
+			continue
+		}
+		delete(m.postings, term)
+		for _, trigram := range trigramsOf(term) {
+			delete(m.trigrams[trigram], term)
+			if len(m.trigrams[trigram]) == 0 {
+				delete(m.trigrams, trigram)
+			}
+		}
+	}
+	delete(m.docTerms, arenaID)
+}
+
+// Search scores arenas by the best match of each query token, weighted by where it matched.
+// Arenas matching more of the query tokens rank first.
+func (m *MemoryIndex) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	tokens := tokenize(query)
+	if len(tokens) == 0 || limit <= 0 {
+		return nil, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	scores := map[uint]float64{}
+	matched := map[uint]int{}
+	for _, token := range tokens {
+		best := map[uint]float64{}
+		for term := range m.candidates(token) {
+			quality := matchQuality(token, term)
+			if quality == 0 {
+				continue
+			}
+			for arenaID, weight := range m.postings[term] {
+				if score := quality * weight; score > best[arenaID] {
+					best[arenaID] = score
+				}
+			}
+		}
+		for arenaID, score := range best {
+			scores[arenaID] += score
+			matched[arenaID]++
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for arenaID, score := range scores {
+		hits = append(hits, Hit{ArenaID: arenaID, Score: score * float64(matched[arenaID]) / float64(len(tokens))})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if matched[hits[i].ArenaID] != matched[hits[j].ArenaID] {
+			return matched[hits[i].ArenaID] > matched[hits[j].ArenaID]
+		}
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ArenaID < hits[j].ArenaID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// candidates returns the indexed terms sharing at least one trigram with the token. The lock must be held.
+func (m *MemoryIndex) candidates(token string) map[string]bool {
+	terms := map[string]bool{}
+	for _, trigram := range trigramsOf(token) {
+		for term := range m.trigrams[trigram] {
+			terms[term] = true
+		}
+	}
+	return terms
+}
+
+// matchQuality rates how well an indexed term matches a query token: exactly, as a prefix
+// of the term, or within the edit distance allowed for the token length. Zero means no match.
+func matchQuality(token, term string) float64 {
+	switch {
+	case token == term:
+		return qualityExact
+	case strings.HasPrefix(term, token):
+		return qualityPrefix
+	}
+
+	allowed := 0
+	switch n := len([]rune(token)); {
+	case n >= 8:
+		allowed = 2
+	case n >= 4:
+		allowed = 1
+	}
+	if allowed > 0 && editDistance(token, term, allowed) <= allowed {
+		return qualityFuzzy
+	}
+	return 0
+}
+
+// tokenize lowercases the text and splits it into words of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// trigramsOf returns the trigrams of the term padded at both ends, so that short
+// tokens and prefixes share the leading trigrams of the terms they start.
+func trigramsOf(term string) []string {
+	runes := []rune("$$" + term + "$")
+	trigrams := make([]string, 0, len(runes)-2)
+	for i := 0; i+3 <= len(runes); i++ {
+		trigrams = append(trigrams, string(runes[i:i+3]))
+	}
+	return trigrams
+}
+
+// editDistance returns the Damerau-Levenshtein distance between a and b (optimal string
+// alignment), or limit+1 as soon as it is known to exceed limit.
+func editDistance(a, b string, limit int) int {
+	s, t := []rune(a), []rune(b)
+	if diff := len(s) - len(t); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(t)]
+}
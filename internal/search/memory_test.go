@@ -0,0 +1,157 @@
+package search
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"futsal", "futsal", 2, 0},
+		{"futsal", "futsl", 2, 1},
+		{"futsal", "futsla", 2, 1}, // Transposition
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 1, 2}, // Stops once over the limit
+		{"abcd", "a", 1, 2},
+		{"", "ab", 2, 2},
+		{"bádminton", "badminton", 1, 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestMatchQuality(t *testing.T) {
+	tests := []struct {
+		token, term string
+		want        float64
+	}{
+		{"futsal", "futsal", qualityExact},
+		{"fut", "futsal", qualityPrefix},
+		{"futsl", "futsal", qualityFuzzy},
+		{"fot", "futsal", 0},    // Too short for typos
+		{"fotsol", "futsal", 0}, // Two typos need eight letters
+		{"badmintn", "badminton", qualityFuzzy},
+		{"bdmintn", "badminton", 0}, // Seven letters allow a single typo
+		{"padel", "futsal", 0},
+	}
+	for _, tt := range tests {
+		if got := matchQuality(tt.token, tt.term); got != tt.want {
+			t.Errorf("matchQuality(%q, %q) = %v, want %v", tt.token, tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Futsal-Center, Jl. Sudirman 21 (Jakarta)")
+	want := []string{"futsal", "center", "jl", "sudirman", "21", "jakarta"}
+	if len(got) != len(want) {
+		t.Fatalf("tokenize = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("tokenize = %q, want %q", got, want)
+			break
+		}
+	}
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryIndex()
+	docs := []Document{
+		{ArenaID: 1, Name: "Futsal Center", Location: "Jakarta", SportTypes: []string{"Futsal"}, FieldNames: []string{"Court A"}},
+		{ArenaID: 2, Name: "Senayan Arena", Location: "Jakarta Pusat", Description: "Indoor futsal and badminton",
+			SportTypes: []string{"Badminton"}, FieldNames: []string{"Hall 1"}},
+		{ArenaID: 3, Name: "Bandung Badminton Hall", Location: "Bandung", SportTypes: []string{"Badminton"}},
+	}
+	for _, doc := range docs {
+		if err := index.Upsert(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []uint
+	}{
+		{"name beats description", "futsal", 10, []uint{1, 2}},
+		{"typo", "futsl", 10, []uint{1, 2}},
+		{"typo in a long word", "badmintn", 10, []uint{3, 2}},
+		{"prefix ties break on ID", "jak", 10, []uint{1, 2}},
+		{"case and punctuation are ignored", "BANDUNG!", 10, []uint{3}},
+		{"more matched tokens rank first", "badminton jakarta", 10, []uint{2, 3, 1}},
+		{"limit", "badminton", 1, []uint{3}},
+		{"no typos in short tokens", "fot", 10, nil},
+		{"no match", "padel", 10, nil},
+		{"empty query", " ", 10, nil},
+		{"zero limit", "futsal", 0, nil},
+	}
+	for _, tt := range tests {
+		hits, err := index.Search(ctx, tt.query, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameArenas(hits, tt.want) {
+			t.Errorf("%s: Search(%q) = %v, want arenas %v", tt.name, tt.query, hits, tt.want)
+		}
+	}
+
+	// Exact name matches score their full weight, typos less
+	exact, _ := index.Search(ctx, "futsal", 1)
+	typo, _ := index.Search(ctx, "futsl", 1)
+	if math.Abs(exact[0].Score-weightName) > 1e-9 || math.Abs(typo[0].Score-weightName*qualityFuzzy) > 1e-9 {
+		t.Errorf("scores %v and %v, want %v and %v", exact[0].Score, typo[0].Score, weightName, weightName*qualityFuzzy)
+	}
+}
+
+func TestMemoryIndexUpdates(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryIndex()
+	index.Upsert(ctx, Document{ArenaID: 1, Name: "Futsal Center", SportTypes: []string{"Futsal"}})
+	index.Upsert(ctx, Document{ArenaID: 2, Name: "Futsal Park"})
+
+	// Upserting replaces the earlier terms of the arena
+	index.Upsert(ctx, Document{ArenaID: 1, Name: "Padel Club", SportTypes: []string{"Padel"}})
+	if hits, _ := index.Search(ctx, "futsal", 10); !sameArenas(hits, []uint{2}) {
+		t.Errorf("futsal after renaming = %v, want arena 2", hits)
+	}
+	if hits, _ := index.Search(ctx, "padel", 10); !sameArenas(hits, []uint{1}) {
+		t.Errorf("padel after renaming = %v, want arena 1", hits)
+	}
+
+	// Deleting drops the arena and the terms only it used
+	index.Delete(ctx, 1)
+	index.Delete(ctx, 99)
+	if hits, _ := index.Search(ctx, "padel", 10); len(hits) != 0 {
+		t.Errorf("padel after deleting = %v, want none", hits)
+	}
+	if _, ok := index.postings["padel"]; ok {
+		t.Error("the postings of padel were kept")
+	}
+	if _, ok := index.trigrams["pad"]; ok {
+		t.Error("the trigrams of padel were kept")
+	}
+}
+
+// sameArenas reports whether the hits are for the given arenas, in order.
+func sameArenas(hits []Hit, want []uint) bool {
+	if len(hits) != len(want) {
+		return false
+	}
+	for i, hit := range hits {
+		if hit.ArenaID != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package search
+
+import "context"
+
+// Document is the searchable content of an arena and its fields
+type Document struct {
+	ArenaID     uint
+	Name        string
+	Location    string
+	Description string
+	SportTypes  []string
+	FieldNames  []string
+}
+
+// Hit is an arena matching a query, with its relevance score
+type Hit struct {
+	ArenaID uint    `json:"arena_id"`
+	Score   float64 `json:"score"`
+}
+
+// Index is a full-text index of arenas. Implementations must be safe for concurrent use.
+type Index interface {
+	// Upsert adds the document to the index, replacing any earlier version of the arena.
+	Upsert(ctx context.Context, doc Document) error
+	// Delete removes the arena from the index. Deleting an unknown arena is not an error.
+	Delete(ctx context.Context, arenaID uint) error
+	// Search returns up to limit arenas matching the query, most relevant first.
+	Search(ctx context.Context, query string, limit int) ([]Hit, error)
+}
+
+// Default is the index used by the application, an embedded in-memory index built from the
+// database on start. Each process keeps its own copy: changes made through other replicas
+// only show up once Refresh runs, so with several replicas it must run periodically.
+var Default Index = NewMemoryIndex()
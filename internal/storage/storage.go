@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an object doesn't exist
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores uploaded objects, such as arena photos, under slash separated keys
+type Storage interface {
+	// Put stores the data under the key, replacing any existing object.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes the object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL giving read access to the object until the TTL expires.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Default is the storage used by the application, configured by FromEnv
+var Default Storage
+
+// FromEnv builds the storage selected by STORAGE_DRIVER: "local" (the default) stores
+// objects in MEDIA_DIR, "s3" in an S3-compatible bucket.
+func FromEnv() (Storage, error) {
+	switch strings.ToLower(os.Getenv("STORAGE_DRIVER")) {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		// Every replica must sign and check media links with the same secret
+		secret := os.Getenv("MEDIA_URL_SECRET")
+		if secret == "" {
+			return nil, errors.New("MEDIA_URL_SECRET must be set for local media storage")
+		}
+		return NewLocal(dir, os.Getenv("MEDIA_BASE_URL")+"/api/media", secret)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+	default:
+		return nil, errors.New("STORAGE_DRIVER must be local or s3")
+	}
+}
+
+// validKey rejects keys that could escape the storage root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
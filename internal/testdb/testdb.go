@@ -0,0 +1,35 @@
+// Package testdb opens in-memory SQLite databases for tests of code using gorm.
+package testdb
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// New returns an empty in-memory database with the tables of the given models, closed when
+// the test ends. Foreign key constraints are left out, so tests only create the rows they need.
+func New(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:                                   logger.Discard,
+		TranslateError:                           true, // As in production, unique violations become gorm.ErrDuplicatedKey
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each connection to ":memory:" has a database of its own, so keep a single one
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
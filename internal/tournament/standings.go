@@ -0,0 +1,82 @@
+package tournament
+
+import "sort"
+
+// Points awarded per match in standings tables
+const (
+	PointsWin  = 3
+	PointsDraw = 1
+)
+
+// Result is the score of a played match
+type Result struct {
+	Home      uint
+	Away      uint
+	HomeScore int
+	AwayScore int
+}
+
+// Standing is a row of a standings table
+type Standing struct {
+	Rank           int  `json:"rank"`
+	TeamID         uint `json:"team_id"`
+	Played         int  `json:"played"`
+	Won            int  `json:"won"`
+	Drawn          int  `json:"drawn"`
+	Lost           int  `json:"lost"`
+	GoalsFor       int  `json:"goals_for"`
+	GoalsAgainst   int  `json:"goals_against"`
+	GoalDifference int  `json:"goal_difference"`
+	Points         int  `json:"points"`
+}
+
+// Standings ranks the teams by points, then goal difference, then goals scored.
+// Teams with equal records keep their seed order.
+func Standings(teams []uint, results []Result) []Standing {
+	rows := make([]Standing, len(teams))
+	index := map[uint]int{}
+	for i, team := range teams {
+		rows[i].TeamID = team
+		index[team] = i
+	}
+
+	record := func(team uint, scored, conceded int) {
+		i, ok := index[team]
+		if !ok {
+			return
+		}
+		row := &rows[i]
+		row.Played++
+		row.GoalsFor += scored
+		row.GoalsAgainst += conceded
+		row.GoalDifference = row.GoalsFor - row.GoalsAgainst
+		switch {
+		case scored > conceded:
+			row.Won++
+			row.Points += PointsWin
+		case scored == conceded:
+			row.Drawn++
+			row.Points += PointsDraw
+		default:
+			row.Lost++
+		}
+	}
+	for _, result := range results {
+		record(result.Home, result.HomeScore, result.AwayScore)
+		record(result.Away, result.AwayScore, result.HomeScore)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Points != rows[j].Points {
+			return rows[i].Points > rows[j].Points
+		}
+		if rows[i].GoalDifference != rows[j].GoalDifference {
+			return rows[i].GoalDifference > rows[j].GoalDifference
+		}
+		return rows[i].GoalsFor > rows[j].GoalsFor
+	})
+	for i := range rows {
+		rows[i].Rank = i + 1
+	}
+	return rows
+}
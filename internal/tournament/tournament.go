@@ -0,0 +1,345 @@
+package tournament
+
+import (
+	"errors"
+	"sort"
+)
+
+// Tournament formats
+const (
+	RoundRobin        = "round_robin"        // League where every team plays every other team once
+	SingleElimination = "single_elimination" // Knockout bracket
+	DoubleElimination = "double_elimination" // Knockout with a losers bracket and a grand final
+)
+
+// Brackets a match belongs to
+const (
+	BracketLeague  = "league"
+	BracketWinners = "winners"
+	BracketLosers  = "losers"
+	BracketFinal   = "final"
+)
+
+var (
+	ErrUnknownFormat  = errors.New("format must be round_robin, single_elimination or double_elimination")
+	ErrTooFewTeams    = errors.New("at least two teams are needed")
+	ErrNotPlayable    = errors.New("this fixture is not waiting for a result")
+	ErrDrawNotAllowed = errors.New("knockout fixtures need a winner")
+)
+
+// Slot is a side of a match
+type Slot int
+
+const (
+	Home Slot = iota
+	Away
+)
+
+// Link points to the slot of the match a team moves on to
+type Link struct {
+	Match int // Index of the match in the tournament's match list
+	Slot  Slot
+}
+
+// Match is a fixture of a tournament. Team IDs are zero while unknown, and stay zero
+// for byes. Winner and loser are only set once the match is done.
+type Match struct {
+	Bracket  string
+	Round    int
+	Position int
+	Home     uint
+	Away     uint
+	Winner   uint
+	Loser    uint
+	Done     bool
+	WinnerTo *Link // Where the winner plays next, nil for finals and league matches
+	LoserTo  *Link // Where the loser plays next in double elimination
+}
+
+// Ready reports whether both teams of the match are known and it hasn't been played.
+func (m Match) Ready() bool {
+	return !m.Done && m.Home != 0 && m.Away != 0
+}
+
+// Generate creates the fixtures of a tournament for teams in seed order, best first,
+// and resolves the byes of knockout brackets.
+func Generate(format string, teams []uint) ([]Match, error) {
+	if len(teams) < 2 {
+		return nil, ErrTooFewTeams
+	}
+
+	var matches []Match
+	switch format {
+	case RoundRobin:
+		return roundRobin(teams), nil
+	case SingleElimination:
+		matches, _ = winnersBracket(teams)
+	case DoubleElimination:
+		matches = doubleElimination(teams)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	Advance(matches)
+	return matches, nil
+}
+
+// roundRobin schedules every pairing with the circle method, so each team plays once per round.
+func roundRobin(teams []uint) []Match {
+	ids := append([]uint(nil), teams...)
+	if len(ids)%2 == 1 {
+		ids = append(ids, 0) // The team drawn against 0 rests that round
+	}
+	n := len(ids)
+
+	var matches []Match
+	for round := 1; round < n; round++ {
+		position := 0
+		for i := 0; i < n/2; i++ {
+			home, away := ids[i], ids[n-1-i]
+			if home == 0 || away == 0 {
+				continue
+			}
+			// Alternate sides so no team is always at home
+			if (round+i)%2 == 0 {
+				home, away = away, home
+			}
+			position++
+			matches = append(matches, Match{Bracket: BracketLeague, Round: round, Position: position, Home: home, Away: away})
+		}
+
+		// Keep the first team in place and rotate the others
+		last := ids[n-1]
+		copy(ids[2:], ids[1:n-1])
+		ids[1] = last
+	}
+	return matches
+}
+
+// winnersBracket builds a knockout bracket padded to a power of two, with the top seeds
+// getting the byes. It returns the matches and their indexes by round.
+func winnersBracket(teams []uint) ([]Match, [][]int) {
+	size, rounds := 1, 0
+	for size < len(teams) {
+		size *= 2
+		rounds++
+	}
+	order := seedOrder(size)
+
+	var matches []Match
+	byRound := make([][]int, rounds)
+	for round := 1; round <= rounds; round++ {
+		for position := 0; position < size>>round; position++ {
+			match := Match{Bracket: BracketWinners, Round: round, Position: position + 1}
+			if round == 1 {
+				match.Home = seeded(teams, order[2*position])
+				match.Away = seeded(teams, order[2*position+1])
+			}
+			byRound[round-1] = append(byRound[round-1], len(matches))
+			matches = append(matches, match)
+		}
+	}
+	for round := 1; round < rounds; round++ {
+		for position, i := range byRound[round-1] {
+			matches[i].WinnerTo = &Link{Match: byRound[round][position/2], Slot: Slot(position % 2)}
+		}
+	}
+	return matches, byRound
+}
+
+// doubleElimination adds a losers bracket and a grand final to the winners bracket.
+// The grand final is played once, without a bracket reset if the winners bracket winner loses it.
+// Losers of winners round r+1 drop into losers round 2r, in reverse order to avoid early rematches.
+func doubleElimination(teams []uint) []Match {
+	matches, winners := winnersBracket(teams)
+	rounds := len(winners)
+	add := func(match Match) int {
+		matches = append(matches, match)
+		return len(matches) - 1
+	}
+
+	final := add(Match{Bracket: BracketFinal, Round: max(rounds, 2*(rounds-1)) + 1, Position: 1})
+	matches[winners[rounds-1][0]].WinnerTo = &Link{Match: final, Slot: Home}
+	if rounds == 1 {
+		// Two teams simply meet again in the final
+		matches[winners[0][0]].LoserTo = &Link{Match: final, Slot: Away}
+		return matches
+	}
+
+	var previous []int
+	for round := 1; round <= 2*(rounds-1); round++ {
+		var current []int
+		count := len(winners[(round+1)/2])
+		for position := 0; position < count; position++ {
+			current = append(current, add(Match{Bracket: BracketLosers, Round: round, Position: position + 1}))
+		}
+
+		switch {
+		case round == 1:
+			for position, i := range winners[0] {
+				matches[i].LoserTo = &Link{Match: current[position/2], Slot: Slot(position % 2)}
+			}
+		case round%2 == 0:
+			for position, i := range previous {
+				matches[i].WinnerTo = &Link{Match: current[position], Slot: Home}
+			}
+			for position, i := range winners[round/2] {
+				matches[i].LoserTo = &Link{Match: current[count-1-position], Slot: Away}
+			}
+		default:
+			for position, i := range previous {
+				matches[i].WinnerTo = &Link{Match: current[position/2], Slot: Slot(position % 2)}
+			}
+		}
+		previous = current
+	}
+	matches[previous[0]].WinnerTo = &Link{Match: final, Slot: Away}
+	return matches
+}
+
+// seedOrder returns the seeds of a bracket of the given size in slot order, so that
+// the best seeds only meet in the last rounds.
+func seedOrder(size int) []int {
+	order := []int{1}
+	for n := 1; n < size; n *= 2 {
+		next := make([]int, 0, 2*n)
+		for _, seed := range order {
+			next = append(next, seed, 2*n+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// seeded returns the team with the given seed, or zero for a bye.
+func seeded(teams []uint, seed int) uint {
+	if seed > len(teams) {
+		return 0
+	}
+	return teams[seed-1]
+}
+
+// Record stores the score of a ready match and moves the teams through the bracket.
+// It returns the indexes of the matches that changed, including the recorded one.
+func Record(matches []Match, i, homeScore, awayScore int) ([]int, error) {
+	match := &matches[i]
+	if !match.Ready() {
+		return nil, ErrNotPlayable
+	}
+	if homeScore == awayScore && match.Bracket != BracketLeague {
+		return nil, ErrDrawNotAllowed
+	}
+
+	match.Done = true
+	switch {
+	case homeScore > awayScore:
+		match.Winner, match.Loser = match.Home, match.Away
+	case homeScore < awayScore:
+		match.Winner, match.Loser = match.Away, match.Home
+	}
+
+	changed := map[int]bool{i: true}
+	for _, j := range propagate(matches, i) {
+		changed[j] = true
+	}
+	for _, j := range Advance(matches) {
+		changed[j] = true
+	}
+	return sortedKeys(changed), nil
+}
+
+// Advance settles the matches that can't be played because a side will never be filled,
+// moving the remaining team on. It returns the indexes of the matches that changed.
+func Advance(matches []Match) []int {
+	sources := map[Link][]int{}
+	for i, match := range matches {
+		if match.WinnerTo != nil {
+			sources[*match.WinnerTo] = append(sources[*match.WinnerTo], i)
+		}
+		if match.LoserTo != nil {
+			sources[*match.LoserTo] = append(sources[*match.LoserTo], i)
+		}
+	}
+	// A slot is settled once it has a team or every match that could fill it is done
+	settled := func(i int, slot Slot, team uint) bool {
+		if team != 0 {
+			return true
+		}
+		for _, source := range sources[Link{Match: i, Slot: slot}] {
+			if !matches[source].Done {
+				return false
+			}
+		}
+		return true
+	}
+
+	changed := map[int]bool{}
+	for progress := true; progress; {
+		progress = false
+		for i := range matches {
+			match := &matches[i]
+			if match.Done || match.Ready() || !settled(i, Home, match.Home) || !settled(i, Away, match.Away) {
+				continue
+			}
+			// A bye: the team present, if any, goes through without playing
+			match.Done = true
+			match.Winner = match.Home
+			if match.Winner == 0 {
+				match.Winner = match.Away
+			}
+			changed[i] = true
+			for _, j := range propagate(matches, i) {
+				changed[j] = true
+			}
+			progress = true
+		}
+	}
+	return sortedKeys(changed)
+}
+
+// propagate places the winner and loser of a done match in their next matches.
+func propagate(matches []Match, i int) []int {
+	var changed []int
+	place := func(link *Link, team uint) {
+		if link == nil || team == 0 {
+			return
+		}
+		if link.Slot == Home {
+			matches[link.Match].Home = team
+		} else {
+			matches[link.Match].Away = team
+		}
+		changed = append(changed, link.Match)
+	}
+	place(matches[i].WinnerTo, matches[i].Winner)
+	place(matches[i].LoserTo, matches[i].Loser)
+	return changed
+}
+
+// Champion returns the winner of a finished knockout tournament, or zero.
+func Champion(matches []Match) uint {
+	for _, match := range matches {
+		if match.Bracket != BracketLeague && match.WinnerTo == nil && match.LoserTo == nil && match.Done {
+			return match.Winner
+		}
+	}
+	return 0
+}
+
+// Finished reports whether every match has been played or settled.
+func Finished(matches []Match) bool {
+	for _, match := range matches {
+		if !match.Done {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(set map[int]bool) []int {
+	keys := make([]int, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,244 @@
+package tournament
+
+import (
+	"errors"
+	"testing"
+)
+
+// seeds returns teams 1 to n, in seed order.
+func seeds(n int) []uint {
+	teams := make([]uint, n)
+	for i := range teams {
+		teams[i] = uint(i + 1)
+	}
+	return teams
+}
+
+// play records every ready match, first to last, until none is left, letting the better
+// seed win, or the worse one when upsets is set. It returns how many matches were played.
+func play(t *testing.T, matches []Match, upsets bool) int {
+	t.Helper()
+	played := 0
+	for {
+		i := -1
+		for j := range matches {
+			if matches[j].Ready() {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			return played
+		}
+		homeScore, awayScore := 2, 1
+		if (matches[i].Home > matches[i].Away) != upsets {
+			homeScore, awayScore = 1, 2
+		}
+		if _, err := Record(matches, i, homeScore, awayScore); err != nil {
+			t.Fatalf("recording match %d: %v", i, err)
+		}
+		if played++; played > len(matches) {
+			t.Fatalf("played %d matches out of %d", played, len(matches))
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	if _, err := Generate(SingleElimination, seeds(1)); !errors.Is(err, ErrTooFewTeams) {
+		t.Errorf("one team: err = %v, want ErrTooFewTeams", err)
+	}
+	if _, err := Generate("swiss", seeds(4)); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("unknown format: err = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	for n := 2; n <= 9; n++ {
+		matches, err := Generate(RoundRobin, seeds(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != n*(n-1)/2 {
+			t.Errorf("%d teams: %d matches, want %d", n, len(matches), n*(n-1)/2)
+		}
+
+		// Circle method: every pair meets once and no team plays twice in a round
+		met := map[[2]uint]bool{}
+		perRound := map[[2]int]bool{}
+		rounds := 0
+		for _, match := range matches {
+			pair := [2]uint{min(match.Home, match.Away), max(match.Home, match.Away)}
+			if pair[0] == 0 || pair[0] == pair[1] || met[pair] {
+				t.Errorf("%d teams: unexpected pairing %d-%d", n, match.Home, match.Away)
+			}
+			met[pair] = true
+			for _, team := range pair {
+				key := [2]int{match.Round, int(team)}
+				if perRound[key] {
+					t.Errorf("%d teams: team %d plays twice in round %d", n, team, match.Round)
+				}
+				perRound[key] = true
+			}
+			rounds = max(rounds, match.Round)
+		}
+		if want := n - 1 + n%2; rounds != want {
+			t.Errorf("%d teams: %d rounds, want %d", n, rounds, want)
+		}
+
+		if played := play(t, matches, false); played != len(matches) || !Finished(matches) {
+			t.Errorf("%d teams: played %d of %d matches", n, played, len(matches))
+		}
+		var results []Result
+		for _, match := range matches {
+			score := Result{Home: match.Home, Away: match.Away, HomeScore: 1}
+			if match.Winner == match.Away {
+				score.HomeScore, score.AwayScore = 0, 1
+			}
+			results = append(results, score)
+		}
+		table := Standings(seeds(n), results)
+		if table[0].TeamID != 1 || table[0].Points != PointsWin*(n-1) || table[n-1].TeamID != uint(n) {
+			t.Errorf("%d teams: standings %+v, want team 1 first with every win and team %d last", n, table, n)
+		}
+	}
+}
+
+func TestRoundRobinFirstRound(t *testing.T) {
+	matches, _ := Generate(RoundRobin, seeds(4))
+	want := []Match{
+		{Bracket: BracketLeague, Round: 1, Position: 1, Home: 1, Away: 4},
+		{Bracket: BracketLeague, Round: 1, Position: 2, Home: 3, Away: 2},
+	}
+	for i, match := range want {
+		if matches[i] != match {
+			t.Errorf("match %d = %+v, want %+v", i, matches[i], match)
+		}
+	}
+}
+
+func TestSingleElimination(t *testing.T) {
+	for n := 2; n <= 9; n++ {
+		for _, upsets := range []bool{false, true} {
+			matches, err := Generate(SingleElimination, seeds(n))
+			if err != nil {
+				t.Fatal(err)
+			}
+			size := 1
+			for size < n {
+				size *= 2
+			}
+			if len(matches) != size-1 {
+				t.Errorf("%d teams: %d matches, want %d", n, len(matches), size-1)
+			}
+
+			// The top seeds get the byes, which are settled right away
+			byes := 0
+			for _, match := range matches {
+				if match.Round == 1 && match.Done {
+					byes++
+					if match.Away != 0 || match.Winner != match.Home || match.Home > uint(size-n) {
+						t.Errorf("%d teams: bye %+v is not for a top seed", n, match)
+					}
+				}
+				if match.LoserTo != nil {
+					t.Errorf("%d teams: knockout match %+v has a losers bracket link", n, match)
+				}
+			}
+			if byes != size-n {
+				t.Errorf("%d teams: %d byes, want %d", n, byes, size-n)
+			}
+
+			if played := play(t, matches, upsets); played != n-1 || !Finished(matches) {
+				t.Errorf("%d teams: played %d matches, want %d", n, played, n-1)
+			}
+			want := uint(1)
+			if upsets {
+				want = uint(n)
+			}
+			if champion := Champion(matches); champion != want {
+				t.Errorf("%d teams, upsets %v: champion %d, want %d", n, upsets, champion, want)
+			}
+		}
+	}
+}
+
+func TestDoubleElimination(t *testing.T) {
+	for n := 2; n <= 9; n++ {
+		for _, upsets := range []bool{false, true} {
+			matches, err := Generate(DoubleElimination, seeds(n))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Every winners bracket loser drops into the losers bracket, or the final for two teams,
+			// and the losers bracket winner meets the winners bracket winner in a single final
+			finals := 0
+			for i, match := range matches {
+				switch match.Bracket {
+				case BracketWinners:
+					if match.LoserTo == nil {
+						t.Errorf("%d teams: winners match %d has no losers bracket link", n, i)
+					} else if next := matches[match.LoserTo.Match].Bracket; next != BracketLosers && !(n == 2 && next == BracketFinal) {
+						t.Errorf("%d teams: loser of winners match %d drops into the %s bracket", n, i, next)
+					}
+				case BracketLosers:
+					if match.LoserTo != nil || match.WinnerTo == nil {
+						t.Errorf("%d teams: losers match %d links %+v %+v", n, i, match.WinnerTo, match.LoserTo)
+					}
+				case BracketFinal:
+					finals++
+					if match.WinnerTo != nil || match.LoserTo != nil {
+						t.Errorf("%d teams: the final leads to another match", n)
+					}
+				}
+			}
+			if finals != 1 {
+				t.Errorf("%d teams: %d finals, want 1 and no bracket reset", n, finals)
+			}
+
+			// Every team but the champion is out after two losses, and the final is never replayed
+			if played := play(t, matches, upsets); played != 2*n-2 || !Finished(matches) {
+				t.Errorf("%d teams: played %d matches, want %d", n, played, 2*n-2)
+			}
+			losses := map[uint]int{}
+			for _, match := range matches {
+				if match.Loser != 0 {
+					losses[match.Loser]++
+				}
+			}
+			want := uint(1)
+			if upsets {
+				want = uint(n)
+			}
+			if champion := Champion(matches); champion != want || losses[want] != 0 {
+				t.Errorf("%d teams, upsets %v: champion %d with %d losses, want %d unbeaten", n, upsets, champion, losses[champion], want)
+			}
+			for team := uint(1); team <= uint(n); team++ {
+				if team != want && losses[team] != 2 {
+					t.Errorf("%d teams, upsets %v: team %d lost %d times, want 2", n, upsets, team, losses[team])
+				}
+			}
+		}
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+	matches, _ := Generate(SingleElimination, seeds(4))
+	if _, err := Record(matches, 0, 1, 1); !errors.Is(err, ErrDrawNotAllowed) {
+		t.Errorf("knockout draw: err = %v, want ErrDrawNotAllowed", err)
+	}
+	if _, err := Record(matches, len(matches)-1, 1, 0); !errors.Is(err, ErrNotPlayable) {
+		t.Errorf("final before its teams are known: err = %v, want ErrNotPlayable", err)
+	}
+	if _, err := Record(matches, 0, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Record(matches, 0, 1, 0); !errors.Is(err, ErrNotPlayable) {
+		t.Errorf("recording twice: err = %v, want ErrNotPlayable", err)
+	}
+
+	league, _ := Generate(RoundRobin, seeds(3))
+	if _, err := Record(league, 0, 2, 2); err != nil || !league[0].Done || league[0].Winner != 0 {
+		t.Errorf("league draw: err = %v, match %+v", err, league[0])
+	}
+}
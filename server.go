@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"sparring-backend/auth"
 	"sparring-backend/handlers"
 	"sparring-backend/internal/database"
+	"sparring-backend/internal/jobs"
+	"sparring-backend/internal/loyalty"
 	"sparring-backend/internal/models"
+	"sparring-backend/internal/scheduler"
+	"sparring-backend/internal/search"
+	"sparring-backend/internal/storage"
 	"sparring-backend/middleware"
 	"time"
 
@@ -21,9 +27,48 @@ func main() {
 		return
 	}
 
+	// Load the embedded search index from the database
+	if err := search.Rebuild(context.Background(), search.Default, database.DB); err != nil {
+		log.Fatalf("Could not build the search index: %v", err)
+		return
+	}
+
+	// Store uploaded media on the configured backend
+	mediaStorage, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Could not initialize media storage: %v", err)
+		return
+	}
+	storage.Default = mediaStorage
+
+	// Read the loyalty program settings once, for the handlers and the loyalty jobs
+	loyalty.Default = loyalty.ConfigFromEnv()
+
 	// Rotate secret every 30 days
 	auth.StartSecretRotation(30 * 24 * time.Hour)
 
+	// Run background jobs; a database lease makes sure only one replica runs each job
+	jobScheduler := scheduler.New(database.DB)
+	for _, job := range jobs.BookingJobs(jobs.BookingConfigFromEnv()) {
+		jobScheduler.Register(job)
+	}
+	for _, job := range jobs.PaymentReminderJobs(jobs.PaymentReminderConfigFromEnv()) {
+		jobScheduler.Register(job)
+	}
+	for _, job := range jobs.FieldWatchJobs(jobs.FieldWatchConfigFromEnv()) {
+		jobScheduler.Register(job)
+	}
+	for _, job := range jobs.PayoutJobs(jobs.PayoutConfigFromEnv()) {
+		jobScheduler.Register(job)
+	}
+	for _, job := range jobs.LoyaltyJobs(loyalty.Default) {
+		jobScheduler.Register(job)
+	}
+	for _, job := range jobs.SearchJobs(jobs.SearchConfigFromEnv()) {
+		jobScheduler.Register(job)
+	}
+	jobScheduler.Start(context.Background())
+
 	// Initialize Gin router
 	router := gin.Default()
 
@@ -40,8 +85,14 @@ func main() {
 	api.POST("/login", rateLimiter.Limit(), handlers.LoginUser)                  // Apply rate limiting to login
 	api.POST("/refresh-token", rateLimiter.Limit(), handlers.RefreshAccessToken) // Apply rate limiting to refresh-token
 	api.POST("/logout", handlers.LogoutUser)
-	api.POST("/arena", handlers.CreateArena)
-	api.POST("/booking", handlers.CreateBooking)
+	api.POST("/webhooks/payments", handlers.PaymentWebhook) // Authenticated by HMAC signature, not JWT
+
+	// Calendar subscription feeds, authenticated by the secret token in the URL
+	api.GET("/calendar/:token/bookings.ics", handlers.UserBookingsFeed)
+	api.GET("/calendar/:token/arenas/:id/bookings.ics", handlers.ArenaBookingsFeed)
+
+	// Locally stored media, authenticated by the signature in the URL
+	api.GET("/media/*key", handlers.ServeLocalMedia)
 
 	// Add the test endpoint here
 	api.GET("/test", func(c *gin.Context) {
@@ -54,6 +105,148 @@ func main() {
 	protected := api.Group("/")
 	protected.Use(middleware.AuthMiddleware()) // Use authentication middleware
 
+	// Bookings
+	protected.POST("/booking", handlers.CreateBooking)
+	protected.GET("/bookings/:id", handlers.GetBooking) // Also serves /bookings/:id.ics
+	protected.GET("/bookings/:id/invoice", handlers.GetBookingInvoice)
+	protected.GET("/bookings/:id/check-in-code", handlers.GetCheckInCode)
+	protected.POST("/bookings/check-in", handlers.CheckInBooking) // Arena staff only
+	protected.GET("/arenas/:id/bookings", handlers.ListArenaBookings)
+	protected.POST("/bookings/:id/add-ons", handlers.AttachBookingAddOns)
+	protected.POST("/bookings/:id/cancel", handlers.CancelBooking)
+
+	protected.GET("/me/bookings", handlers.ListMyBookings)
+	protected.GET("/me/penalties", handlers.ListMyPenalties)
+	protected.POST("/bookings/:id/split", handlers.SplitBooking)
+	protected.GET("/bookings/:id/split", handlers.GetBookingSplit)
+	protected.POST("/bookings/:id/split/cover", handlers.CoverBookingSplit)
+	protected.GET("/me/payment-shares", handlers.ListMyPaymentShares)
+	protected.POST("/me/favorites", handlers.AddFavorite)
+	protected.GET("/me/favorites", handlers.ListFavorites)
+	protected.DELETE("/me/favorites/:id", handlers.RemoveFavorite)
+	protected.POST("/me/saved-searches", handlers.CreateSavedSearch)
+	protected.GET("/me/saved-searches", handlers.ListSavedSearches)
+	protected.DELETE("/me/saved-searches/:id", handlers.DeleteSavedSearch)
+	protected.POST("/me/field-watches", handlers.CreateFieldWatch)
+	protected.GET("/me/field-watches", handlers.ListFieldWatches)
+	protected.DELETE("/me/field-watches/:id", handlers.DeleteFieldWatch)
+
+	// Teams
+	protected.POST("/teams", handlers.CreateTeam)
+	protected.GET("/teams/:id", handlers.GetTeam)
+	protected.GET("/me/teams", handlers.ListMyTeams)
+	protected.PUT("/teams/:id/members/:user_id", handlers.UpdateTeamMember)
+	protected.DELETE("/teams/:id/members/:user_id", handlers.RemoveTeamMember)
+	protected.POST("/teams/:id/invitations", handlers.CreateTeamInvitation)
+	protected.PUT("/teams/:id/availability", handlers.SetTeamAvailability)
+	protected.PUT("/teams/:id/preferred-arenas", handlers.SetTeamPreferredArenas)
+	protected.GET("/teams/:id/suggested-opponents", handlers.SuggestOpponents)
+	protected.GET("/teams/:id/rating-history", handlers.GetTeamRatingHistory)
+	protected.POST("/bookings/:id/result", handlers.ReportMatchResult)
+	protected.GET("/bookings/:id/result", handlers.GetMatchResult)
+	protected.POST("/results/:id/confirm", handlers.ConfirmMatchResult)
+	protected.POST("/results/:id/dispute", handlers.DisputeMatchResult)
+	protected.POST("/tournaments", handlers.CreateTournament)
+	protected.GET("/tournaments", handlers.ListTournaments)
+	protected.GET("/tournaments/:id", handlers.GetTournament)
+	protected.POST("/tournaments/:id/teams", handlers.RegisterTournamentTeam)
+	protected.DELETE("/tournaments/:id/teams/:team_id", handlers.WithdrawTournamentTeam)
+	protected.POST("/tournaments/:id/start", handlers.StartTournament)
+	protected.GET("/tournaments/:id/fixtures", handlers.ListFixtures)
+	protected.GET("/tournaments/:id/standings", handlers.GetTournamentStandings)
+	protected.POST("/tournaments/:id/fixtures/:fixture_id/result", handlers.RecordFixtureResult)
+	protected.POST("/tournaments/:id/fixtures/schedule", handlers.ScheduleTournamentFixtures)
+	protected.GET("/me/invitations", handlers.ListMyInvitations)
+	protected.POST("/invitations/:token/accept", handlers.AcceptInvitation)
+	protected.POST("/invitations/:token/decline", handlers.DeclineInvitation)
+	protected.DELETE("/invitations/:token", handlers.RevokeInvitation)
+
+	// Sparring match requests
+	protected.POST("/match-requests", handlers.CreateMatchRequest)
+	protected.GET("/match-requests", handlers.ListMatchRequests)
+	protected.GET("/match-requests/:id", handlers.GetMatchRequest)
+	protected.POST("/match-requests/:id/accept", handlers.AcceptMatchRequest)
+	protected.POST("/match-requests/:id/cancel", handlers.CancelMatchRequest)
+
+	// Calendar feeds
+	protected.GET("/me/calendar", handlers.GetCalendarFeeds)
+	protected.POST("/me/calendar/reset", handlers.ResetCalendarToken)
+
+	// Arenas
+	protected.POST("/arena", handlers.CreateArena)
+	protected.GET("/arenas", handlers.ListArenas)
+	protected.GET("/arenas/search", handlers.SearchArenas)
+	protected.GET("/arenas/:id", handlers.GetArena)
+	protected.PUT("/arenas/:id", handlers.UpdateArena)
+	protected.DELETE("/arenas/:id", handlers.DeleteArena)
+	protected.POST("/arenas/:id/media", handlers.UploadArenaMedia)
+	protected.DELETE("/media/:id", handlers.DeleteArenaMedia)
+	protected.GET("/search", handlers.SearchText)
+	protected.POST("/arenas/:id/reviews", handlers.CreateReview)
+	protected.GET("/arenas/:id/reviews", handlers.ListArenaReviews)
+	protected.PUT("/reviews/:id/reply", handlers.ReplyToReview)
+	protected.POST("/reviews/:id/report", handlers.ReportReview)
+	protected.GET("/review-reports", handlers.ListReviewReports)
+	protected.POST("/reviews/:id/hide", handlers.HideReview)
+	protected.POST("/reviews/:id/restore", handlers.RestoreReview)
+
+	// Owner reports
+	protected.GET("/arenas/:id/reports/summary", handlers.GetArenaReportSummary)
+	protected.GET("/arenas/:id/reports/occupancy", handlers.GetArenaOccupancy)
+	protected.GET("/arenas/:id/reports/revenue", handlers.GetArenaRevenue)
+	protected.GET("/arenas/:id/reports/top-customers", handlers.GetArenaTopCustomers)
+
+	// Arena staff
+	protected.POST("/arenas/:id/staff/invitations", handlers.CreateStaffInvitation)
+	protected.GET("/arenas/:id/staff", handlers.ListArenaStaff)
+	protected.PUT("/arenas/:id/staff/:user_id", handlers.UpdateArenaStaff)
+	protected.DELETE("/arenas/:id/staff/:user_id", handlers.RemoveArenaStaff)
+	protected.GET("/me/staff-arenas", handlers.ListMyStaffArenas)
+	protected.GET("/me/staff-invitations", handlers.ListMyStaffInvitations)
+	protected.POST("/staff-invitations/:token/accept", handlers.AcceptStaffInvitation)
+	protected.POST("/staff-invitations/:token/decline", handlers.DeclineStaffInvitation)
+	protected.DELETE("/staff-invitations/:token", handlers.RevokeStaffInvitation)
+
+	// Memberships and passes
+	protected.POST("/arenas/:id/pass-plans", handlers.CreatePassPlan)
+	protected.GET("/arenas/:id/pass-plans", handlers.ListPassPlans)
+	protected.PUT("/pass-plans/:id", handlers.UpdatePassPlan)
+	protected.POST("/arenas/:id/passes", handlers.IssuePass)
+	protected.GET("/arenas/:id/passes", handlers.ListArenaPasses)
+	protected.POST("/passes/:id/cancel", handlers.CancelPass)
+	protected.GET("/me/passes", handlers.ListMyPasses)
+
+	// Loyalty points and referrals
+	protected.GET("/me/points", handlers.GetMyPoints)
+	protected.GET("/me/referrals", handlers.ListMyReferrals)
+	protected.GET("/users/:id/points", handlers.GetUserPoints)     // Admin only
+	protected.POST("/users/:id/points", handlers.AdjustUserPoints) // Admin only
+
+	// Payouts and ledger
+	protected.GET("/me/statement", handlers.GetMyStatement)
+	protected.GET("/me/payouts", handlers.ListMyPayouts)
+	protected.GET("/payouts", handlers.ListPayouts)                  // Admin only
+	protected.POST("/payouts/:id/sent", handlers.MarkPayoutSent)     // Admin only
+	protected.POST("/payouts/:id/failed", handlers.MarkPayoutFailed) // Admin only
+	protected.PUT("/arenas/:id/commission", handlers.SetArenaCommission)
+	protected.GET("/ledger/reconciliation", handlers.GetLedgerReconciliation)
+
+	// Promo codes
+	protected.POST("/promo-codes", handlers.CreatePromoCode)
+	protected.POST("/promo-codes/validate", handlers.ValidatePromoCode)
+
+	// Pricing rules and quotes
+	protected.POST("/arenas/:id/pricing-rules", handlers.CreatePricingRule)
+	protected.GET("/arenas/:id/pricing-rules", handlers.ListPricingRules)
+	protected.DELETE("/pricing-rules/:id", handlers.DeletePricingRule)
+	protected.GET("/fields/:id/quote", handlers.QuoteField)
+
+	// Add-ons
+	protected.POST("/arenas/:id/add-ons", handlers.CreateAddOn)
+	protected.GET("/arenas/:id/add-ons", handlers.ListAddOns)
+	protected.PUT("/add-ons/:id", handlers.UpdateAddOn)
+	protected.DELETE("/add-ons/:id", handlers.DeleteAddOn)
+
 	// Route to fetch current user details
 	protected.GET("/protected", func(c *gin.Context) {
 		// Retrieve the user from the context